@@ -0,0 +1,263 @@
+package tree
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ansiPalette are the xterm colors of the 16 basic SGR colors, 30-37 and
+// 90-97 for the foreground, 40-47 and 100-107 for the background.
+var ansiPalette = [16]string{
+	"#000000", "#cd0000", "#00cd00", "#cdcd00", "#0000ee", "#cd00cd", "#00cdcd", "#e5e5e5",
+	"#7f7f7f", "#ff0000", "#00ff00", "#ffff00", "#5c5cff", "#ff00ff", "#00ffff", "#ffffff",
+}
+
+// ansiAttrs are the CSS declarations of the SGR attributes.
+var ansiAttrs = []struct {
+	code int
+	css  string
+}{
+	{1, "font-weight: bold"},
+	{2, "opacity: 0.7"},
+	{3, "font-style: italic"},
+	{4, "text-decoration: underline"},
+	{9, "text-decoration: line-through"},
+}
+
+// HTMLStylesheet returns the CSS rules of the classes of the spans written
+// by HTMLWriter, e.g. ".sgr-34 { color: #0000ee; }".
+func HTMLStylesheet() string {
+	var b strings.Builder
+	for _, attr := range ansiAttrs {
+		fmt.Fprintf(&b, ".sgr-%d { %s; }\n", attr.code, attr.css)
+	}
+	for i, color := range ansiPalette {
+		fg, bg := 30+i, 40+i
+		if i >= 8 {
+			fg, bg = 90+i-8, 100+i-8
+		}
+		fmt.Fprintf(&b, ".sgr-%d { color: %s; }\n.sgr-%d { background-color: %s; }\n", fg, color, bg, color)
+	}
+	return b.String()
+}
+
+// HTMLWriter converts the colored output written to it, e.g. as the
+// OutFile of the Colorize option, to HTML: the text is escaped, and the
+// SGR escape sequences become spans with the classes of HTMLStylesheet,
+// or inline styles for the 256 and the 24-bit colors. The other escape
+// sequences are dropped. Close closes the last span.
+type HTMLWriter struct {
+	w io.Writer
+	// partial is an escape sequence split between two writes
+	partial []byte
+	// the attributes and colors of the next text, and whether its span is
+	// already open
+	attrs  []int
+	fg, bg string
+	open   bool
+}
+
+// NewHTMLWriter returns an HTMLWriter writing to w.
+func NewHTMLWriter(w io.Writer) *HTMLWriter {
+	return &HTMLWriter{w: w}
+}
+
+// ANSIToHTML converts a colored text to HTML, see HTMLWriter.
+func ANSIToHTML(s string) string {
+	var b strings.Builder
+	hw := NewHTMLWriter(&b)
+	io.WriteString(hw, s)
+	hw.Close()
+	return b.String()
+}
+
+func (hw *HTMLWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	var out bytes.Buffer
+	if hw.partial != nil {
+		p = append(hw.partial, p...)
+		hw.partial = nil
+	}
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, Escape[0])
+		if i < 0 {
+			hw.text(&out, p)
+			break
+		}
+		hw.text(&out, p[:i])
+		p = p[i:]
+		end := escapeEnd(p)
+		if end < 0 {
+			hw.partial = append([]byte(nil), p...)
+			break
+		}
+		if len(p) > 2 && p[1] == '[' && p[end-1] == 'm' {
+			hw.sgr(&out, string(p[2:end-1]))
+		}
+		p = p[end:]
+	}
+	if _, err := hw.w.Write(out.Bytes()); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Close closes the open span, if any. It doesn't close the underlying
+// writer.
+func (hw *HTMLWriter) Close() error {
+	if !hw.open {
+		return nil
+	}
+	hw.open = false
+	_, err := io.WriteString(hw.w, "</span>")
+	return err
+}
+
+// escapeEnd returns the length of the escape sequence at the start of p,
+// or -1 if it's incomplete: a CSI sequence ends with a byte in 0x40-0x7e,
+// the others are two bytes long.
+func escapeEnd(p []byte) int {
+	if len(p) < 2 {
+		return -1
+	}
+	if p[1] != '[' {
+		return 2
+	}
+	for i := 2; i < len(p); i++ {
+		if p[i] >= 0x40 && p[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// text writes escaped text, in the span of the current style.
+func (hw *HTMLWriter) text(out *bytes.Buffer, p []byte) {
+	if len(p) == 0 {
+		return
+	}
+	if !hw.open && (len(hw.attrs) > 0 || hw.fg != "" || hw.bg != "") {
+		out.WriteString(hw.span())
+		hw.open = true
+	}
+	out.WriteString(html.EscapeString(string(p)))
+}
+
+// span returns the opening tag of the span of the current style.
+func (hw *HTMLWriter) span() string {
+	var classes, styles []string
+	for _, code := range hw.attrs {
+		classes = append(classes, "sgr-"+strconv.Itoa(code))
+	}
+	for _, c := range []struct{ color, prop string }{{hw.fg, "color"}, {hw.bg, "background-color"}} {
+		switch {
+		case c.color == "":
+		case c.color[0] == '#':
+			styles = append(styles, c.prop+": "+c.color)
+		default:
+			classes = append(classes, c.color)
+		}
+	}
+	s := "<span"
+	if classes != nil {
+		s += ` class="` + strings.Join(classes, " ") + `"`
+	}
+	if styles != nil {
+		s += ` style="` + strings.Join(styles, "; ") + `"`
+	}
+	return s + ">"
+}
+
+// sgr applies the parameters of an SGR sequence, e.g. "01;34", to the
+// current style.
+func (hw *HTMLWriter) sgr(out *bytes.Buffer, params string) {
+	var codes []int
+	for _, s := range strings.Split(params, ";") {
+		// Empty parameters are zeros, i.e. resets
+		code, _ := strconv.Atoi(s)
+		codes = append(codes, code)
+	}
+	if hw.open {
+		out.WriteString("</span>")
+		hw.open = false
+	}
+	for i := 0; i < len(codes); i++ {
+		switch code := codes[i]; {
+		case code == 0:
+			hw.attrs, hw.fg, hw.bg = nil, "", ""
+		case code == 1 || code == 2 || code == 3 || code == 4 || code == 9:
+			hw.attrs = removeAttr(hw.attrs, code)
+			hw.attrs = append(hw.attrs, code)
+		case code == 22:
+			hw.attrs = removeAttr(removeAttr(hw.attrs, 1), 2)
+		case code == 23 || code == 24:
+			hw.attrs = removeAttr(hw.attrs, code-20)
+		case code == 29:
+			hw.attrs = removeAttr(hw.attrs, 9)
+		case code >= 30 && code <= 37, code >= 90 && code <= 97:
+			hw.fg = "sgr-" + strconv.Itoa(code)
+		case code >= 40 && code <= 47, code >= 100 && code <= 107:
+			hw.bg = "sgr-" + strconv.Itoa(code)
+		case code == 39:
+			hw.fg = ""
+		case code == 49:
+			hw.bg = ""
+		case code == 38 || code == 48:
+			color, n := extendedColor(codes[i+1:])
+			i += n
+			if code == 38 {
+				hw.fg = color
+			} else {
+				hw.bg = color
+			}
+		}
+	}
+}
+
+func removeAttr(attrs []int, code int) []int {
+	for i, attr := range attrs {
+		if attr == code {
+			return append(attrs[:i], attrs[i+1:]...)
+		}
+	}
+	return attrs
+}
+
+// extendedColor returns the color of the parameters following a 38 or 48
+// code: "5;n" for the 256 colors of xterm, or "2;r;g;b". It returns the
+// number of parameters used, and "" if they're invalid.
+func extendedColor(codes []int) (color string, n int) {
+	switch {
+	case len(codes) >= 2 && codes[0] == 5:
+		return xtermColor(codes[1]), 2
+	case len(codes) >= 4 && codes[0] == 2:
+		return fmt.Sprintf("#%02x%02x%02x", uint8(codes[1]), uint8(codes[2]), uint8(codes[3])), 4
+	}
+	return "", len(codes)
+}
+
+// xtermColor returns the color of an index of the xterm 256 colors: the
+// basic ones, a 6x6x6 cube, and 24 grays.
+func xtermColor(i int) string {
+	switch {
+	case i < 0 || i > 255:
+		return ""
+	case i < 16:
+		return ansiPalette[i]
+	case i < 232:
+		i -= 16
+		level := func(v int) int {
+			if v == 0 {
+				return 0
+			}
+			return 55 + v*40
+		}
+		return fmt.Sprintf("#%02x%02x%02x", level(i/36), level(i/6%6), level(i%6))
+	}
+	gray := 8 + (i-232)*10
+	return fmt.Sprintf("#%02x%02x%02x", gray, gray, gray)
+}
@@ -0,0 +1,36 @@
+package tree
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestANSIToHTML(t *testing.T) {
+	for _, test := range []struct {
+		input, expected string
+	}{
+		{"plain <a&b>", "plain &lt;a&amp;b&gt;"},
+		{ANSIColorFormat("1;34", "dir") + "\n", `<span class="sgr-1 sgr-34">dir</span>` + "\n"},
+		{"\x1b[01;32mex\x1b[0m \x1b[00;36mgo\x1b[0m", `<span class="sgr-1 sgr-32">ex</span> <span class="sgr-36">go</span>`},
+		{"\x1b[38;5;208mo\x1b[48;2;1;2;3mb\x1b[39;49m", `<span style="color: #ff8700">o</span><span style="color: #ff8700; background-color: #010203">b</span>`},
+		{"\x1b[1mbold\x1b[22m\x1b[Kplain\x1b[4m", `<span class="sgr-1">bold</span>plain`},
+		{"\x1b[31munclosed", `<span class="sgr-31">unclosed</span>`},
+	} {
+		if got := ANSIToHTML(test.input); got != test.expected {
+			t.Errorf("%q: got %s, expected %s", test.input, got, test.expected)
+		}
+	}
+	// The escape sequences may be split between the writes
+	var b strings.Builder
+	hw := NewHTMLWriter(&b)
+	for _, s := range []string{"\x1b", "[1;", "31mre", "d\x1b[0", "m"} {
+		hw.Write([]byte(s))
+	}
+	hw.Close()
+	if expected := `<span class="sgr-1 sgr-31">red</span>`; b.String() != expected {
+		t.Errorf("split writes: got %s, expected %s", b.String(), expected)
+	}
+	if css := HTMLStylesheet(); !strings.Contains(css, ".sgr-34 { color: #0000ee; }") || !strings.Contains(css, ".sgr-107 { background-color: #ffffff; }") {
+		t.Errorf("unexpected stylesheet:\n%s", css)
+	}
+}
@@ -0,0 +1,254 @@
+package tree
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// archiveExts are the archive formats listed by the Archives option, by
+// extension.
+var archiveExts = []struct {
+	ext    string
+	format string
+}{
+	{".zip", "zip"},
+	{".jar", "zip"},
+	{".tar", "tar"},
+	{".tar.gz", "tar.gz"},
+	{".tgz", "tar.gz"},
+}
+
+// archiveFormat returns the archive format of a file name, or "".
+func archiveFormat(name string) string {
+	name = strings.ToLower(name)
+	for _, a := range archiveExts {
+		if strings.HasSuffix(name, a.ext) {
+			return a.format
+		}
+	}
+	return ""
+}
+
+// archiveInfo is the FileInfo of an archive entry.
+type archiveInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi *archiveInfo) Name() string       { return fi.name }
+func (fi *archiveInfo) Size() int64        { return fi.size }
+func (fi *archiveInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *archiveInfo) ModTime() time.Time { return fi.modTime }
+func (fi *archiveInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *archiveInfo) Sys() interface{}   { return nil }
+
+// errArchiveFs is returned when the Fs can't open files.
+var errArchiveFs = errors.New("the file-system doesn't implement FileOpener")
+
+// expandArchive lists the entries of an archive as the children of its
+// node, see the Archives option. It returns the number of directories and
+// files listed.
+func (node *Node) expandArchive(opts *Options, format string) (dirs, files int) {
+	if opts.DeepLevel > 0 && opts.DeepLevel <= node.depth {
+		node.truncated = true
+		return
+	}
+	entries, err := opts.readArchive(node.path, format)
+	if err != nil {
+		opts.warn("cannot read archive", node.path, err)
+		return
+	}
+	node.archive = format
+	node.nodes = make(Nodes, 0)
+	parents := map[string]*Node{"": node}
+	var parent func(dir string) *Node
+	parent = func(dir string) *Node {
+		if n, ok := parents[dir]; ok {
+			return n
+		}
+		p := parent(archiveDir(dir))
+		n := p.addArchived(&archiveInfo{name: path.Base(dir), mode: os.ModeDir | 0755}, opts.DeepLevel)
+		if n != nil {
+			parents[dir] = n
+		}
+		return n
+	}
+	for _, fi := range entries {
+		name := strings.Trim(path.Clean("/"+fi.name), "/")
+		if name == "" {
+			continue
+		}
+		p := parent(archiveDir(name))
+		if p == nil {
+			continue
+		}
+		fi.name = path.Base(name)
+		if n, ok := parents[name]; ok && fi.IsDir() {
+			n.FileInfo = fi
+			continue
+		}
+		if n := p.addArchived(fi, opts.DeepLevel); n != nil && fi.IsDir() {
+			parents[name] = n
+		}
+	}
+	dirs, files = node.sumArchived(opts)
+	node.sdirs, node.sfiles = node.sdirs+dirs, node.sfiles+files
+	return
+}
+
+// archiveDir returns the parent directory of an archive entry, "" for the
+// top-level ones.
+func archiveDir(name string) string {
+	if dir := path.Dir(name); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+// addArchived adds an archive entry to the node, unless it's deeper than
+// maxDepth (the DeepLevel option).
+func (node *Node) addArchived(fi *archiveInfo, maxDepth int) *Node {
+	if node == nil {
+		return nil
+	}
+	if maxDepth > 0 && node.depth >= maxDepth {
+		node.truncated = true
+		return nil
+	}
+	nnode := &Node{
+		FileInfo: fi,
+		path:     node.path + "/" + fi.name,
+		depth:    node.depth + 1,
+		vpaths:   node.vpaths,
+		archived: true,
+	}
+	if fi.IsDir() {
+		nnode.nodes = make(Nodes, 0)
+	}
+	node.nodes = append(node.nodes, nnode)
+	return nnode
+}
+
+// sumArchived computes the sizes and counts of the archive directories
+// below the node, and sorts them.
+func (node *Node) sumArchived(opts *Options) (dirs, files int) {
+	for _, nnode := range node.nodes {
+		if nnode.IsDir() {
+			d, f := nnode.sumArchived(opts)
+			dirs, files = dirs+d+1, files+f
+		} else {
+			files++
+		}
+		if node.IsDir() {
+			node.addSize(nnode)
+		}
+	}
+	node.entries = len(node.nodes)
+	node.empty = node.IsDir() && len(node.nodes) == 0
+	if node.IsDir() {
+		node.nfiles = files
+	}
+	if !opts.NoSort {
+		node.sort(opts)
+	}
+	return
+}
+
+// readArchive returns the entries of an archive file.
+func (opts *Options) readArchive(name, format string) ([]*archiveInfo, error) {
+	fo, ok := opts.Fs.(FileOpener)
+	if !ok {
+		return nil, errArchiveFs
+	}
+	r, err := fo.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	switch format {
+	case "zip":
+		return readZip(r)
+	case "tar.gz":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return readTar(zr)
+	}
+	return readTar(r)
+}
+
+// readerAt returns r as an io.ReaderAt, and its size. It's read in
+// memory, unless it implements io.ReaderAt and Stat like *os.File.
+func readerAt(r io.Reader) (io.ReaderAt, int64, error) {
+	if f, ok := r.(interface {
+		io.ReaderAt
+		Stat() (os.FileInfo, error)
+	}); ok {
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, 0, err
+		}
+		return f, fi.Size(), nil
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(b), int64(len(b)), nil
+}
+
+// readZip returns the entries of a zip archive.
+func readZip(r io.Reader) ([]*archiveInfo, error) {
+	ra, size, err := readerAt(r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*archiveInfo
+	for _, f := range zr.File {
+		entries = append(entries, newArchiveInfo(f.Name, f.FileInfo()))
+	}
+	return entries, nil
+}
+
+// readTar returns the entries of a tar archive.
+func readTar(r io.Reader) ([]*archiveInfo, error) {
+	tr := tar.NewReader(r)
+	var entries []*archiveInfo
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, newArchiveInfo(hdr.Name, hdr.FileInfo()))
+	}
+}
+
+// newArchiveInfo returns the archiveInfo of an entry. The links and the
+// special files are listed as regular files.
+func newArchiveInfo(name string, fi os.FileInfo) *archiveInfo {
+	mode := fi.Mode()
+	if !mode.IsDir() {
+		mode &= os.ModePerm
+	}
+	return &archiveInfo{name: name, size: fi.Size(), mode: mode, modTime: fi.ModTime()}
+}
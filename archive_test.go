@@ -0,0 +1,66 @@
+package tree
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func TestArchives(t *testing.T) {
+	zb := new(bytes.Buffer)
+	zw := zip.NewWriter(zb)
+	for _, name := range []string{"src/main.go", "src/", "README", "src/lib/a.go"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte("package"))
+	}
+	zw.Close()
+	tb := new(bytes.Buffer)
+	gw := gzip.NewWriter(tb)
+	tw := tar.NewWriter(gw)
+	for _, name := range []string{"bin/", "bin/tool", "LICENSE"} {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: 4, Typeflag: tar.TypeReg}
+		if name[len(name)-1] == '/' {
+			hdr.Mode, hdr.Size, hdr.Typeflag = 0755, 0, tar.TypeDir
+		}
+		tw.WriteHeader(hdr)
+		if hdr.Size > 0 {
+			tw.Write([]byte("data"))
+		}
+	}
+	tw.Close()
+	gw.Close()
+	root := &file{name: "root", files: []*file{
+		{name: "a.zip", size: int64(zb.Len()), content: zb.String()},
+		{name: "b.tgz", size: int64(tb.Len()), content: tb.String()},
+		{name: "c.zip", content: "not a zip"},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"archives", &Options{Fs: fs, OutFile: out, Archives: true}, `root
+├── a.zip [zip archive]
+│   ├── README
+│   └── src
+│       ├── lib
+│       │   └── a.go
+│       └── main.go
+├── b.tgz [tar.gz archive]
+│   ├── LICENSE
+│   └── bin
+│       └── tool
+└── c.zip
+`, 3, 8},
+		{"archives-depth", &Options{Fs: fs, OutFile: out, Archives: true, DeepLevel: 2}, `root
+├── a.zip [zip archive]
+│   ├── README
+│   └── src
+├── b.tgz [tar.gz archive]
+│   ├── LICENSE
+│   └── bin
+└── c.zip
+`, 2, 5}})
+}
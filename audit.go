@@ -0,0 +1,134 @@
+package tree
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Risk is a risky property of an entry, marked by the Audit option.
+type Risk string
+
+// The risks of the Audit option.
+const (
+	// WorldWritable is a directory writable by all users without the
+	// sticky bit, or a file writable by all users.
+	WorldWritable Risk = "world-writable"
+	Setuid        Risk = "setuid"
+	Setgid        Risk = "setgid"
+	// NoOwner is an entry owned by an unknown UID or the "nobody" user.
+	NoOwner Risk = "no owner"
+)
+
+// AuditStyle is the color of the Audit markers, bold red.
+const AuditStyle = "1;31"
+
+// AuditFinding is a risky entry of a tree visited with the Audit option.
+type AuditFinding struct {
+	Path  string
+	Risks []Risk
+}
+
+// owners caches whether the UIDs have an owner, as users are looked up
+// for each audited entry.
+var owners = struct {
+	sync.Mutex
+	m map[uint64]bool
+}{m: make(map[uint64]bool)}
+
+// hasOwner reports whether uid is a known user other than nobody.
+func hasOwner(uid uint64) bool {
+	owners.Lock()
+	defer owners.Unlock()
+	ok, cached := owners.m[uid]
+	if !cached {
+		name, err := lookupUser(strconv.FormatUint(uid, 10))
+		ok = err == nil && name != "nobody"
+		owners.m[uid] = ok
+	}
+	return ok
+}
+
+// auditRisks returns the risks of a file.
+func auditRisks(fi os.FileInfo) []Risk {
+	var risks []Risk
+	mode := fi.Mode()
+	switch {
+	case mode&os.ModeSymlink != 0:
+		// Their permissions are not used
+	case mode.IsDir() && mode&0002 != 0 && mode&os.ModeSticky == 0,
+		mode.IsRegular() && mode&0002 != 0:
+		risks = append(risks, WorldWritable)
+	}
+	if mode.IsRegular() && mode&os.ModeSetuid != 0 {
+		risks = append(risks, Setuid)
+	}
+	if mode.IsRegular() && mode&os.ModeSetgid != 0 {
+		risks = append(risks, Setgid)
+	}
+	if ok, _, _, uid, _ := getStat(fi); ok && !hasOwner(uid) {
+		risks = append(risks, NoOwner)
+	}
+	return risks
+}
+
+// auditMarker returns the markers of the risks of a node, e.g.
+// " [setuid]", or "".
+func (opts *Options) auditMarker(node *Node) string {
+	var s string
+	for _, risk := range node.risks {
+		marker := "[" + string(risk) + "]"
+		if opts.Colorize {
+			marker = ANSIColorFormat(AuditStyle, marker)
+		}
+		s += " " + marker
+	}
+	return s
+}
+
+// AuditFindings returns the risky entries of a tree visited with the
+// Audit option, in display order.
+func (node *Node) AuditFindings() (findings []AuditFinding) {
+	if node.risks != nil {
+		findings = append(findings, AuditFinding{node.path, node.risks})
+	}
+	for _, nnode := range node.nodes {
+		findings = append(findings, nnode.AuditFindings()...)
+	}
+	return
+}
+
+// FprintAudit writes a summary of the given findings, e.g:
+//
+//	audit found 2 risky entries: 1 setuid, 1 world-writable
+//	  root/bin/su [setuid]
+//	  root/tmp [world-writable]
+func FprintAudit(w io.Writer, findings []AuditFinding) {
+	if len(findings) == 0 {
+		fmt.Fprintln(w, "audit found no risky entries")
+		return
+	}
+	counts := make(map[Risk]int)
+	for _, f := range findings {
+		for _, risk := range f.Risks {
+			counts[risk]++
+		}
+	}
+	var what []string
+	for _, risk := range []Risk{Setuid, Setgid, WorldWritable, NoOwner} {
+		if counts[risk] > 0 {
+			what = append(what, fmt.Sprintf("%d %s", counts[risk], risk))
+		}
+	}
+	fmt.Fprintf(w, "audit found %s: %s\n", plural(len(findings), "risky entry", "risky entries"), strings.Join(what, ", "))
+	for _, f := range findings {
+		var risks []string
+		for _, risk := range f.Risks {
+			risks = append(risks, string(risk))
+		}
+		fmt.Fprintf(w, "  %s [%s]\n", f.Path, strings.Join(risks, ", "))
+	}
+}
@@ -0,0 +1,49 @@
+package tree
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	root := &file{name: "root", mode: os.ModeDir | 0755, files: []*file{
+		{name: "bin", mode: os.ModeDir | 0755, files: []*file{
+			{name: "ls", mode: 0755},
+			{name: "su", mode: os.ModeSetuid | 0755},
+			{name: "wall", mode: os.ModeSetgid | 0755},
+		}},
+		{name: "orphan", mode: 0644, stat: &syscall.Stat_t{Uid: 54321}},
+		{name: "public", mode: os.ModeDir | 0777, files: []*file{}},
+		{name: "tmp", mode: os.ModeDir | os.ModeSticky | 0777, files: []*file{}},
+		{name: "writable", mode: 0666},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"audit", &Options{Fs: fs, OutFile: out, Audit: true}, `root
+├── bin
+│   ├── ls
+│   ├── su [setuid]
+│   └── wall [setgid]
+├── orphan [no owner]
+├── public [world-writable]
+├── tmp
+└── writable [world-writable]
+`, 3, 5},
+	})
+	inf := New("root")
+	inf.Visit(&Options{Fs: fs, OutFile: out, Audit: true})
+	b := new(bytes.Buffer)
+	FprintAudit(b, inf.AuditFindings())
+	expected := `audit found 5 risky entries: 1 setuid, 1 setgid, 2 world-writable, 1 no owner
+  root/bin/su [setuid]
+  root/bin/wall [setgid]
+  root/orphan [no owner]
+  root/public [world-writable]
+  root/writable [world-writable]
+`
+	if b.String() != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", b, expected)
+	}
+}
@@ -0,0 +1,266 @@
+// Package azuretree implements a tree.Fs of an Azure Blob Storage
+// container.
+//
+// The directories are emulated by listing the blobs with a "/" delimiter.
+// On the accounts with a hierarchical namespace (ADLS Gen2), the real
+// directories are listed too, including the empty ones. E.g.:
+//
+//	fs := azuretree.New("account", "container")
+//	fs.SAS = "sv=2020-10-02&ss=b&sig=..." // Unless the container is public
+//	opts := &tree.Options{Fs: fs, OutFile: os.Stdout}
+//	inf := tree.New(".")
+//	inf.Visit(opts)
+//	inf.Print(opts)
+package azuretree
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiVersion is the version of the Blob service REST API used.
+const apiVersion = "2020-10-02"
+
+// FS is the file-system of a container. The paths are the names of the
+// blobs, and "." or "" is the root of the container. Each directory is
+// listed once, and then served from memory.
+type FS struct {
+	Account   string
+	Container string
+	// SAS is the shared access signature query string authorizing the
+	// requests, if the container isn't public.
+	SAS string
+	// Client sends the requests, http.DefaultClient by default, e.g. to
+	// authorize them otherwise.
+	Client *http.Client
+	// Endpoint defaults to https://<Account>.blob.core.windows.net.
+	Endpoint string
+
+	mu   sync.Mutex
+	dirs map[string]*listing
+}
+
+// listing is the content of a directory.
+type listing struct {
+	names []string
+	files map[string]*fileInfo
+}
+
+// New returns the FS of the given container.
+func New(account, container string) *FS {
+	return &FS{Account: account, Container: container}
+}
+
+// Stat returns the FileInfo of a blob or a directory.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	name = clean(name)
+	if name == "" {
+		return &fileInfo{name: fs.Container, mode: os.ModeDir | 0755}, nil
+	}
+	l, err := fs.list(parent(name))
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	fi, ok := l.files[path.Base(name)]
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return fi, nil
+}
+
+// ReadDir returns the names of the blobs and of the directories below a
+// directory.
+func (fs *FS) ReadDir(name string) ([]string, error) {
+	l, err := fs.list(clean(name))
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return l.names, nil
+}
+
+// Open reads the content of a blob, see tree.FileOpener.
+func (fs *FS) Open(name string) (io.ReadCloser, error) {
+	u := fmt.Sprintf("%s/%s/%s", fs.endpoint(), url.PathEscape(fs.Container), (&url.URL{Path: clean(name)}).EscapedPath())
+	resp, err := fs.get(u, nil)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return resp.Body, nil
+}
+
+// enumerationResults is the response of the List Blobs operation.
+type enumerationResults struct {
+	Blobs struct {
+		Blob []struct {
+			Name       string
+			Properties struct {
+				LastModified  string `xml:"Last-Modified"`
+				ContentLength int64  `xml:"Content-Length"`
+				ResourceType  string
+			}
+			Metadata struct {
+				IsFolder string `xml:"hdi_isfolder"`
+			}
+		}
+		BlobPrefix []struct {
+			Name string
+		}
+	}
+	NextMarker string
+}
+
+// list returns the listing of a directory, "" for the root.
+func (fs *FS) list(dir string) (*listing, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if l, ok := fs.dirs[dir]; ok {
+		return l, nil
+	}
+	prefix := dir
+	if prefix != "" {
+		prefix += "/"
+	}
+	l := &listing{files: make(map[string]*fileInfo)}
+	q := url.Values{"restype": {"container"}, "comp": {"list"}, "delimiter": {"/"}, "prefix": {prefix}, "include": {"metadata"}}
+	for {
+		resp, err := fs.get(fmt.Sprintf("%s/%s", fs.endpoint(), url.PathEscape(fs.Container)), q)
+		if err != nil {
+			return nil, err
+		}
+		var page enumerationResults
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range page.Blobs.BlobPrefix {
+			if name := strings.TrimSuffix(strings.TrimPrefix(p.Name, prefix), "/"); name != "" {
+				l.files[name] = &fileInfo{name: name, mode: os.ModeDir | 0755}
+			}
+		}
+		for _, blob := range page.Blobs.Blob {
+			name := strings.TrimPrefix(blob.Name, prefix)
+			if name == "" || strings.HasSuffix(name, "/") {
+				continue
+			}
+			mtime, _ := time.Parse(time.RFC1123, blob.Properties.LastModified)
+			fi := &fileInfo{name: name, size: blob.Properties.ContentLength, mode: 0644, modTime: mtime}
+			// The real directories of the hierarchical namespace
+			if blob.Properties.ResourceType == "directory" || strings.EqualFold(blob.Metadata.IsFolder, "true") {
+				fi.size, fi.mode = 0, os.ModeDir|0755
+			} else if _, ok := l.files[name]; ok {
+				continue
+			}
+			l.files[name] = fi
+		}
+		if page.NextMarker == "" {
+			break
+		}
+		q.Set("marker", page.NextMarker)
+	}
+	if len(l.files) == 0 && dir != "" && !fs.listedDir(dir) {
+		return nil, os.ErrNotExist
+	}
+	for name := range l.files {
+		l.names = append(l.names, name)
+	}
+	sort.Strings(l.names)
+	if fs.dirs == nil {
+		fs.dirs = make(map[string]*listing)
+	}
+	fs.dirs[dir] = l
+	return l, nil
+}
+
+// listedDir reports whether dir was listed as a directory by its parent,
+// which is how the empty directories are known.
+func (fs *FS) listedDir(dir string) bool {
+	l, ok := fs.dirs[parent(dir)]
+	if !ok {
+		return false
+	}
+	fi, ok := l.files[path.Base(dir)]
+	return ok && fi.IsDir()
+}
+
+// get sends a GET request with the query q and the SAS, and fails on the
+// non-2xx responses.
+func (fs *FS) get(u string, q url.Values) (*http.Response, error) {
+	query := q.Encode()
+	if fs.SAS != "" {
+		if query != "" {
+			query += "&"
+		}
+		query += strings.TrimPrefix(fs.SAS, "?")
+	}
+	if query != "" {
+		u += "?" + query
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-version", apiVersion)
+	client := fs.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, os.ErrNotExist
+		}
+		return nil, fmt.Errorf("azure: %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	return resp, nil
+}
+
+func (fs *FS) endpoint() string {
+	if fs.Endpoint != "" {
+		return strings.TrimSuffix(fs.Endpoint, "/")
+	}
+	return "https://" + fs.Account + ".blob.core.windows.net"
+}
+
+// clean returns the blob name of a path, "" for the root.
+func clean(name string) string {
+	return strings.Trim(path.Clean("/"+name), "/")
+}
+
+// parent returns the parent directory of a blob name, "" for the root.
+func parent(name string) string {
+	if d := path.Dir(name); d != "." {
+		return d
+	}
+	return ""
+}
+
+// fileInfo is the FileInfo of a blob or a directory.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() interface{}   { return nil }
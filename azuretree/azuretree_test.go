@@ -0,0 +1,114 @@
+package azuretree
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/a8m/tree"
+)
+
+// blobs of the mock container; "empty" is a directory of the
+// hierarchical namespace.
+var blobs = map[string]string{
+	"README":        "hello",
+	"empty":         "",
+	"data/a.csv":    "a,b",
+	"data/raw/b.gz": "bb",
+}
+
+// server serves the Blob service API of the blobs, with pages of 1 blob.
+func server(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("sig") != "secret" || r.Header.Get("x-ms-version") == "" {
+			http.Error(w, "unauthorized", http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/container" {
+			content, ok := blobs[strings.TrimPrefix(r.URL.Path, "/container/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte(content))
+			return
+		}
+		prefix := q.Get("prefix")
+		var names []string
+		prefixes := make(map[string]bool)
+		for name := range blobs {
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			if i := strings.IndexByte(name[len(prefix):], '/'); i >= 0 {
+				prefixes[name[:len(prefix)+i+1]] = true
+			} else {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		start, _ := strconv.Atoi(q.Get("marker"))
+		fmt.Fprint(w, "<EnumerationResults><Blobs>")
+		if start == 0 {
+			for p := range prefixes {
+				fmt.Fprintf(w, "<BlobPrefix><Name>%s</Name></BlobPrefix>", p)
+			}
+		}
+		if start < len(names) {
+			name, kind := names[start], "file"
+			if name == "empty" {
+				kind = "directory"
+			}
+			fmt.Fprintf(w, "<Blob><Name>%s</Name><Properties><Last-Modified>Thu, 02 Jan 2020 03:04:05 GMT</Last-Modified>"+
+				"<Content-Length>%d</Content-Length><ResourceType>%s</ResourceType></Properties></Blob>", name, len(blobs[name]), kind)
+		}
+		fmt.Fprint(w, "</Blobs>")
+		if start+1 < len(names) {
+			fmt.Fprintf(w, "<NextMarker>%d</NextMarker>", start+1)
+		}
+		fmt.Fprint(w, "</EnumerationResults>")
+	}))
+}
+
+func TestFS(t *testing.T) {
+	srv := server(t)
+	defer srv.Close()
+	fs := &FS{Container: "container", Endpoint: srv.URL, SAS: "?sv=2020-10-02&sig=secret"}
+	b := new(bytes.Buffer)
+	opts := &tree.Options{Fs: fs, OutFile: b, ByteSize: true}
+	inf := tree.New(".")
+	if d, f := inf.Visit(opts); d != 3 || f != 3 {
+		t.Errorf("expect 3 dirs and 3 files, got %d and %d", d, f)
+	}
+	inf.Print(opts)
+	expected := `[         10]  .
+├── [          5]  README
+├── [          5]  data
+│   ├── [          3]  a.csv
+│   └── [          2]  raw
+│       └── [          2]  b.gz
+└── [          0]  empty
+`
+	if b.String() != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", b, expected)
+	}
+	r, err := fs.Open("data/raw/b.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if content, _ := ioutil.ReadAll(r); string(content) != "bb" {
+		t.Errorf("unexpected content %q", content)
+	}
+	fs = &FS{Container: "container", Endpoint: srv.URL}
+	if _, err := fs.ReadDir("."); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expect a 403 error without the SAS, got %v", err)
+	}
+}
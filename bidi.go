@@ -0,0 +1,32 @@
+package tree
+
+import "unicode"
+
+// The first strong isolate and pop directional isolate marks.
+const (
+	bidiFSI = "⁨"
+	bidiPDI = "⁩"
+)
+
+// rtlScripts are the scripts written from right to left.
+var rtlScripts = []*unicode.RangeTable{
+	unicode.Hebrew,
+	unicode.Arabic,
+	unicode.Syriac,
+	unicode.Thaana,
+	unicode.Nko,
+	unicode.Samaritan,
+	unicode.Mandaic,
+	unicode.Adlam,
+}
+
+// isolateBidi wraps s in bidi isolation marks if it contains right-to-left
+// characters, see the BidiIsolate option.
+func isolateBidi(s string) string {
+	for _, r := range s {
+		if unicode.In(r, rtlScripts...) {
+			return bidiFSI + s + bidiPDI
+		}
+	}
+	return s
+}
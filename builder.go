@@ -0,0 +1,153 @@
+package tree
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// OptionsBuilder builds Options fluently, e.g.:
+//
+//	opts, err := tree.NewOptions().Fs(fs).All().MaxDepth(2).SortBySize().Build()
+//
+// The sort methods replace each other, so only the last one applies. The
+// output defaults to os.Stdout.
+type OptionsBuilder struct {
+	opts Options
+}
+
+// NewOptions returns a new OptionsBuilder.
+func NewOptions() *OptionsBuilder {
+	return &OptionsBuilder{Options{OutFile: os.Stdout}}
+}
+
+// Build validates, and returns the built Options.
+func (b *OptionsBuilder) Build() (*Options, error) {
+	opts := b.opts
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return &opts, nil
+}
+
+// With calls fn with the options being built, for the settings that
+// have no builder method.
+func (b *OptionsBuilder) With(fn func(*Options)) *OptionsBuilder {
+	fn(&b.opts)
+	return b
+}
+
+// Fs sets the file-system to walk.
+func (b *OptionsBuilder) Fs(fs Fs) *OptionsBuilder { b.opts.Fs = fs; return b }
+
+// Output sets the writer the tree is printed to.
+func (b *OptionsBuilder) Output(w io.Writer) *OptionsBuilder { b.opts.OutFile = w; return b }
+
+// All lists hidden files too.
+func (b *OptionsBuilder) All() *OptionsBuilder { b.opts.All = true; return b }
+
+// DirsOnly lists directories only.
+func (b *OptionsBuilder) DirsOnly() *OptionsBuilder { b.opts.DirsOnly = true; return b }
+
+// FullPath prints the full path of each entry.
+func (b *OptionsBuilder) FullPath() *OptionsBuilder { b.opts.FullPath = true; return b }
+
+// FollowLinks follows symbolic links like directories.
+func (b *OptionsBuilder) FollowLinks() *OptionsBuilder { b.opts.FollowLink = true; return b }
+
+// MaxDepth descends only n directories deep.
+func (b *OptionsBuilder) MaxDepth(n int) *OptionsBuilder { b.opts.DeepLevel = n; return b }
+
+// Pattern lists only the files matching the given pattern.
+func (b *OptionsBuilder) Pattern(p string) *OptionsBuilder { b.opts.Pattern = p; return b }
+
+// IPattern excludes the files matching the given pattern.
+func (b *OptionsBuilder) IPattern(p string) *OptionsBuilder { b.opts.IPattern = p; return b }
+
+// Glob interprets the patterns as wildcards.
+func (b *OptionsBuilder) Glob() *OptionsBuilder { b.opts.Glob = true; return b }
+
+// IgnoreCase ignores case when matching patterns.
+func (b *OptionsBuilder) IgnoreCase() *OptionsBuilder { b.opts.IgnoreCase = true; return b }
+
+// SizeRange lists only the files of min to max bytes. Zero means
+// unbounded.
+func (b *OptionsBuilder) SizeRange(min, max int64) *OptionsBuilder {
+	b.opts.MinSize, b.opts.MaxSize = min, max
+	return b
+}
+
+// ModifiedWithin lists only the files modified within the given duration.
+func (b *OptionsBuilder) ModifiedWithin(d time.Duration) *OptionsBuilder {
+	b.opts.NewerThan = time.Now().Add(-d)
+	return b
+}
+
+// Filter sets a programmatic filter, see Options.Filter.
+func (b *OptionsBuilder) Filter(fn func(*Node) bool) *OptionsBuilder { b.opts.Filter = fn; return b }
+
+// Prune removes the directories left empty by the filters.
+func (b *OptionsBuilder) Prune() *OptionsBuilder { b.opts.Prune = true; return b }
+
+// ByteSize prints the size of each entry in bytes.
+func (b *OptionsBuilder) ByteSize() *OptionsBuilder { b.opts.ByteSize = true; return b }
+
+// HumanSize prints the size of each entry in a human readable way.
+func (b *OptionsBuilder) HumanSize() *OptionsBuilder { b.opts.UnitSize = true; return b }
+
+// FileMode prints the permissions of each file.
+func (b *OptionsBuilder) FileMode() *OptionsBuilder { b.opts.FileMode = true; return b }
+
+// LastMod prints the last modification time of each file.
+func (b *OptionsBuilder) LastMod() *OptionsBuilder { b.opts.LastMod = true; return b }
+
+// Colorize turns colorization on.
+func (b *OptionsBuilder) Colorize() *OptionsBuilder { b.opts.Colorize = true; return b }
+
+// NoIndent doesn't print the indentation lines.
+func (b *OptionsBuilder) NoIndent() *OptionsBuilder { b.opts.NoIndent = true; return b }
+
+// Reverse reverses the order of the sort.
+func (b *OptionsBuilder) Reverse() *OptionsBuilder { b.opts.ReverSort = true; return b }
+
+// Unsorted leaves the entries unsorted.
+func (b *OptionsBuilder) Unsorted() *OptionsBuilder { b.setSort(nil); b.opts.NoSort = true; return b }
+
+// SortByName sorts the entries by name.
+func (b *OptionsBuilder) SortByName() *OptionsBuilder { b.setSort(&b.opts.NameSort); return b }
+
+// SortByNameFold sorts the entries by name case-insensitively, and also
+// ignoring their leading dots if ignoreDots is set.
+func (b *OptionsBuilder) SortByNameFold(ignoreDots bool) *OptionsBuilder {
+	b.setSort(&b.opts.NameSort)
+	b.opts.FoldSort, b.opts.DotlessSort = true, ignoreDots
+	return b
+}
+
+// SortByVersion sorts the entries alphanumerically by version.
+func (b *OptionsBuilder) SortByVersion() *OptionsBuilder { b.setSort(&b.opts.VerSort); return b }
+
+// SortBySize sorts the entries by size.
+func (b *OptionsBuilder) SortBySize() *OptionsBuilder { b.setSort(&b.opts.SizeSort); return b }
+
+// SortByExtension sorts the entries by extension, and then by name.
+func (b *OptionsBuilder) SortByExtension() *OptionsBuilder { b.setSort(&b.opts.ExtSort); return b }
+
+// SortByModTime sorts the entries by last modification time.
+func (b *OptionsBuilder) SortByModTime() *OptionsBuilder { b.setSort(&b.opts.ModSort); return b }
+
+// SortByChangeTime sorts the entries by last status change time.
+func (b *OptionsBuilder) SortByChangeTime() *OptionsBuilder { b.setSort(&b.opts.CTimeSort); return b }
+
+// DirsFirst lists directories before files.
+func (b *OptionsBuilder) DirsFirst() *OptionsBuilder { b.opts.DirSort = true; return b }
+
+// setSort clears the sort options, and sets the given one.
+func (b *OptionsBuilder) setSort(sort *bool) {
+	o := &b.opts
+	o.NoSort, o.NameSort, o.VerSort, o.SizeSort = false, false, false, false
+	o.ModSort, o.CTimeSort, o.ExtSort = false, false, false
+	if sort != nil {
+		*sort = true
+	}
+}
@@ -0,0 +1,26 @@
+package tree
+
+import "testing"
+
+func TestOptionsBuilder(t *testing.T) {
+	opts, err := NewOptions().Fs(fs).Output(out).All().MaxDepth(2).SortByModTime().SortBySize().Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !opts.All || opts.DeepLevel != 2 || !opts.SizeSort || opts.ModSort {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+	opts, err = NewOptions().Fs(fs).Output(out).SortBySize().DirsFirst().SortByModTime().Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !opts.DirSort || !opts.ModSort || opts.SizeSort {
+		t.Errorf("unexpected sort options: %+v", opts)
+	}
+	if _, err := NewOptions().Output(out).Build(); err == nil || err.Error() != "missing Fs option" {
+		t.Errorf("expected missing Fs error, got: %v", err)
+	}
+	if _, err := NewOptions().Fs(fs).Pattern("(a").Build(); err == nil {
+		t.Error("expected invalid Pattern error")
+	}
+}
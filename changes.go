@@ -0,0 +1,268 @@
+package tree
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// The kinds of Change.
+const (
+	Added    = "added"
+	Removed  = "removed"
+	Resized  = "resized"
+	Retimed  = "retimed"
+	Modified = "modified"
+)
+
+// Change is the difference of an entry between a snapshot and a walk.
+type Change struct {
+	// Kind is Added, Removed, Resized, Retimed (same size, another
+	// modification time) or Modified (same size and time, but another type
+	// or checksum).
+	Kind string `json:"change"`
+	// Path is the slash-separated path of the entry, relative to the root.
+	Path string `json:"path"`
+	// Old and New are the entry in the snapshot (or the manifest) and in
+	// the walk, without their Contents.
+	Old *Entry `json:"old,omitempty"`
+	New *Entry `json:"new,omitempty"`
+	// Mismatches are the properties that don't match the manifest of a
+	// Mismatched entry: "type", "size", "mode", "link" or "checksum".
+	Mismatches []string `json:"mismatches,omitempty"`
+}
+
+// WriteSnapshot writes a visited node as a snapshot: a Document of its
+// entries, on a single JSON line. The snapshots of several roots may be
+// written one after the other.
+func WriteSnapshot(w io.Writer, node *Node) error {
+	return json.NewEncoder(w).Encode(&Document{
+		Version: SchemaVersion,
+		Tree:    []*Entry{NewEntry(node)},
+	})
+}
+
+// ReadSnapshot reads the Documents written by WriteSnapshot (or served by
+// Handler), and returns their roots. The JSON option output, in the GNU
+// tree -J format, isn't a snapshot and is rejected.
+func ReadSnapshot(r io.Reader) ([]*Entry, error) {
+	var roots []*Entry
+	dec := json.NewDecoder(r)
+	for {
+		var doc Document
+		if err := dec.Decode(&doc); err == io.EOF {
+			return roots, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if doc.Version != SchemaVersion {
+			return nil, fmt.Errorf("snapshot version %d not supported, should be %d", doc.Version, SchemaVersion)
+		}
+		roots = append(roots, doc.Tree...)
+	}
+}
+
+// Diff returns the changes from the old entry to the new one, parents
+// before their children and siblings sorted by name. The contents of the
+// added and removed directories are reported too, but the directories
+// are only compared by their contents.
+func Diff(old, new *Entry) []*Change {
+	var changes []*Change
+	diffEntries("", old, new, diffChange).walk(func(c *changeNode) {
+		if c.change != nil {
+			changes = append(changes, c.change)
+		}
+	})
+	return changes
+}
+
+// changeNode is an entry of the tree of changes.
+type changeNode struct {
+	name     string
+	change   *Change
+	children []*changeNode
+}
+
+func (c *changeNode) walk(fn func(*changeNode)) {
+	if c == nil {
+		return
+	}
+	fn(c)
+	for _, child := range c.children {
+		child.walk(fn)
+	}
+}
+
+// diffEntries returns the tree of changes of an entry at the given path,
+// as returned by compare for it and each of its children, or nil if none
+// changed.
+func diffEntries(rel string, old, new *Entry, compare func(old, new *Entry) *Change) *changeNode {
+	if old == nil && new == nil {
+		return nil
+	}
+	c := &changeNode{}
+	if new != nil {
+		c.name = new.Name
+	} else {
+		c.name = old.Name
+	}
+	if c.change = compare(old, new); c.change != nil {
+		c.change.Path = rel
+		if rel == "" {
+			c.change.Path = "."
+		}
+	}
+	olds, news := contentsByName(old), contentsByName(new)
+	var names []string
+	for name := range news {
+		names = append(names, name)
+	}
+	for name := range olds {
+		if _, ok := news[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		crel := name
+		if rel != "" {
+			crel = rel + "/" + name
+		}
+		if child := diffEntries(crel, olds[name], news[name], compare); child != nil {
+			c.children = append(c.children, child)
+		}
+	}
+	if c.change == nil && c.children == nil {
+		return nil
+	}
+	return c
+}
+
+// diffChange returns the change of an entry, or nil.
+func diffChange(old, new *Entry) *Change {
+	switch {
+	case old == nil:
+		return &Change{Kind: Added, New: shallowEntry(new)}
+	case new == nil:
+		return &Change{Kind: Removed, Old: shallowEntry(old)}
+	}
+	if kind := entryChange(old, new); kind != "" {
+		return &Change{Kind: kind, Old: shallowEntry(old), New: shallowEntry(new)}
+	}
+	return nil
+}
+
+// entryChange returns the kind of change of a kept entry, or "".
+func entryChange(old, new *Entry) string {
+	switch {
+	case old.Type != new.Type:
+		return Modified
+	case new.Type == "directory":
+		return ""
+	case old.Size != new.Size:
+		return Resized
+	case !old.ModTime.Equal(new.ModTime):
+		return Retimed
+	}
+	if sum, ok := new.Meta[checksumKey].(string); ok {
+		if osum, ok := old.Meta[checksumKey].(string); ok && osum != sum {
+			return Modified
+		}
+	}
+	return ""
+}
+
+// contentsByName returns the children of an entry by name.
+func contentsByName(e *Entry) map[string]*Entry {
+	m := make(map[string]*Entry)
+	if e != nil {
+		for _, child := range e.Contents {
+			m[child.Name] = child
+		}
+	}
+	return m
+}
+
+// shallowEntry returns a copy of an entry, without its Contents.
+func shallowEntry(e *Entry) *Entry {
+	c := *e
+	c.Contents = nil
+	return &c
+}
+
+// rootEntry returns the entry of roots for the given node, the only one
+// if none has its path.
+func rootEntry(roots []*Entry, node *Node) *Entry {
+	for _, e := range roots {
+		if e.Path == node.path {
+			return e
+		}
+	}
+	if len(roots) == 1 {
+		return roots[0]
+	}
+	return nil
+}
+
+// printChanges prints the changes of the visited node against the given
+// roots, as NDJSON lines with ChangesJSON, or else as a tree of the
+// changed entries and their parents. It returns the number of changes.
+func (node *Node) printChanges(opts *Options, roots []*Entry, compare func(old, new *Entry) *Change) (n int) {
+	root := NewEntry(node)
+	c := diffEntries("", rootEntry(roots, node), root, compare)
+	c.walk(func(c *changeNode) {
+		if c.change != nil {
+			n++
+		}
+	})
+	if opts.ChangesJSON {
+		enc := json.NewEncoder(opts.OutFile)
+		c.walk(func(c *changeNode) {
+			if c.change != nil {
+				enc.Encode(c.change)
+			}
+		})
+		return n
+	}
+	if c == nil {
+		c = &changeNode{}
+	}
+	// The root is printed as walked
+	c.name = root.Name
+	c.print("", opts.lines(), opts)
+	return n
+}
+
+func (c *changeNode) print(indent string, lines indentLines, opts *Options) {
+	name := c.name
+	if ch := c.change; ch != nil {
+		label := ch.Kind
+		switch ch.Kind {
+		case Resized:
+			label = fmt.Sprintf("%s %d -> %d", label, ch.Old.Size, ch.New.Size)
+		case Retimed:
+			label = fmt.Sprintf("%s %s -> %s", label, ch.Old.ModTime.Format(opts.timeFormat()), ch.New.ModTime.Format(opts.timeFormat()))
+		case Modified:
+			if ch.Old.Type != ch.New.Type {
+				label = fmt.Sprintf("%s %s -> %s", label, ch.Old.Type, ch.New.Type)
+			}
+		case Mismatched:
+			label += " " + mismatchLabel(ch)
+		}
+		name = "[" + label + "]  " + name
+	}
+	fmt.Fprintln(opts.OutFile, name)
+	for i, child := range c.children {
+		add := lines.vertical
+		if i == len(c.children)-1 {
+			fmt.Fprint(opts.OutFile, indent+lines.last)
+			add = strings.Repeat(" ", utf8.RuneCountInString(lines.vertical))
+		} else {
+			fmt.Fprint(opts.OutFile, indent+lines.branch)
+		}
+		child.print(indent+add, lines, opts)
+	}
+}
@@ -0,0 +1,65 @@
+package tree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChanges(t *testing.T) {
+	t0, t1 := time.Unix(1546387200, 0), time.Unix(1546473600, 0)
+	root := &file{name: "root", files: []*file{
+		{name: "a", size: 5, lastMod: t0},
+		{name: "b", size: 5, lastMod: t0},
+		{name: "old", files: []*file{{name: "x", lastMod: t0}}},
+		{name: "sub", files: []*file{{name: "c", lastMod: t0}, {name: "d", lastMod: t0}}},
+	}}
+	fs.clean().addFile(root.name, root)
+	snapshot := new(bytes.Buffer)
+	inf := New("root")
+	inf.Visit(&Options{Fs: fs, OutFile: out})
+	inf.Print(&Options{Fs: fs, OutFile: out, Snapshot: snapshot})
+	out.clear()
+	baseline, err := ReadSnapshot(snapshot)
+	if err != nil || len(baseline) != 1 {
+		t.Fatalf("unexpected snapshot %v, %v", baseline, err)
+	}
+	root = &file{name: "root", files: []*file{
+		{name: "a", size: 12, lastMod: t1},
+		{name: "b", size: 5, lastMod: t0},
+		{name: "new", lastMod: t1},
+		{name: "sub", files: []*file{{name: "c", lastMod: t0}, {name: "d", lastMod: t1}}},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"changes", &Options{Fs: fs, OutFile: out, Baseline: baseline, TimeFormat: "2006-01-02"}, `root
+├── [resized 5 -> 12]  a
+├── [added]  new
+├── [removed]  old
+│   └── [removed]  x
+└── sub
+    └── [retimed 2019-01-02 -> 2019-01-03]  d
+`, 1, 5},
+	})
+	inf = New("root")
+	inf.Visit(&Options{Fs: fs, OutFile: out})
+	if changes := Diff(NewEntry(inf), NewEntry(inf)); changes != nil {
+		t.Errorf("expect no changes, got %v", changes)
+	}
+	var kinds []string
+	for _, c := range Diff(baseline[0], NewEntry(inf)) {
+		kinds = append(kinds, c.Kind+" "+c.Path)
+	}
+	expected := "resized a,added new,removed old,removed old/x,retimed sub/d"
+	if got := strings.Join(kinds, ","); got != expected {
+		t.Errorf("Diff = %s, expected %s", got, expected)
+	}
+	// NDJSON
+	b := new(bytes.Buffer)
+	inf.Print(&Options{Fs: fs, OutFile: b, Baseline: baseline, ChangesJSON: true})
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], `{"change":"resized","path":"a","old":{"type":"file","name":"a"`) {
+		t.Errorf("unexpected NDJSON changes:\n%s", b)
+	}
+}
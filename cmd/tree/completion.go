@@ -0,0 +1,170 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/a8m/tree"
+)
+
+var cmdUsage = `
+Directories like host:path are listed over ssh, like scp's. The remote
+host needs GNU find, and TREE_SSH sets the ssh command (e.g. "ssh -p 2222").
+
+Default options are read from the tree/config file of the user configuration
+directory (e.g. ~/.config/tree/config), as "flag = value" lines.
+`
+
+// flagArgs are the completed values of the flags that take a fixed set
+// of values.
+var flagArgs = map[string][]string{
+	"sort":       {"name", "version", "size", "mtime", "ctime", "extension"},
+	"type":       {"f", "l", "s", "p", "b", "c"},
+	"charset":    {"utf-8", "ascii"},
+	"errors":     {"text", "json", "inline"},
+	"icon-set":   {"emoji", "nerd"},
+	"checksum":   {"md5", "sha1", "sha256"},
+	"link-graph": {"dot", "json"},
+	"completion": {"bash", "zsh", "fish", "powershell"},
+}
+
+// completionFlag is a flag, as shown in the completion scripts.
+type completionFlag struct {
+	name  string // with its dashes, e.g. "-a" or "--sort"
+	short bool
+	desc  string
+	// takes an argument, and its completed values
+	arg  bool
+	args []string
+	file bool
+}
+
+// usageLine matches a flag line of tree.Usage.
+var usageLine = regexp.MustCompile(`^    -{1,2}([\w-]+)(?:, --([\w-]+))?(?: (?:[A-Z]|filename))?\s+(.*)$`)
+
+// completionFlags returns the flags of tree.ParseFlags, with the
+// descriptions of tree.Usage.
+func completionFlags() []completionFlag {
+	descs := make(map[string]string)
+	for _, line := range strings.Split(tree.Usage, "\n") {
+		if m := usageLine.FindStringSubmatch(line); m != nil {
+			descs[m[1]], descs[m[2]] = m[3], m[3]
+		}
+	}
+	var flags []completionFlag
+	tree.NewFlagSet().VisitAll(func(f *flag.Flag) {
+		cf := completionFlag{name: "--" + f.Name, desc: descs[f.Name]}
+		if len(f.Name) == 1 {
+			cf.name, cf.short = "-"+f.Name, true
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+			cf.arg, cf.args, cf.file = true, flagArgs[f.Name], f.Name == "o" || f.Name == "output" ||
+				f.Name == "snapshot" || f.Name == "changes" || f.Name == "verify"
+			// The formats are the ones registered by the imported packages
+			if f.Name == "format" {
+				cf.args = tree.Formats()
+			}
+		}
+		flags = append(flags, cf)
+	})
+	return flags
+}
+
+// completion writes the completion script of the given shell.
+func completion(w io.Writer, shell string) error {
+	flags := completionFlags()
+	switch shell {
+	case "bash":
+		bashCompletion(w, flags)
+	case "zsh":
+		zshCompletion(w, flags)
+	case "fish":
+		fishCompletion(w, flags)
+	case "powershell":
+		powershellCompletion(w, flags)
+	default:
+		return fmt.Errorf("completion shell '%s' not valid, should be one of: "+
+			"bash,zsh,fish,powershell", shell)
+	}
+	return nil
+}
+
+func bashCompletion(w io.Writer, flags []completionFlag) {
+	var names []string
+	fmt.Fprint(w, "# bash completion for tree\n_tree() {\n")
+	fmt.Fprint(w, "    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprint(w, "    case \"$prev\" in\n")
+	for _, f := range flags {
+		names = append(names, f.name)
+		switch {
+		case f.file:
+			fmt.Fprintf(w, "        %s) COMPREPLY=($(compgen -f -- \"$cur\")); return;;\n", f.name)
+		case f.args != nil:
+			fmt.Fprintf(w, "        %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); return;;\n", f.name, strings.Join(f.args, " "))
+		case f.arg:
+			fmt.Fprintf(w, "        %s) return;;\n", f.name)
+		}
+	}
+	fmt.Fprint(w, "    esac\n")
+	fmt.Fprint(w, "    if [[ \"$cur\" == -* ]]; then\n")
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(names, " "))
+	fmt.Fprint(w, "    else\n        COMPREPLY=($(compgen -d -- \"$cur\"))\n    fi\n}\n")
+	fmt.Fprint(w, "complete -F _tree tree\n")
+}
+
+func zshCompletion(w io.Writer, flags []completionFlag) {
+	quote := strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`)
+	fmt.Fprint(w, "#compdef tree\n\n_arguments \\\n")
+	for _, f := range flags {
+		spec := fmt.Sprintf("%s[%s]", f.name, quote.Replace(f.desc))
+		switch {
+		case f.file:
+			spec += ":file:_files"
+		case f.args != nil:
+			spec += fmt.Sprintf(":value:(%s)", strings.Join(f.args, " "))
+		case f.arg:
+			spec += ":value: "
+		}
+		fmt.Fprintf(w, "    '%s' \\\n", spec)
+	}
+	fmt.Fprint(w, "    '*:directory:_files -/'\n")
+}
+
+func fishCompletion(w io.Writer, flags []completionFlag) {
+	quote := strings.NewReplacer(`\`, `\\`, "'", `\'`)
+	fmt.Fprint(w, "# fish completion for tree\n")
+	for _, f := range flags {
+		opt := "-l " + strings.TrimPrefix(f.name, "--")
+		if f.short {
+			opt = "-s " + strings.TrimPrefix(f.name, "-")
+		}
+		switch {
+		case f.file:
+			opt += " -r -F"
+		case f.args != nil:
+			opt += fmt.Sprintf(" -x -a '%s'", strings.Join(f.args, " "))
+		case f.arg:
+			opt += " -x"
+		}
+		fmt.Fprintf(w, "complete -c tree %s -d '%s'\n", opt, quote.Replace(f.desc))
+	}
+}
+
+func powershellCompletion(w io.Writer, flags []completionFlag) {
+	quote := strings.NewReplacer("'", "''")
+	fmt.Fprint(w, "# powershell completion for tree\n")
+	fmt.Fprint(w, "Register-ArgumentCompleter -Native -CommandName tree -ScriptBlock {\n")
+	fmt.Fprint(w, "    param($wordToComplete, $commandAst, $cursorPosition)\n    @(\n")
+	for _, f := range flags {
+		desc := f.desc
+		if desc == "" {
+			desc = f.name
+		}
+		fmt.Fprintf(w, "        [System.Management.Automation.CompletionResult]::new('%s', '%s', 'ParameterName', '%s')\n",
+			f.name, f.name, quote.Replace(desc))
+	}
+	fmt.Fprint(w, "    ) | Where-Object { $_.CompletionText -like \"$wordToComplete*\" }\n}\n")
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/a8m/tree/sshtree"
+)
+
+// remoteDir splits a "host:path" directory like scp's, unless it's a
+// local path or the host starts with '-', like an ssh option.
+func remoteDir(dir string) (host, path string, ok bool) {
+	i := strings.IndexByte(dir, ':')
+	if i < 2 || strings.ContainsAny(dir[:i], `/\`) || dir[0] == '-' {
+		return "", "", false
+	}
+	if _, err := os.Lstat(dir); err == nil {
+		return "", "", false
+	}
+	if path = dir[i+1:]; path == "" {
+		path = "."
+	}
+	return dir[:i], path, true
+}
+
+// remoteFs returns the FS of the directories if they're on a remote host,
+// and their remote paths. The ssh command can be set by TREE_SSH, e.g.
+// "ssh -p 2222".
+func remoteFs(dirs []string) (*sshtree.FS, []string, error) {
+	var host string
+	var local int
+	paths := make([]string, len(dirs))
+	for i, dir := range dirs {
+		h, path, ok := remoteDir(dir)
+		switch {
+		case !ok:
+			local++
+		case host == "":
+			host = h
+		case h != host:
+			return nil, nil, errors.New("cannot list the directories of several hosts at once")
+		}
+		paths[i] = path
+	}
+	switch {
+	case host == "":
+		return nil, dirs, nil
+	case local > 0:
+		return nil, nil, errors.New("cannot mix local and remote directories")
+	}
+	fs := sshtree.New(host)
+	fs.Command = strings.Fields(os.Getenv("TREE_SSH"))
+	return fs, paths, nil
+}
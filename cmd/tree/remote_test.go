@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemoteFs(t *testing.T) {
+	for _, test := range []struct {
+		dirs  []string
+		host  string
+		paths []string
+		err   string
+	}{
+		{dirs: []string{".", "a"}, paths: []string{".", "a"}},
+		{dirs: []string{"host:"}, host: "host", paths: []string{"."}},
+		{dirs: []string{"host:a", "host:/b"}, host: "host", paths: []string{"a", "/b"}},
+		{dirs: []string{"-oProxyCommand=x:a"}, paths: []string{"-oProxyCommand=x:a"}},
+		{dirs: []string{".", "host:a"}, err: "cannot mix local and remote directories"},
+		{dirs: []string{"host:a", "."}, err: "cannot mix local and remote directories"},
+		{dirs: []string{"host:a", "other:b"}, err: "cannot list the directories of several hosts at once"},
+	} {
+		fs, paths, err := remoteFs(test.dirs)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%v: got error %v, expected %s", test.dirs, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.dirs, err)
+			continue
+		}
+		var host string
+		if fs != nil {
+			host = fs.Host
+		}
+		if host != test.host {
+			t.Errorf("%v: got host %q, expected %q", test.dirs, host, test.host)
+		}
+		if !reflect.DeepEqual(paths, test.paths) {
+			t.Errorf("%v: got paths %q, expected %q", test.dirs, paths, test.paths)
+		}
+	}
+}
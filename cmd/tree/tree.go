@@ -1,7 +1,6 @@
 package main
 
 import (
-	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -10,163 +9,125 @@ import (
 	"github.com/a8m/tree/ostree"
 )
 
-var (
-	// List
-	a          = flag.Bool("a", false, "")
-	d          = flag.Bool("d", false, "")
-	f          = flag.Bool("f", false, "")
-	ignorecase = flag.Bool("ignore-case", false, "")
-	noreport   = flag.Bool("noreport", false, "")
-	l          = flag.Bool("l", false, "")
-	L          = flag.Int("L", 3, "")
-	P          = flag.String("P", "", "")
-	I          = flag.String("I", "", "")
-	o          = flag.String("o", "", "")
-	// Files
-	s      = flag.Bool("s", false, "")
-	h      = flag.Bool("h", false, "")
-	p      = flag.Bool("p", false, "")
-	u      = flag.Bool("u", false, "")
-	g      = flag.Bool("g", false, "")
-	Q      = flag.Bool("Q", false, "")
-	D      = flag.Bool("D", false, "")
-	inodes = flag.Bool("inodes", false, "")
-	device = flag.Bool("device", false, "")
-	// Sort
-	U         = flag.Bool("U", false, "")
-	v         = flag.Bool("v", false, "")
-	t         = flag.Bool("t", false, "")
-	c         = flag.Bool("c", false, "")
-	r         = flag.Bool("r", false, "")
-	dirsfirst = flag.Bool("dirsfirst", false, "")
-	sort      = flag.String("sort", "", "")
-	// Graphics
-	i = flag.Bool("i", false, "")
-	C = flag.Bool("C", false, "")
-)
-
-var usage = `Usage: tree [options...] [paths...]
-
-Options:
-    ------- Listing options -------
-    -a		    All files are listed.
-    -d		    List directories only.
-    -l		    Follow symbolic links like directories.
-    -f		    Print the full path prefix for each file.
-    -L		    Descend only level directories deep.
-    -P		    List only those files that match the pattern given.
-    -I		    Do not list files that match the given pattern.
-    --ignore-case   Ignore case when pattern matching.
-    --noreport	    Turn off file/directory count at end of tree listing.
-    -o filename	    Output to file instead of stdout.
-    -------- File options ---------
-    -Q		    Quote filenames with double quotes.
-    -p		    Print the protections for each file.
-    -u		    Displays file owner or UID number.
-    -g		    Displays file group owner or GID number.
-    -s		    Print the size in bytes of each file.
-    -h		    Print the size in a more human readable way.
-    -D		    Print the date of last modification or (-c) status change.
-    --inodes	    Print inode number of each file.
-    --device	    Print device ID number to which each file belongs.
-    ------- Sorting options -------
-    -v		    Sort files alphanumerically by version.
-    -t		    Sort files by last modification time.
-    -c		    Sort files by last status change time.
-    -U		    Leave files unsorted.
-    -r		    Reverse the order of the sort.
-    --dirsfirst	    List directories before files (-U disables).
-    --sort X	    Select sort: name,version,size,mtime,ctime.
-    ------- Graphics options ------
-    -i		    Don't print indentation lines.
-    -C		    Turn colorization on always.
-`
-
 func main() {
-	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
-	var nd, nf int
+	flag.Usage = func() { fmt.Fprint(os.Stderr, tree.Usage+cmdUsage) }
 	var dirs = []string{"."}
-	flag.Parse()
-	// Make it work with leading dirs
-	if args := flag.Args(); len(args) > 0 {
-		dirs = args
-	}
-	// Output file
-	var outFile = os.Stdout
-	var err error
-	if *o != "" {
-		outFile, err = os.Create(*o)
+	// Config file, overridden by the environment, and explicit flags
+	args := append(tree.EnvArgs(), os.Args[1:]...)
+	if path, err := tree.ConfigPath(); err == nil {
+		config, err := tree.LoadConfig(path)
 		if err != nil {
 			errAndExit(err)
 		}
+		args = append(config, args...)
+	}
+	opts, targs, err := tree.ParseFlags(args)
+	if err == flag.ErrHelp {
+		flag.Usage()
+		os.Exit(0)
+	}
+	if err != nil {
+		errAndExit(err)
+	}
+	// Shell completion
+	if targs.Completion != "" {
+		if err := completion(os.Stdout, targs.Completion); err != nil {
+			errAndExit(err)
+		}
+		return
+	}
+	if err := targs.ReadStdin(); err != nil {
+		errAndExit(err)
+	}
+	opts.Fs = new(ostree.FS)
+	// Make it work with leading dirs
+	if len(targs.Paths) > 0 {
+		dirs = targs.Paths
+	}
+	// Remote directories, listed over ssh
+	fs, dirs, err := remoteFs(dirs)
+	if err != nil {
+		errAndExit(err)
 	}
-	defer outFile.Close()
-	// Check sort-type
-	if *sort != "" {
-		switch *sort {
-		case "version", "mtime", "ctime", "name", "size":
-		default:
-			msg := fmt.Sprintf("sort type '%s' not valid, should be one of: "+
-				"name,version,size,mtime,ctime", *sort)
-			errAndExit(errors.New(msg))
+	if fs != nil {
+		if opts.DeepLevel > 0 {
+			fs.MaxDepth = opts.DeepLevel + 1
 		}
+		opts.Fs = fs
 	}
-	// Set options
-	opts := &tree.Options{
-		// Required
-		Fs:      new(ostree.FS),
-		OutFile: outFile,
-		// List
-		All:        *a,
-		DirsOnly:   *d,
-		FullPath:   *f,
-		DeepLevel:  *L,
-		FollowLink: *l,
-		Pattern:    *P,
-		IPattern:   *I,
-		IgnoreCase: *ignorecase,
-		// Files
-		ByteSize: *s,
-		UnitSize: *h,
-		FileMode: *p,
-		ShowUid:  *u,
-		ShowGid:  *g,
-		LastMod:  *D,
-		Quotes:   *Q,
-		Inodes:   *inodes,
-		Device:   *device,
-		// Sort
-		NoSort:    *U,
-		ReverSort: *r,
-		DirSort:   *dirsfirst,
-		VerSort:   *v || *sort == "version",
-		ModSort:   *t || *sort == "mtime",
-		CTimeSort: *c || *sort == "ctime",
-		NameSort:  *sort == "name",
-		SizeSort:  *sort == "size",
-		// Graphics
-		NoIndent: *i,
-		Colorize: *C,
-	}
-	for _, dir := range dirs {
-		inf := tree.New(dir)
-		d, f := inf.Visit(opts)
-		nd, nf = nd+d, nf+f
-		inf.Print(opts)
+	if err := opts.Validate(); err != nil {
+		errAndExit(err)
 	}
+	// Output files, opened once the options are valid
+	if err := targs.Open(opts); err != nil {
+		errAndExit(err)
+	}
+	res := tree.Run(dirs, opts)
 	// Print footer report
-	if !*noreport {
-		footer := fmt.Sprintf("\n%d directories", nd)
+	if !opts.NoReport {
+		footer := "\n" + count(res.Dirs, res.ScannedDirs, "directories")
 		if !opts.DirsOnly {
-			footer += fmt.Sprintf(", %d files", nf)
+			footer += ", " + count(res.Files, res.ScannedFiles, "files")
+		}
+		if res.Dirs != res.ScannedDirs || (!opts.DirsOnly && res.Files != res.ScannedFiles) {
+			footer += " matched"
 		}
-		fmt.Fprintln(outFile, footer)
+		if res.BrokenLinks > 0 {
+			footer += fmt.Sprintf(", %d broken links", res.BrokenLinks)
+		}
+		fmt.Fprintln(opts.OutFile, footer)
+	}
+	// Print errors summary
+	if opts.ErrorSummary && len(res.Errors) > 0 {
+		if opts.ErrFile != nil {
+			tree.FprintErrors(opts.ErrFile, res.Errors)
+		} else {
+			fmt.Fprintln(opts.OutFile)
+			tree.FprintErrors(opts.OutFile, res.Errors)
+		}
+	}
+	// Print audit summary
+	if opts.Audit {
+		fmt.Fprintln(opts.OutFile)
+		tree.FprintAudit(opts.OutFile, res.Findings)
+	}
+	// Print stale files report
+	if !opts.StaleSince.IsZero() {
+		fmt.Fprintln(opts.OutFile)
+		tree.FprintStale(opts.OutFile, res.Stale)
+	}
+	// Print hardlinks report
+	if opts.Hardlinks {
+		fmt.Fprintln(opts.OutFile)
+		tree.FprintHardlinks(opts.OutFile, res.Hardlinks)
+	}
+	// Print filesystems report
+	if opts.FsUsage && len(res.Mounts) > 0 {
+		fmt.Fprintln(opts.OutFile)
+		tree.FprintMounts(opts.OutFile, res.Mounts)
+	}
+	if f, ok := opts.OutFile.(*os.File); ok {
+		f.Close()
+	}
+	if f, ok := opts.Snapshot.(*os.File); ok {
+		f.Close()
+	}
+	os.Exit(res.ExitCode())
+}
+
+// count formats the number of matched entries, out of the scanned ones
+// when they differ. The numbers aren't grouped, like GNU tree's, so the
+// scripts parsing the report keep working.
+func count(matched, scanned int, what string) string {
+	if matched == scanned {
+		return fmt.Sprintf("%d %s", matched, what)
 	}
+	return fmt.Sprintf("%d of %d %s", matched, scanned, what)
 }
 
 func usageAndExit(msg string) {
 	if msg != "" {
-		fmt.Fprintf(os.Stderr, msg)
+		fmt.Fprint(os.Stderr, msg)
 		fmt.Fprintf(os.Stderr, "\n\n")
 	}
 	flag.Usage()
@@ -66,6 +66,110 @@ func ANSIColor(node *Node, s string) string {
 	return ANSIColorFormat(style, s)
 }
 
+// ParseColors returns a Color function for the given LS_COLORS like
+// specification, e.g. "di=01;34:ln=01;36:*.go=00;36". The supported
+// keys are di, ln, or (orphan link), pi, so, bd, cd, ex, fi, and '*'
+// suffixes for the regular files. Entries without a style are printed
+// as is.
+func ParseColors(spec string) (func(*Node, string) string, error) {
+	styles := make(map[string]string)
+	var suffixes []string
+	for _, entry := range strings.Split(spec, ":") {
+		if entry == "" {
+			continue
+		}
+		i := strings.IndexByte(entry, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid colors entry '%s'", entry)
+		}
+		key, style := entry[:i], entry[i+1:]
+		if strings.Trim(style, "0123456789;") != "" {
+			return nil, fmt.Errorf("invalid style '%s' in colors entry '%s'", style, entry)
+		}
+		if key[0] == '*' {
+			key = strings.ToLower(key[1:])
+			suffixes = append(suffixes, key)
+		}
+		styles[key] = style
+	}
+	return func(node *Node, s string) string {
+		var key string
+		var mode = node.Mode()
+		switch {
+		case node.IsDir() || mode&os.ModeDir != 0:
+			key = "di"
+		case mode&os.ModeSymlink != 0:
+			key = "ln"
+			if _, err := filepath.EvalSymlinks(node.path); err != nil && styles["or"] != "" {
+				key = "or"
+			}
+		case mode&os.ModeNamedPipe != 0:
+			key = "pi"
+		case mode&os.ModeSocket != 0:
+			key = "so"
+		case mode&os.ModeCharDevice != 0:
+			key = "cd"
+		case mode&os.ModeDevice != 0:
+			key = "bd"
+		case mode&modeExecute != 0:
+			key = "ex"
+		default:
+			key = "fi"
+			name := strings.ToLower(node.Name())
+			for _, suffix := range suffixes {
+				if strings.HasSuffix(name, suffix) {
+					key = suffix
+				}
+			}
+		}
+		if style := styles[key]; style != "" {
+			return ANSIColorFormat(style, s)
+		}
+		return s
+	}, nil
+}
+
+// HighlightStyle is the style used to highlight pattern matches in names.
+const HighlightStyle = "7"
+
+// colorize colors the given name, and highlights the part of it that
+// matches the Pattern option if Highlight is set.
+func (opts *Options) colorize(node *Node, name string) string {
+	cnode := node
+	if opts.MagicColor {
+		cnode = opts.magicNode(node)
+	}
+	if !opts.Highlight || opts.Pattern == "" || node.IsDir() {
+		return opts.color(cnode, name)
+	}
+	// The patterns compiled for the walk, if the node was visited
+	var list patternList
+	var err error
+	if node.filters != nil {
+		list = node.filters.pattern
+	} else {
+		list, err = compilePatterns(opts.Pattern, opts.Glob, opts.IgnoreCase)
+	}
+	base := strings.LastIndex(name, node.Name())
+	if err != nil || base < 0 {
+		return opts.color(cnode, name)
+	}
+	loc := list.find(node.Name())
+	if loc == nil || loc[0] == loc[1] {
+		return opts.color(cnode, name)
+	}
+	start, end := base+loc[0], base+loc[1]
+	var s string
+	if start > 0 {
+		s += opts.color(cnode, name[:start])
+	}
+	s += ANSIColorFormat(HighlightStyle, name[start:end])
+	if end < len(name) {
+		s += opts.color(cnode, name[end:])
+	}
+	return s
+}
+
 // case-insensitive contains helper
 func contains(slice []string, str string) bool {
 	for _, val := range slice {
@@ -76,4 +180,8 @@ func contains(slice []string, str string) bool {
 	return false
 }
 
-// TODO: HTMLColor
+// HTMLColor colors like ANSIColor, but returns HTML: the escaped text in
+// spans with the classes of HTMLStylesheet.
+func HTMLColor(node *Node, s string) string {
+	return ANSIToHTML(ANSIColor(node, s))
+}
@@ -2,6 +2,7 @@ package tree
 
 import (
 	"os"
+	"strings"
 	"syscall"
 	"testing"
 )
@@ -51,3 +52,83 @@ func TestFileMode(t *testing.T) {
 		}
 	}
 }
+
+func TestParseColors(t *testing.T) {
+	color, err := ParseColors("di=01;34:ex=01;32:*.go=00;36:*_test.go=01;33")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, test := range []struct {
+		name     string
+		mode     os.FileMode
+		expected string
+	}{
+		{"dir", os.ModeDir, "\x1b[01;34mdir\x1b[0m"},
+		{"exec", os.FileMode(syscall.S_IXUSR), "\x1b[01;32mexec\x1b[0m"},
+		{"main.GO", 0, "\x1b[00;36mmain.GO\x1b[0m"},
+		{"main_test.go", 0, "\x1b[01;33mmain_test.go\x1b[0m"},
+		{"fifo", os.ModeNamedPipe, "fifo"},
+		{"simple", 0, "simple"},
+	} {
+		fi := &file{name: test.name, mode: test.mode}
+		if actual := color(&Node{FileInfo: fi}, fi.name); actual != test.expected {
+			t.Errorf("\ngot:\n%+v\nexpected:\n%+v", actual, test.expected)
+		}
+	}
+	for _, spec := range []string{"di", "=01", "di=bold"} {
+		if _, err := ParseColors(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
+
+var highlightTests = []struct {
+	name     string
+	pattern  string
+	glob     bool
+	expected string
+}{
+	{"foo.go", "oo", false, "f\x1b[7moo\x1b[0m.go"},
+	{"foo.jpg", "^foo", false, "\x1b[7mfoo\x1b[0m\x1b[1;35m.jpg\x1b[0m"},
+	{"foo.go", "*.go", true, "\x1b[7mfoo.go\x1b[0m"},
+	{"foo.go", "bar|!foo", false, "foo.go"},
+}
+
+func TestHighlight(t *testing.T) {
+	for _, test := range highlightTests {
+		opts := &Options{Pattern: test.pattern, Glob: test.glob, Highlight: true}
+		no := &Node{FileInfo: &file{name: test.name}}
+		if actual := opts.colorize(no, test.name); actual != test.expected {
+			t.Errorf("\ngot:\n%+q\nexpected:\n%+q", actual, test.expected)
+		}
+	}
+}
+
+func TestMagicColor(t *testing.T) {
+	tar := strings.Repeat("\x00", 257) + "ustar\x0000"
+	root := &file{name: "root", files: []*file{
+		{name: "prog", content: "\x7fELF\x02\x01\x01"},
+		{name: "run", content: "#!/bin/sh\necho hi\n"},
+		{name: "data", content: "\x1f\x8b\x08\x00"},
+		{name: "backup", content: tar},
+		{name: "notes", content: "hello"},
+	}}
+	fs.clean().addFile(root.name, root)
+	opts := &Options{Fs: fs, MagicColor: true}
+	for _, test := range []struct {
+		name     string
+		expected string
+	}{
+		{"prog", "\x1b[1;32mprog\x1b[0m"},
+		{"run", "\x1b[1;32mrun\x1b[0m"},
+		{"data", "\x1b[1;31mdata\x1b[0m"},
+		{"backup", "\x1b[1;31mbackup\x1b[0m"},
+		{"notes", "notes"},
+	} {
+		fi, _ := fs.Stat("root/" + test.name)
+		no := &Node{FileInfo: fi, path: "root/" + test.name}
+		if actual := opts.colorize(no, test.name); actual != test.expected {
+			t.Errorf("\ngot:\n%+q\nexpected:\n%+q", actual, test.expected)
+		}
+	}
+}
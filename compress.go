@@ -0,0 +1,153 @@
+package tree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+)
+
+// errNoSize is returned for the compressed files whose headers don't
+// record the uncompressed size.
+var errNoSize = errors.New("uncompressed size not recorded")
+
+// compressedSizes are the readers of the uncompressed size of the
+// compressed files, by extension. See the Uncompressed option.
+var compressedSizes = map[string]func(r io.ReaderAt, size int64) (int64, error){
+	".gz":  gzipSize,
+	".zst": zstdSize,
+	".xz":  xzSize,
+}
+
+// uncompressedSize returns the uncompressed size of a compressed file, or
+// 0 if it's not compressed or its size is unknown.
+func (opts *Options) uncompressedSize(node *Node) int64 {
+	name := strings.ToLower(node.Name())
+	i := strings.LastIndexByte(name, '.')
+	if i < 0 || compressedSizes[name[i:]] == nil {
+		return 0
+	}
+	fo, ok := opts.Fs.(FileOpener)
+	if !ok {
+		return 0
+	}
+	r, err := fo.Open(node.path)
+	if err != nil {
+		opts.warn("cannot read compressed file", node.path, err)
+		return 0
+	}
+	defer r.Close()
+	ra, size, err := readerAt(r)
+	if err == nil {
+		size, err = compressedSizes[name[i:]](ra, size)
+	}
+	if err != nil {
+		opts.warn("cannot read uncompressed size", node.path, err)
+		return 0
+	}
+	return size
+}
+
+// gzipSize reads the ISIZE field of the gzip trailer, which is the size
+// modulo 2^32 of the last member.
+func gzipSize(r io.ReaderAt, size int64) (int64, error) {
+	var b [4]byte
+	if size < 18 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if _, err := r.ReadAt(b[:], size-4); err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint32(b[:])), nil
+}
+
+// zstdSize reads the Frame_Content_Size field of the first zstd frame.
+func zstdSize(r io.ReaderAt, size int64) (int64, error) {
+	var b [18]byte
+	n, err := r.ReadAt(b[:], 0)
+	if n < 5 || !bytes.Equal(b[:4], []byte{0x28, 0xb5, 0x2f, 0xfd}) {
+		if err == nil {
+			err = errors.New("not a zstd frame")
+		}
+		return 0, err
+	}
+	fhd := b[4]
+	single := fhd>>5&1 == 1
+	off := 5 + []int{0, 1, 2, 4}[fhd&3]
+	if !single {
+		off++ // window descriptor
+	}
+	fcs := []int{0, 2, 4, 8}[fhd>>6]
+	if fcs == 0 && single {
+		fcs = 1
+	}
+	if fcs == 0 {
+		return 0, errNoSize
+	}
+	if off+fcs > n {
+		return 0, io.ErrUnexpectedEOF
+	}
+	f := b[off : off+fcs]
+	switch fcs {
+	case 1:
+		return int64(f[0]), nil
+	case 2:
+		return int64(binary.LittleEndian.Uint16(f)) + 256, nil
+	case 4:
+		return int64(binary.LittleEndian.Uint32(f)), nil
+	}
+	return int64(binary.LittleEndian.Uint64(f)), nil
+}
+
+// xzSize sums the uncompressed sizes of the blocks recorded in the index
+// of the last xz stream.
+func xzSize(r io.ReaderAt, size int64) (int64, error) {
+	var footer [12]byte
+	if size < 32 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if _, err := r.ReadAt(footer[:], size-12); err != nil {
+		return 0, err
+	}
+	if footer[10] != 'Y' || footer[11] != 'Z' {
+		return 0, errors.New("not a xz stream")
+	}
+	isize := (int64(binary.LittleEndian.Uint32(footer[4:8])) + 1) * 4
+	if isize > size-12 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	index := make([]byte, isize)
+	if _, err := r.ReadAt(index, size-12-isize); err != nil {
+		return 0, err
+	}
+	if index[0] != 0 {
+		return 0, errors.New("invalid xz index")
+	}
+	buf := bytes.NewReader(index[1:])
+	n, err := binary.ReadUvarint(buf)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for ; n > 0; n-- {
+		if _, err := binary.ReadUvarint(buf); err != nil { // unpadded size
+			return 0, err
+		}
+		u, err := binary.ReadUvarint(buf)
+		if err != nil {
+			return 0, err
+		}
+		total += int64(u)
+	}
+	return total, nil
+}
+
+// uncompressedColumn returns the Uncompressed column of a node, blank if
+// its uncompressed size is unknown.
+func (opts *Options) uncompressedColumn(node *Node) string {
+	if node.usize > 0 {
+		return opts.sizeColumn(node.usize)
+	}
+	return strings.Repeat(" ", len(opts.sizeColumn(0)))
+}
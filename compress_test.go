@@ -0,0 +1,35 @@
+package tree
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUncompressed(t *testing.T) {
+	gz := new(bytes.Buffer)
+	w := gzip.NewWriter(gz)
+	w.Write([]byte(strings.Repeat("log line\n", 1000)))
+	w.Close()
+	xz, _ := hex.DecodeString("fd377a585a000004e6d6b4460200210116000000742fe5a3e00bb700155d00006ffdffffa3b7ff473e481572396151b840ea30440000000030166e63f61b005f000131b817000000262c0ae1b1c467fb020000000004595a")
+	zst := []byte{0x28, 0xb5, 0x2f, 0xfd, 0x60, 0xb8, 0x0a}
+	root := &file{name: "root", files: []*file{
+		{name: "a.log.gz", size: int64(gz.Len()), content: gz.String()},
+		{name: "b.xz", size: int64(len(xz)), content: string(xz)},
+		{name: "c.zst", size: int64(len(zst)), content: string(zst)},
+		{name: "d.gz", size: 3, content: "bad"},
+		{name: "e.log", size: 10},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"uncompressed", &Options{Fs: fs, OutFile: out, Uncompressed: true, UnitSize: true}, fmt.Sprintf(`[%4d     ]  root
+├── [%4d 8.8K]  a.log.gz
+├── [  88 2.9K]  b.xz
+├── [   7 2.9K]  c.zst
+├── [   3     ]  d.gz
+└── [  10     ]  e.log
+`, gz.Len()+108, gz.Len()), 0, 5}})
+}
@@ -0,0 +1,140 @@
+package tree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ConfigPath returns the path of the default configuration file, e.g.
+// ~/.config/tree/config on Linux.
+func ConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tree", "config"), nil
+}
+
+// ReadConfig reads a configuration file, and returns it as flags of
+// ParseFlags. The file is a flat TOML table where the keys are the flag
+// names, for example:
+//
+//	# Default excludes
+//	I = "node_modules|.git"
+//	dirsfirst = true
+//	L = 2
+//
+// Prepending the returned flags to the command line ones keeps the
+// explicit flags in precedence over the configuration.
+func ReadConfig(r io.Reader) ([]string, error) {
+	fl := NewFlagSet()
+	var args []string
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		i := strings.IndexByte(line, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("config line %d: missing '='", n)
+		}
+		key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		f := fl.Lookup(key)
+		if f == nil {
+			return nil, fmt.Errorf("config line %d: unknown option '%s'", n, key)
+		}
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			s, err := unquoteConfig(value)
+			if err != nil {
+				return nil, fmt.Errorf("config line %d: %v", n, err)
+			}
+			value = s
+		} else if i := strings.IndexByte(value, '#'); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		if err := f.Value.Set(value); err != nil {
+			return nil, fmt.Errorf("config line %d: invalid value '%s' for '%s'", n, value, key)
+		}
+		args = append(args, "-"+key+"="+value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// LoadConfig reads the configuration file at path, see ReadConfig. A
+// missing file is not an error.
+func LoadConfig(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadConfig(f)
+}
+
+// envFlags are the environment variables read by EnvArgs, and their
+// flags. The first variable set is used.
+var envFlags = []struct {
+	flag string
+	vars []string
+}{
+	{"charset", []string{"TREE_CHARSET"}},
+	{"colors", []string{"TREE_COLORS", "LS_COLORS"}},
+	{"icon-map", []string{"TREE_ICONS"}},
+	{"I", []string{"TREE_IGNORE"}},
+}
+
+// EnvArgs returns the defaults set in the environment (TREE_CHARSET,
+// TREE_COLORS or LS_COLORS, TREE_ICONS and TREE_IGNORE) as flags of ParseFlags. The
+// precedence order is: command line flags, environment variables, and
+// then the configuration file. That is:
+//
+//	args := append(append(config, tree.EnvArgs()...), os.Args[1:]...)
+func EnvArgs() []string {
+	var args []string
+	for _, env := range envFlags {
+		for _, name := range env.vars {
+			if value := os.Getenv(name); value != "" {
+				args = append(args, "-"+env.flag+"="+value)
+				break
+			}
+		}
+	}
+	return args
+}
+
+// unquoteConfig unquotes a TOML basic ("...") or literal ('...') string,
+// ignoring a trailing comment.
+func unquoteConfig(s string) (string, error) {
+	quote := s[0]
+	end := -1
+	for i := 1; i < len(s); i++ {
+		if s[i] == '\\' && quote == '"' {
+			i++
+		} else if s[i] == quote {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return "", fmt.Errorf("unterminated string %s", s)
+	}
+	if rest := strings.TrimSpace(s[end+1:]); rest != "" && rest[0] != '#' {
+		return "", fmt.Errorf("unexpected '%s' after string", rest)
+	}
+	if quote == '\'' {
+		return s[1:end], nil
+	}
+	return strconv.Unquote(s[:end+1])
+}
@@ -0,0 +1,71 @@
+package tree
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadConfig(t *testing.T) {
+	config := `
+# Default excludes
+I = "node_modules|.git" # comment
+P = '*.go'
+dirsfirst = true
+L = 2
+`
+	args, err := ReadConfig(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"-I=node_modules|.git", "-P=*.go", "-dirsfirst=true", "-L=2"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("\ngot:\n%v\nexpected:\n%v", args, expected)
+	}
+	opts, _, err := ParseFlags(append(args, "-L", "4"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.IPattern != "node_modules|.git" || !opts.DirSort || opts.DeepLevel != 4 {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+	for _, test := range []struct {
+		config   string
+		expected string
+	}{
+		{"a", "config line 1: missing '='"},
+		{"\nfoo = 1", "config line 2: unknown option 'foo'"},
+		{"L = x", "config line 1: invalid value 'x' for 'L'"},
+		{`I = "a`, `config line 1: unterminated string "a`},
+		{`I = "a" b`, "config line 1: unexpected 'b' after string"},
+	} {
+		if _, err := ReadConfig(strings.NewReader(test.config)); err == nil || err.Error() != test.expected {
+			t.Errorf("%q: got error %v, expected %s", test.config, err, test.expected)
+		}
+	}
+}
+
+func TestEnvArgs(t *testing.T) {
+	for name, value := range map[string]string{
+		"TREE_CHARSET": "ascii",
+		"TREE_COLORS":  "",
+		"LS_COLORS":    "di=01;34",
+		"TREE_IGNORE":  "",
+	} {
+		defer os.Setenv(name, os.Getenv(name))
+		os.Setenv(name, value)
+	}
+	expected := []string{"-charset=ascii", "-colors=di=01;34"}
+	if args := EnvArgs(); !reflect.DeepEqual(args, expected) {
+		t.Errorf("\ngot:\n%v\nexpected:\n%v", args, expected)
+	}
+	os.Setenv("TREE_COLORS", "di=01;35")
+	opts, _, err := ParseFlags(append(EnvArgs(), "--charset", "utf-8"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.Charset != "utf-8" || opts.Color == nil {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+}
@@ -5,6 +5,7 @@ package tree
 import (
 	"os"
 	"syscall"
+	"time"
 )
 
 func CTimeSort(f1, f2 os.FileInfo) bool {
@@ -16,3 +17,23 @@ func CTimeSort(f1, f2 os.FileInfo) bool {
 	}
 	return s1.Ctimespec.Sec < s2.Ctimespec.Sec
 }
+
+// changeTime returns the status change time of the given file, or its
+// modification time if it isn't an os node.
+func changeTime(fi os.FileInfo) time.Time {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fi.ModTime()
+	}
+	return time.Unix(int64(st.Ctimespec.Sec), int64(st.Ctimespec.Nsec))
+}
+
+// accessTime returns the last access time of the given file, or its
+// modification time if it isn't an os node.
+func accessTime(fi os.FileInfo) time.Time {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fi.ModTime()
+	}
+	return time.Unix(int64(st.Atimespec.Sec), int64(st.Atimespec.Nsec))
+}
@@ -2,5 +2,20 @@
 
 package tree
 
+import (
+	"os"
+	"time"
+)
+
 // CtimeSort for unsupported OS - just compare ModTime
 var CTimeSort = ModSort
+
+// changeTime for unsupported OS - just return ModTime
+func changeTime(fi os.FileInfo) time.Time {
+	return fi.ModTime()
+}
+
+// accessTime for unsupported OS - just return ModTime
+func accessTime(fi os.FileInfo) time.Time {
+	return fi.ModTime()
+}
@@ -0,0 +1,65 @@
+package tree
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// Decorator computes an extra column of the entries, e.g. their checksum,
+// git status or mime type. See the Decorators option.
+type Decorator struct {
+	// Name is the Meta key of the computed values.
+	Name string
+	// Fn returns the column of the given node, which was stat'ed.
+	Fn func(node *Node) string
+	// Width is the minimum width of the column, the values are left
+	// aligned.
+	Width int
+	// Parallel runs Fn concurrently on the nodes, with GOMAXPROCS
+	// workers. Fn must be safe for concurrent use.
+	Parallel bool
+}
+
+// decorate runs the decorators on the visited nodes of root, and attaches
+// their values to the nodes.
+func (opts *Options) decorate(root *Node) {
+	if len(opts.Decorators) == 0 {
+		return
+	}
+	nodes := root.Flatten().Filter(func(n *Node) bool { return n.FileInfo != nil })
+	for _, d := range opts.Decorators {
+		if !d.Parallel {
+			for _, node := range nodes {
+				node.SetMeta(d.Name, d.Fn(node))
+			}
+			continue
+		}
+		work := make(chan *Node)
+		var wg sync.WaitGroup
+		for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+			wg.Add(1)
+			go func(d Decorator) {
+				defer wg.Done()
+				for node := range work {
+					node.SetMeta(d.Name, d.Fn(node))
+				}
+			}(d)
+		}
+		for _, node := range nodes {
+			work <- node
+		}
+		close(work)
+		wg.Wait()
+	}
+}
+
+// decorations returns the decorator columns of the node.
+func (opts *Options) decorations(node *Node) []string {
+	var cols []string
+	for _, d := range opts.Decorators {
+		v, _ := node.Meta(d.Name).(string)
+		cols = append(cols, fmt.Sprintf("%-*s", d.Width, v))
+	}
+	return cols
+}
@@ -0,0 +1,182 @@
+package tree
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SchemaVersion is the version of the Entry schema, used by the
+// snapshots, the JSON of Handler, the ChangesJSON lines and StreamEntries.
+// It's incremented on incompatible changes to the Entry, Report or
+// Document field names or semantics. The JSON option isn't part of it: it
+// prints the format of GNU tree -J, for the tools that read that one.
+const SchemaVersion = 1
+
+// Entry is the stable representation of a visited node, used by the
+// outputs of the versioned schema (see SchemaVersion). Unlike Node, its
+// fields are part of the documented schema.
+type Entry struct {
+	// Type is one of: "directory", "file", "link", "socket", "fifo",
+	// "blockdev", "chardev" or "other".
+	Type string `json:"type"`
+	// Name is the base name, or the given path for the root.
+	Name string `json:"name"`
+	// Path is the path of the entry, including the root.
+	Path string `json:"path"`
+	// Depth is the level in the tree, the root's depth is 0.
+	Depth int `json:"depth"`
+	// Size in bytes. For directories it's the sum of the visited files
+	// sizes.
+	Size int64 `json:"size"`
+	// Mode is the file mode bits, and Perm their ls(1) like string.
+	Mode os.FileMode `json:"mode"`
+	Perm string      `json:"prot"`
+	// ModTime is the last modification time.
+	ModTime time.Time `json:"time"`
+	// Target is the symbolic link target.
+	Target string `json:"target,omitempty"`
+	// Error is set if the entry couldn't be read.
+	Error string `json:"error,omitempty"`
+	// Info is the comment of the entry in the .info files, see the Info
+	// option.
+	Info []string `json:"info,omitempty"`
+	// Meta are the values attached to the node, including the ones of
+	// the Decorators.
+	Meta map[string]interface{} `json:"meta,omitempty"`
+	// Contents are the visited children of a directory.
+	Contents []*Entry `json:"contents,omitempty"`
+}
+
+// Report is the summary of a walk.
+type Report struct {
+	Directories int `json:"directories"`
+	Files       int `json:"files"`
+}
+
+// Document is the top-level value of the snapshots and the JSON of
+// Handler.
+type Document struct {
+	Version int      `json:"version"`
+	Tree    []*Entry `json:"tree"`
+	Report  *Report  `json:"report,omitempty"`
+}
+
+// typeNames are the Entry.Type of each FileType.
+var typeNames = map[FileType]string{
+	TypeFile:        "file",
+	TypeSymlink:     "link",
+	TypeSocket:      "socket",
+	TypeFifo:        "fifo",
+	TypeBlockDevice: "blockdev",
+	TypeCharDevice:  "chardev",
+	TypeOther:       "other",
+}
+
+// NewEntry returns the Entry of a visited node and its children.
+func NewEntry(node *Node) *Entry {
+	e := newEntry(node)
+	for _, nnode := range node.nodes {
+		e.Contents = append(e.Contents, NewEntry(nnode))
+	}
+	return e
+}
+
+// newEntry returns the Entry of a visited node, without its children.
+func newEntry(node *Node) *Entry {
+	e := &Entry{Path: node.path, Depth: node.depth}
+	if node.err != nil || node.FileInfo == nil {
+		e.Type, e.Name = "other", node.path
+		if node.err != nil {
+			e.Error = node.err.Error()
+		}
+		return e
+	}
+	e.Name = node.Name()
+	if node.depth == 0 {
+		e.Name = node.path
+	}
+	e.Mode, e.Perm = node.Mode(), node.Mode().String()
+	e.ModTime = node.ModTime()
+	if node.IsDir() {
+		e.Type = "directory"
+		e.Size, _ = node.dirSize()
+	} else {
+		e.Type = typeNames[fileType(node.Mode())]
+		e.Size = node.Size()
+	}
+	if node.Mode()&os.ModeSymlink != 0 {
+		e.Target = node.link
+	}
+	e.Info = node.comment
+	node.metaMu.Lock()
+	for k, v := range node.meta {
+		if e.Meta == nil {
+			e.Meta = make(map[string]interface{})
+		}
+		e.Meta[k] = v
+	}
+	node.metaMu.Unlock()
+	return e
+}
+
+// MarshalJSON encodes a visited node and its children as their Entry, so
+// the Node and Nodes values can be persisted or sent directly.
+func (node *Node) MarshalJSON() ([]byte, error) {
+	return json.Marshal(NewEntry(node))
+}
+
+// UnmarshalJSON decodes a node encoded by MarshalJSON, or an Entry of a
+// snapshot (but not the JSON option output), as a visited tree that can be printed and
+// queried. The FileInfo of its nodes only has the Entry properties, and
+// the Meta values are decoded as JSON values, e.g. float64 numbers.
+func (node *Node) UnmarshalJSON(b []byte) error {
+	var e Entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return err
+	}
+	node.setEntry(&e)
+	return nil
+}
+
+// setEntry sets the node and its children from an Entry.
+func (node *Node) setEntry(e *Entry) {
+	node.path, node.depth = e.Path, e.Depth
+	if e.Error != "" {
+		node.err = errors.New(e.Error)
+		return
+	}
+	mode := e.Mode
+	if e.Type == "directory" {
+		mode |= os.ModeDir
+	}
+	fi := &archiveInfo{name: filepath.Base(e.Path), mode: mode, modTime: e.ModTime}
+	if e.Depth == 0 {
+		fi.name = e.Name
+	}
+	node.FileInfo = fi
+	node.link, node.comment = e.Target, e.Info
+	for k, v := range e.Meta {
+		node.SetMeta(k, v)
+	}
+	if !mode.IsDir() {
+		fi.size = e.Size
+		return
+	}
+	node.size, node.disk = e.Size, e.Size
+	node.entries = len(e.Contents)
+	node.empty = len(e.Contents) == 0
+	node.nodes = make(Nodes, 0, len(e.Contents))
+	for _, ce := range e.Contents {
+		nnode := new(Node)
+		nnode.setEntry(ce)
+		node.nodes = append(node.nodes, nnode)
+		if nnode.FileInfo != nil && nnode.IsDir() {
+			node.nfiles += nnode.nfiles
+		} else if nnode.FileInfo != nil {
+			node.nfiles++
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package tree
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEntry(t *testing.T) {
+	mtime := time.Date(2015, 8, 1, 0, 0, 0, 0, time.UTC)
+	root := &file{
+		name:    "root",
+		lastMod: mtime,
+		files: []*file{
+			{name: "a", size: 10, lastMod: mtime, mode: 0644},
+			{name: "b", lastMod: mtime, files: []*file{{name: "c", size: 5, lastMod: mtime, mode: 0600}}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	opts := &Options{Fs: fs, OutFile: out}
+	inf := New(root.name)
+	d, f := inf.Visit(opts)
+	doc := &Document{
+		Version: SchemaVersion,
+		Tree:    []*Entry{NewEntry(inf)},
+		Report:  &Report{Directories: d, Files: f},
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"version":1,"tree":[{"type":"directory","name":"root","path":"root","depth":0,"size":15,"mode":0,"prot":"----------","time":"2015-08-01T00:00:00Z","contents":[` +
+		`{"type":"file","name":"a","path":"root/a","depth":1,"size":10,"mode":420,"prot":"-rw-r--r--","time":"2015-08-01T00:00:00Z"},` +
+		`{"type":"directory","name":"b","path":"root/b","depth":1,"size":5,"mode":0,"prot":"----------","time":"2015-08-01T00:00:00Z","contents":[` +
+		`{"type":"file","name":"c","path":"root/b/c","depth":2,"size":5,"mode":384,"prot":"-rw-------","time":"2015-08-01T00:00:00Z"}]}]}],` +
+		`"report":{"directories":1,"files":2}}`
+	if string(b) != expected {
+		t.Errorf("\ngot:\n%s\nexpected:\n%s", b, expected)
+	}
+}
+
+func TestStreamEntries(t *testing.T) {
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a", files: []*file{{name: "b"}}},
+			{name: "c"},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	inf := New(root.name)
+	inf.Visit(&Options{Fs: fs, OutFile: out})
+	var paths []string
+	err := StreamEntries(inf, func(e *Entry) error {
+		if e.Contents != nil {
+			t.Errorf("%s: unexpected contents", e.Path)
+		}
+		paths = append(paths, e.Path)
+		if len(paths) == 3 {
+			return errors.New("stop")
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "stop" {
+		t.Errorf("expected the send error, got: %v", err)
+	}
+	if actual := strings.Join(paths, " "); actual != "root root/a root/a/b" {
+		t.Errorf("\ngot:\n%s\nexpected:\n%s", actual, "root root/a root/a/b")
+	}
+}
+
+func TestEntryMeta(t *testing.T) {
+	node := &Node{FileInfo: &file{name: "a"}, path: "a"}
+	node.SetMeta("hash", "abc")
+	b, err := json.Marshal(NewEntry(node))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"meta":{"hash":"abc"}`) {
+		t.Errorf("missing meta in %s", b)
+	}
+}
+
+func TestNodeJSON(t *testing.T) {
+	defer out.clear()
+	mtime := time.Date(2015, 8, 1, 0, 0, 0, 0, time.UTC)
+	root := &file{
+		name:    "root",
+		lastMod: mtime,
+		files: []*file{
+			{name: "a", size: 10, lastMod: mtime, mode: 0644},
+			{name: "b", lastMod: mtime, files: []*file{{name: "c", size: 5, lastMod: mtime, mode: 0600}}},
+			{name: "d", lastMod: mtime, files: []*file{}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	opts := &Options{Fs: fs, OutFile: out, ByteSize: true, FileMode: true, FileCount: true, LastMod: true}
+	inf := New(root.name)
+	inf.Visit(opts)
+	inf.nodes[0].SetMeta("owner", "alice")
+	b, err := json.Marshal(Nodes{inf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nodes Nodes
+	if err := json.Unmarshal(b, &nodes); err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 {
+		t.Fatalf("got %d nodes, expected 1", len(nodes))
+	}
+	out.clear()
+	inf.Print(opts)
+	expected := out.str
+	out.clear()
+	nodes[0].Print(opts)
+	if !out.equal(expected) {
+		t.Errorf("round-trip: got:\n%s\nexpected:\n%s", out.str, expected)
+	}
+	if nodes[0].Path() != "root" || nodes[0].TotalSize() != 15 || nodes[0].nodes[0].Meta("owner") != "alice" {
+		t.Errorf("round-trip: got path %q, size %d, meta %v", nodes[0].Path(), nodes[0].TotalSize(), nodes[0].nodes[0].Meta("owner"))
+	}
+	var node Node
+	if err := json.Unmarshal([]byte(`{"type":"other","name":"x","path":"root/x","depth":1,"error":"open root/x: permission denied"}`), &node); err != nil {
+		t.Fatal(err)
+	}
+	if node.err == nil || node.Depth() != 1 {
+		t.Errorf("error entry: got err %v, depth %d", node.err, node.Depth())
+	}
+}
@@ -0,0 +1,104 @@
+package tree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrRecursionLimit is the error of the directories at the depth of the
+// RecursionLimit option.
+var ErrRecursionLimit = errors.New("recursion limit reached")
+
+// WalkError is an entry that couldn't be read during a walk.
+type WalkError struct {
+	Path string
+	// Dir is set if the entry is a directory that couldn't be listed.
+	Dir bool
+	Err error
+}
+
+func (e *WalkError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, errReason(e.Err))
+}
+
+// Errors returns the entries that couldn't be read while visiting the
+// node, including the files the Checksum option couldn't hash, in
+// display order.
+func (node *Node) Errors() (errs []*WalkError) {
+	if node.err != nil {
+		dir := node.FileInfo != nil && node.IsDir()
+		errs = append(errs, &WalkError{node.path, dir, node.err})
+	}
+	// Checksum option
+	if node.hashErr != nil {
+		errs = append(errs, &WalkError{node.path, false, node.hashErr})
+	}
+	for _, nnode := range node.nodes {
+		errs = append(errs, nnode.Errors()...)
+	}
+	return
+}
+
+// FprintErrors writes a summary of the given errors, e.g:
+//
+//	could not read 2 directories and 1 file:
+//	  root/a [permission denied]
+func FprintErrors(w io.Writer, errs []*WalkError) {
+	var dirs, files int
+	for _, e := range errs {
+		if e.Dir {
+			dirs++
+		} else {
+			files++
+		}
+	}
+	var what []string
+	if dirs > 0 {
+		what = append(what, plural(dirs, "directory", "directories"))
+	}
+	if files > 0 {
+		what = append(what, plural(files, "file", "files"))
+	}
+	fmt.Fprintf(w, "could not read %s:\n", strings.Join(what, " and "))
+	for _, e := range errs {
+		fmt.Fprintf(w, "  %s [%s]\n", e.Path, errReason(e.Err))
+	}
+}
+
+// writeErr writes the error of node to the ErrFile option.
+func (opts *Options) writeErr(node *Node) {
+	e := &WalkError{node.path, node.FileInfo != nil && node.IsDir(), node.err}
+	if !opts.ErrJSON {
+		fmt.Fprintln(opts.ErrFile, e.Error())
+		return
+	}
+	b, _ := json.Marshal(struct {
+		Path  string `json:"path"`
+		Dir   bool   `json:"dir"`
+		Error string `json:"error"`
+	}{e.Path, e.Dir, errReason(e.Err)})
+	fmt.Fprintf(opts.ErrFile, "%s\n", b)
+}
+
+// errReason strips the operation and path from an os error, e.g.
+// "open /root: permission denied" becomes "permission denied".
+func errReason(err error) string {
+	if _, ok := err.(*PatternError); ok {
+		return err.Error()
+	}
+	msg := err.Error()
+	if msgs := strings.Split(msg, ": "); len(msgs) > 1 {
+		msg = msgs[1]
+	}
+	return msg
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, one)
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
@@ -0,0 +1,92 @@
+package tree
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestErrorSummary(t *testing.T) {
+	defer out.clear()
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a"},
+			{name: "b", files: []*file{}},
+			{name: "c", files: []*file{{name: "d"}}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	efs := &errFs{fs, map[string]error{
+		"root/a":           errors.New("lstat root/a: permission denied"),
+		"readdir:root/b":   errors.New("open root/b: permission denied"),
+		"readdir:root/c/d": errors.New("not reached"),
+	}}
+	opts := &Options{Fs: efs, OutFile: out, ErrorSummary: true}
+	inf := New(root.name)
+	inf.Visit(opts)
+	inf.Print(opts)
+	FprintErrors(out, inf.Errors())
+	expected := `root
+├── a
+├── b
+└── c
+    └── d
+could not read 1 directory and 1 file:
+  root/a [permission denied]
+  root/b [permission denied]
+`
+	if !out.equal(expected) {
+		t.Errorf("\ngot:\n%s\nexpected:\n%s", out.str, expected)
+	}
+}
+
+func TestErrFile(t *testing.T) {
+	defer out.clear()
+	root := &file{
+		name:  "root",
+		files: []*file{{name: "a"}, {name: "b", files: []*file{}}},
+	}
+	fs.clean().addFile(root.name, root)
+	efs := &errFs{fs, map[string]error{
+		"root/a":         errors.New("lstat root/a: permission denied"),
+		"readdir:root/b": errors.New("open root/b: permission denied"),
+	}}
+	for _, test := range []struct {
+		json     bool
+		expected string
+	}{
+		{false, "root/a: permission denied\nroot/b: permission denied\n"},
+		{true, `{"path":"root/a","dir":false,"error":"permission denied"}` + "\n" +
+			`{"path":"root/b","dir":true,"error":"permission denied"}` + "\n"},
+	} {
+		errOut := new(bytes.Buffer)
+		opts := &Options{Fs: efs, OutFile: out, ErrFile: errOut, ErrJSON: test.json}
+		inf := New(root.name)
+		inf.Visit(opts)
+		inf.Print(opts)
+		if expected := "root\n├── a\n└── b\n"; !out.equal(expected) {
+			t.Errorf("\ngot:\n%s\nexpected:\n%s", out.str, expected)
+		}
+		if errOut.String() != test.expected {
+			t.Errorf("\ngot:\n%s\nexpected:\n%s", errOut, test.expected)
+		}
+		out.clear()
+	}
+}
+
+func TestPatternError(t *testing.T) {
+	defer out.clear()
+	fs.clean().addFile("root", &file{name: "root", files: []*file{{name: "a"}}})
+	opts := &Options{Fs: fs, OutFile: out, IPattern: "(a"}
+	r := Run([]string{"root"}, opts)
+	if expected := "root [invalid IPattern: error parsing regexp: missing closing ): `(a`]\n"; !out.equal(expected) {
+		t.Errorf("\ngot:\n%s\nexpected:\n%s", out.str, expected)
+	}
+	if len(r.Errors) != 1 || r.ExitCode() != 1 {
+		t.Fatalf("expected a single error, got: %+v", r.Errors)
+	}
+	if pe, ok := r.Errors[0].Err.(*PatternError); !ok || pe.Option != "IPattern" || pe.Pattern != "(a" {
+		t.Errorf("expected a PatternError, got: %#v", r.Errors[0].Err)
+	}
+}
@@ -0,0 +1,10 @@
+//+build !windows
+
+package tree
+
+import "os"
+
+// isExecutable reports whether fi is a regular file with an execute bit set.
+func isExecutable(fi os.FileInfo) bool {
+	return fi.Mode().IsRegular() && fi.Mode()&modeExecute != 0
+}
@@ -0,0 +1,28 @@
+//+build windows
+
+package tree
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isExecutable reports whether fi is a regular file with one of the
+// PATHEXT extensions, as Windows has no execute bits.
+func isExecutable(fi os.FileInfo) bool {
+	if !fi.Mode().IsRegular() {
+		return false
+	}
+	exts := os.Getenv("PATHEXT")
+	if exts == "" {
+		exts = ".com;.exe;.bat;.cmd"
+	}
+	ext := filepath.Ext(fi.Name())
+	for _, e := range filepath.SplitList(exts) {
+		if e != "" && strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,276 @@
+package tree
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expr is a compiled find(1) like predicate, see ParseExpr.
+type Expr interface {
+	Match(node *Node) bool
+}
+
+// ExprFunc adapts an ordinary function to the Expr interface.
+type ExprFunc func(node *Node) bool
+
+// Match calls f(node).
+func (f ExprFunc) Match(node *Node) bool { return f(node) }
+
+// ParseExpr compiles a find(1) like expression, for example:
+//
+//	-size +1M -and -mtime -7 -and -not -name '*.log'
+//
+// Supported tests are -name, -iname, -path, -size, -mtime, -mmin, -type,
+// -perm, -user, -group, -empty and -executable. They can be combined with
+// -not (!), -and (-a), -or (-o) and parentheses; juxtaposed tests are
+// joined with -and. Sizes without a suffix are in bytes, and -mtime/-mmin
+// take a find(1) like [+-]N number of days/minutes.
+func ParseExpr(s string) (Expr, error) {
+	args, err := splitArgs(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{args: args, now: time.Now()}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.args) {
+		return nil, fmt.Errorf("unexpected '%s' in expression", p.args[p.pos])
+	}
+	return e, nil
+}
+
+type exprParser struct {
+	args []string
+	pos  int
+	now  time.Time
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.args) {
+		return p.args[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) next() (string, error) {
+	if p.pos >= len(p.args) {
+		return "", fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+	return p.args[p.pos-1], nil
+}
+
+func (p *exprParser) parseOr() (Expr, error) {
+	e, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for tok := p.peek(); tok == "-or" || tok == "-o"; tok = p.peek() {
+		p.pos++
+		rhs, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		lhs := e
+		e = ExprFunc(func(n *Node) bool { return lhs.Match(n) || rhs.Match(n) })
+	}
+	return e, nil
+}
+
+func (p *exprParser) parseAnd() (Expr, error) {
+	e, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch tok := p.peek(); tok {
+		case "", "-or", "-o", ")":
+			return e, nil
+		case "-and", "-a":
+			p.pos++
+		}
+		rhs, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		lhs := e
+		e = ExprFunc(func(n *Node) bool { return lhs.Match(n) && rhs.Match(n) })
+	}
+}
+
+func (p *exprParser) parseNot() (Expr, error) {
+	if tok := p.peek(); tok == "-not" || tok == "!" {
+		p.pos++
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return ExprFunc(func(n *Node) bool { return !e.Match(n) }), nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (Expr, error) {
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case "(":
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok, _ := p.next(); tok != ")" {
+			return nil, fmt.Errorf("missing ')' in expression")
+		}
+		return e, nil
+	case "-empty":
+		return ExprFunc(func(n *Node) bool { return n.empty }), nil
+	case "-executable":
+		return ExprFunc(func(n *Node) bool { return isExecutable(n) }), nil
+	}
+	arg, err := p.next()
+	if err != nil {
+		return nil, fmt.Errorf("missing argument to '%s'", tok)
+	}
+	switch tok {
+	case "-name", "-iname":
+		pattern := arg
+		if tok == "-iname" {
+			pattern = strings.ToLower(arg)
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %v", arg, err)
+		}
+		return ExprFunc(func(n *Node) bool {
+			name := n.Name()
+			if tok == "-iname" {
+				name = strings.ToLower(name)
+			}
+			ok, _ := filepath.Match(pattern, name)
+			return ok
+		}), nil
+	case "-path":
+		if _, err := filepath.Match(arg, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %v", arg, err)
+		}
+		return ExprFunc(func(n *Node) bool {
+			ok, _ := filepath.Match(arg, n.path)
+			return ok
+		}), nil
+	case "-size":
+		cmp, num := splitCmp(arg)
+		size, err := ParseSize(num)
+		if err != nil {
+			return nil, err
+		}
+		return ExprFunc(func(n *Node) bool { return compare(cmp, n.Size(), size) }), nil
+	case "-mtime", "-mmin":
+		cmp, num := splitCmp(arg)
+		v, err := strconv.ParseInt(num, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s' to '%s'", arg, tok)
+		}
+		unit := time.Minute
+		if tok == "-mtime" {
+			unit = 24 * time.Hour
+		}
+		now := p.now
+		return ExprFunc(func(n *Node) bool {
+			return compare(cmp, int64(now.Sub(n.ModTime())/unit), v)
+		}), nil
+	case "-type":
+		t, err := ParseFileTypes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return ExprFunc(func(n *Node) bool { return t.Has(n.Mode()) }), nil
+	case "-perm":
+		perm, match, err := ParsePerm(arg)
+		if err != nil {
+			return nil, err
+		}
+		return ExprFunc(func(n *Node) bool { return match.Match(n.Mode(), perm) }), nil
+	case "-user", "-group":
+		resolve := resolveUser
+		if tok == "-group" {
+			resolve = resolveGroup
+		}
+		want, err := resolve(arg)
+		if err != nil {
+			return nil, err
+		}
+		return ExprFunc(func(n *Node) bool {
+			ok, _, _, uid, gid := getStat(n)
+			if tok == "-group" {
+				uid = gid
+			}
+			return ok && uid == want
+		}), nil
+	}
+	return nil, fmt.Errorf("unknown test '%s' in expression", tok)
+}
+
+// splitCmp splits a find(1) like '+N' (greater than), '-N' (less than)
+// or 'N' (equal) argument.
+func splitCmp(s string) (byte, string) {
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		return s[0], s[1:]
+	}
+	return '=', s
+}
+
+func compare(cmp byte, a, b int64) bool {
+	switch cmp {
+	case '+':
+		return a > b
+	case '-':
+		return a < b
+	default:
+		return a == b
+	}
+}
+
+// splitArgs splits s into shell-like words, honoring single and double
+// quotes.
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	var quote rune
+	inWord := false
+	for _, c := range s {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(c)
+		case c == '\'' || c == '"':
+			quote, inWord = c, true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in expression")
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
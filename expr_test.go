@@ -0,0 +1,68 @@
+package tree
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var exprTests = []struct {
+	expr     string
+	expected string
+}{
+	{"-name '*.go'", "a.go b.go"},
+	{"-iname '*.LOG'", "c.log"},
+	{"-not -name '*.go'", "c.log d"},
+	{"! -name '*.go' -a -size -1k", "c.log d"},
+	{"-size +1k -and -mtime -7", "a.go"},
+	{"-size +1k -or -mmin +60", "a.go b.go c.log"},
+	{"( -name a.go -o -name d ) -and -perm 0755", "d"},
+	{"-path 'root/*.log'", "c.log"},
+	{"-empty", "d"},
+	{"-perm -0111", "d"},
+	{"-executable -or -user 7", "c.log d"},
+	{"-group 2", "b.go"},
+	{"-type f -mtime +1", "b.go"},
+	{"-mtime 0 -mmin -60", "a.go d"},
+}
+
+func TestExpr(t *testing.T) {
+	defer out.clear()
+	now := time.Now()
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a.go", size: 2048, lastMod: now, stat: &syscall.Stat_t{Mode: syscall.S_IFREG | 0644}},
+			{name: "b.go", size: 4096, lastMod: now.Add(-30 * 24 * time.Hour), stat: &syscall.Stat_t{Gid: 2, Mode: syscall.S_IFREG | 0644}},
+			{name: "c.log", size: 100, lastMod: now.Add(-2 * time.Hour), stat: &syscall.Stat_t{Uid: 7, Mode: syscall.S_IFREG | 0644}},
+			{name: "d", size: 0, lastMod: now, stat: &syscall.Stat_t{Mode: syscall.S_IFREG | 0755}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	for _, test := range exprTests {
+		e, err := ParseExpr(test.expr)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.expr, err)
+			continue
+		}
+		opts := &Options{Fs: fs, OutFile: out, Expr: e}
+		inf := New(root.name)
+		inf.Visit(opts)
+		var names []string
+		for _, n := range inf.nodes {
+			names = append(names, n.Name())
+		}
+		if actual := strings.Join(names, " "); actual != test.expected {
+			t.Errorf("%s:\ngot:\n%+v\nexpected:\n%+v", test.expr, actual, test.expected)
+		}
+	}
+}
+
+func TestExprErrors(t *testing.T) {
+	for _, expr := range []string{"", "-name", "-foo bar", "( -empty", "-empty )", "-size x", "-name '[a'", "-name 'a", "-user no-such-user-x", "-group no-such-group-x"} {
+		if _, err := ParseExpr(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
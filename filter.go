@@ -0,0 +1,155 @@
+package tree
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+)
+
+// walkFilters are the compiled Pattern and IPattern options, and the
+// resolved Owner and Group, shared by the nodes of a walk.
+type walkFilters struct {
+	pattern, ipattern patternList
+	uid, gid          uint64
+}
+
+// compileFilters compiles the Pattern and IPattern options, and resolves
+// the Owner and Group names. It returns the PatternError of the first
+// pattern that doesn't compile, or the error of an unknown name.
+func (opts *Options) compileFilters() (*walkFilters, error) {
+	wf := new(walkFilters)
+	for _, p := range []struct {
+		option, pattern string
+		list            *patternList
+	}{
+		{"Pattern", opts.Pattern, &wf.pattern},
+		{"IPattern", opts.IPattern, &wf.ipattern},
+	} {
+		if p.pattern == "" {
+			continue
+		}
+		list, err := compilePatterns(p.pattern, opts.Glob, opts.IgnoreCase)
+		if err != nil {
+			return nil, &PatternError{p.option, p.pattern, err}
+		}
+		*p.list = list
+	}
+	var err error
+	if opts.Owner != "" {
+		if wf.uid, err = resolveUser(opts.Owner); err != nil {
+			return nil, fmt.Errorf("invalid Owner: %v", err)
+		}
+	}
+	if opts.Group != "" {
+		if wf.gid, err = resolveGroup(opts.Group); err != nil {
+			return nil, fmt.Errorf("invalid Group: %v", err)
+		}
+	}
+	return wf, nil
+}
+
+// resolveUser returns the uid of a user name, or of a numeric uid.
+func resolveUser(name string) (uint64, error) {
+	if id, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return id, nil
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown user '%s'", name)
+	}
+	return strconv.ParseUint(u.Uid, 10, 32)
+}
+
+// resolveGroup returns the gid of a group name, or of a numeric gid.
+func resolveGroup(name string) (uint64, error) {
+	if id, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return id, nil
+	}
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown group '%s'", name)
+	}
+	return strconv.ParseUint(g.Gid, 10, 32)
+}
+
+// inFilterDepth reports whether the file filters apply at the given depth.
+func (opts *Options) inFilterDepth(depth int) bool {
+	return (opts.FilterMinDepth <= 0 || depth >= opts.FilterMinDepth) &&
+		(opts.FilterMaxDepth <= 0 || depth <= opts.FilterMaxDepth)
+}
+
+// match reports whether the file passes all the file filters.
+func (node *Node) match(opts *Options) bool {
+	subject := node.Name()
+	if opts.MatchPath {
+		subject = node.relPath()
+	}
+	wf := node.filters
+	if wf == nil {
+		if wf, _ = opts.compileFilters(); wf == nil {
+			return false
+		}
+	}
+	// Pattern matching
+	if opts.Pattern != "" && !wf.pattern.match(subject) {
+		return false
+	}
+	// IPattern matching
+	if opts.IPattern != "" && wf.ipattern.match(subject) {
+		return false
+	}
+	// "broken only" option
+	if opts.BrokenOnly && !node.broken {
+		return false
+	}
+	// "executables only" option
+	if opts.ExecOnly && !isExecutable(node) {
+		return false
+	}
+	// Expression
+	if opts.Expr != nil && !opts.Expr.Match(node) {
+		return false
+	}
+	// File types
+	if opts.Types != 0 && !opts.Types.Has(node.Mode()) {
+		return false
+	}
+	// Permission bits
+	if opts.Perm != 0 && !opts.PermMatch.Match(node.Mode(), opts.Perm) {
+		return false
+	}
+	// Size range
+	if opts.MinSize > 0 && node.Size() < opts.MinSize {
+		return false
+	}
+	if opts.MaxSize > 0 && node.Size() > opts.MaxSize {
+		return false
+	}
+	// Time range
+	if !opts.NewerThan.IsZero() || !opts.OlderThan.IsZero() {
+		mtime := node.ModTime()
+		if opts.ChangeTime {
+			mtime = changeTime(node)
+		}
+		if !opts.NewerThan.IsZero() && !mtime.After(opts.NewerThan) {
+			return false
+		}
+		if !opts.OlderThan.IsZero() && !mtime.Before(opts.OlderThan) {
+			return false
+		}
+	}
+	// Owner/group
+	if opts.Owner != "" || opts.Group != "" {
+		ok, _, _, uid, gid := getStat(node)
+		if !ok {
+			return false
+		}
+		if opts.Owner != "" && uid != wf.uid {
+			return false
+		}
+		if opts.Group != "" && gid != wf.gid {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,854 @@
+package tree
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// Usage is the help text of the flags accepted by ParseFlags.
+var Usage = `Usage: tree [options...] [paths...]
+
+Options:
+    ------- Listing options -------
+    -a, --all               All files are listed.
+    -d, --dirs-only         List directories only.
+    -l, --follow            Follow symbolic links like directories.
+    --dereference           Print the size, mode and time of the symlink targets.
+    -f, --full-path         Print the full path prefix for each file.
+    -L, --level N           Descend only level directories deep.
+    -P, --pattern X         List only those files that match the pattern given.
+    -I, --ignore X          Do not list files that match the given pattern.
+    --ignore-case           Ignore case when pattern matching.
+    --match-path            Match -P and -I patterns against the path relative to the root.
+    --glob                  Use wildcard patterns (e.g. "src/**/*_test.go") for -P and -I.
+                            Patterns are '|' separated, and a '!' prefix carves exceptions
+                            (e.g. -I "*.log|!important.log").
+    --inode-order           Read the entries in inode order (faster on large
+                            directories of some filesystems), then sort them.
+    --dedup                 Show the directories reached more than once (e.g. bind
+                            mounts) only the first time.
+    --filelimit N           Do not descend directories with more than N entries.
+    --max-entries N         Print at most N entries of each directory, and a count of
+                            the rest (e.g. "… and 12 more").
+    --max-lines N           Print at most N entries in all, and a count of the rest.
+    --noreport              Turn off file/directory count at end of tree listing.
+                            The counts are raw numbers, for scripts (e.g. "3 of 12345
+                            files matched" when filtered).
+    --error-summary         Report the unreadable entries after the tree listing.
+    --errors X              Report the unreadable entries on stderr as text (the
+                            default) or json lines, or inline in the tree listing.
+    -o, --output filename   Output to file instead of stdout. Colors are off unless -C.
+    --append                Append to the -o file instead of truncating it.
+    --snapshot filename     Save a snapshot of the walk to the file, for --changes.
+    --changes filename      Print the changes since the snapshot file instead of the tree:
+                            the added, removed, resized, retimed and modified entries.
+    --changes-json          Print the --changes or --verify results as NDJSON lines.
+    --verify filename       Verify the tree against the mtree or snapshot file instead:
+                            print the missing, extra and mismatched entries.
+    --stdin                 Read the paths from the standard input, one per line.
+    -0                      Paths of --stdin are NUL separated (e.g. find -print0).
+    --min-size X            List only files of at least X bytes (e.g. 512, 10K, 100M).
+    --max-size X            List only files of at most X bytes.
+    --newer X               List only files modified (or (-c) changed) after X.
+    --older X               List only files modified (or (-c) changed) before X.
+                            X is a duration ago (e.g. 24h) or a date (2006-01-02[T15:04:05Z07:00]).
+    --owner X               List only files owned by user name or UID X.
+    --group X               List only files owned by group name or GID X.
+    --type X                List only files of the given types, any of: f (regular file),
+                            l (symlink), s (socket), p (fifo), b (block), c (char device).
+    --perm X                List only files with exactly the octal permission bits X,
+                            all of them (-X), or any of them (/X). E.g. -4000 for setuid.
+    --empty                 List only empty files and directories.
+    --broken                List only broken symbolic links.
+    --executable            List only executable files.
+    --hidden                List only hidden files and directories.
+    --hidden-files          Hide the names listed in the .hidden file of each directory
+                            too, like the macOS and GNOME file managers.
+    --expr X                List only files matching the find(1) like expression X,
+                            e.g. "-size +1M -and -mtime -7 -and -not -name '*.log'".
+    --filter-min-depth N    Apply the file filters only from level N on.
+    --filter-max-depth N    Apply the file filters only up to level N.
+    --prune                 Prune empty directories from the output.
+    -------- File options ---------
+    -Q, --quote             Quote filenames with double quotes.
+    --slash                 Append a '/' to the directory names.
+    -p, --perms             Print the protections for each file.
+    -u, --show-owner        Displays file owner or UID number.
+    -g, --show-group        Displays file group owner or GID number.
+    -s, --size              Print the size in bytes of each file.
+    -h, --human             Print the size in a more human readable way.
+    --blocks                Print the disk usage in 1K blocks of each file (like du -k).
+    --disk-size             Print the allocated size next to the apparent one (with -s or -h).
+    --uncompressed          Print the uncompressed size of the .gz, .zst and .xz files
+                            next to their size (with -s or -h).
+    --si                    Like -h, but use SI units (powers of 1000: kB, MB...).
+    --iec                   Like -h, but use IEC units (KiB, MiB...).
+    --precision N           Print N decimals of the -h sizes (default 1 under 10,
+                            -1 for none).
+    -D, --date              Print the date of last modification or (-c) status change.
+    --timefmt X             Format the -D dates with the Go time layout X
+                            (default "Jan 02 15:04").
+    --checksum X            Print the checksum of each file: md5, sha1 or sha256.
+    --hash-workers N        Hash N files concurrently with --checksum (default: the
+                            number of CPUs).
+    --merkle                Print the checksum of each directory too, with --checksum:
+                            the hash of its children names and checksums.
+    --archives              List the content of the zip and tar archives below them.
+    --inodes                Print inode number of each file.
+    --device                Print device ID number to which each file belongs.
+    --fstype                Print the filesystem type of the root and of mount points.
+    --fsusage               Print a capacity report of the filesystems that were walked.
+    --mark-empty            Mark empty files and directories with [empty].
+    --entries               Print the number of entries of each directory.
+    --file-count            Print the number of files below each directory, recursively.
+    --warn-size X           Highlight the directories of more than X bytes (e.g. 10G).
+    --warn-entries N        Highlight the directories of more than N entries.
+    --audit                 Mark the risky entries (world-writable, setuid, setgid or
+                            owned by nobody), and print a summary of them.
+    --stale X               Report the files not modified nor accessed since X (e.g.
+                            2160h or 2023-01-01), by directory with their size.
+    --hardlinks             Report the files with several hard links, and their paths.
+    --exec X                Annotate each file with the output of the command X, run
+                            with its path in place of {} or appended (e.g. "file -b").
+    --exec-workers N        Run N --exec commands concurrently (default: the number
+                            of CPUs).
+    ------- Sorting options -------
+    -v, --version-sort      Sort files alphanumerically by version.
+    -t, --time-sort         Sort files by last modification time.
+    -c, --ctime-sort        Sort files by last status change time.
+    -U, --unsorted          Leave files unsorted, in directory order (the default
+                            is to sort by name).
+    -r, --reverse           Reverse the order of the sort.
+    --dirsfirst             List directories before files (-U disables).
+    --sort X                Select sort: name,version,size,mtime,ctime,extension.
+    --sort-fold             Sort names case-insensitively.
+    --sort-nodots           Ignore the leading dots of names when sorting.
+    ------- Graphics options ------
+    -i, --no-indent         Don't print indentation lines.
+    -C, --color             Turn colorization on always.
+    --highlight             Highlight the part of file names matching -P (with -C).
+    --magic                 Color the files by their content too (with -C), e.g. the
+                            executables and archives without the usual mode or extension.
+    --charset X             Use charset X for the indentation lines: utf-8 or ascii.
+    --indent N              Indent each level by N columns (default 4).
+    --name-width N          Shorten the names longer than N columns with a middle
+                            ellipsis, keeping their extension.
+    --icons                 Print an icon of the type of each file before its name.
+    --icon-set X            Print the icons of set X: emoji (the default) or nerd
+                            (Nerd Font glyphs). Implies --icons.
+    --icon-map X            Extend the icon set with the LS_COLORS like spec X,
+                            e.g. "di=D:*.go=G:Makefile=M".
+    --info                  Print the comments of the .info files below the entries.
+    --accessible            Print for screen readers: the level of each entry instead
+                            of the indentation lines, and the types as words.
+    --bidi                  Isolate the right-to-left file names (e.g. Hebrew, Arabic)
+                            so they don't scramble the lines around them.
+    --depth-prefix          Prefix each line with the depth of its entry (0 for the root).
+    --mtree                 Print a BSD mtree manifest of the tree instead (e.g. for
+                            mtree -f, or bsdtar @file), with --checksum digests.
+    --link-graph X          Print the graph of the symbolic links instead, in the X
+                            format (dot or json), marking the broken and external ones.
+    --format X              Print the tree in the output format X instead, registered by
+                            a package with tree.RegisterFormat.
+    -J, --json              Print the tree as JSON instead, with the type, size, mode
+                            and time of each entry.
+    -X, --xml               Print the tree as XML instead, like GNU tree -X (with the
+                            -p, -u, -g, -s, -D, --inodes and --device attributes).
+    -H, --html X            Print the tree as an HTML page instead, each entry linked
+                            relative to the base HREF X (e.g. "."), colored with -C.
+    --colors X              Set the colors (with -C) from the LS_COLORS like spec X,
+                            e.g. "di=01;34:ln=01;36:ex=01;32:*.go=00;36".
+    --completion X          Print the completion script of shell X instead, one of:
+                            bash, zsh, fish, powershell. E.g. for bash:
+                            source <(tree --completion bash)
+
+Environment variables, overridden by the options:
+    TREE_CHARSET            Default for --charset.
+    TREE_COLORS             Default for --colors, LS_COLORS is used if it's unset.
+    TREE_ICONS              Default for --icon-map.
+    TREE_IGNORE             Default for -I.
+`
+
+// flagValues are the values of the flags defined by newFlagSet.
+type flagValues struct {
+	// List
+	a          bool
+	d          bool
+	f          bool
+	ignorecase bool
+	matchpath  bool
+	glob       bool
+	noreport   bool
+	errsummary bool
+	errors     string
+	l          bool
+	deref      bool
+	L          int
+	P          string
+	I          string
+	o          string
+	append     bool
+	snapshot   string
+	changes    string
+	chjson     bool
+	verify     string
+	stdin      bool
+	nul        bool
+	completion string
+	minsize    string
+	maxsize    string
+	newer      string
+	older      string
+	owner      string
+	group      string
+	types      string
+	perm       string
+	empty      bool
+	broken     bool
+	executable bool
+	hidden     bool
+	hiddenf    bool
+	expr       string
+	fmindepth  int
+	fmaxdepth  int
+	prune      bool
+	filelimit  int
+	maxentries int
+	maxlines   int
+	dedup      bool
+	inodeorder bool
+	// Files
+	s        bool
+	h        bool
+	si       bool
+	iec      bool
+	prec     int
+	blocks   bool
+	disk     bool
+	uncomp   bool
+	p        bool
+	u        bool
+	g        bool
+	Q        bool
+	slash    bool
+	D        bool
+	timefmt  string
+	checksum string
+	hashw    int
+	merkle   bool
+	archives bool
+	inodes   bool
+	device   bool
+	fstype   bool
+	du       bool
+	mempty   bool
+	entries  bool
+	nfiles   bool
+	audit    bool
+	stale    string
+	hardlink bool
+	warnsize string
+	warnent  int
+	exec     string
+	execw    int
+	// Sort
+	U         bool
+	v         bool
+	t         bool
+	c         bool
+	r         bool
+	dirsfirst bool
+	sort      string
+	fold      bool
+	nodots    bool
+	// Graphics
+	i         bool
+	C         bool
+	highlight bool
+	magic     bool
+	charset   string
+	indent    int
+	namew     int
+	icons     bool
+	iconset   string
+	iconmap   string
+	info      bool
+	a11y      bool
+	bidi      bool
+	depthpfx  bool
+	mtree     bool
+	linkgraph string
+	format    string
+	json      bool
+	xml       bool
+	html      string
+	colors    string
+}
+
+// NewFlagSet returns a flag set with the flags accepted by ParseFlags
+// defined, e.g. to generate shell completions.
+func NewFlagSet() *flag.FlagSet {
+	fl, _ := newFlagSet()
+	return fl
+}
+
+func newFlagSet() (*flag.FlagSet, *flagValues) {
+	fl := flag.NewFlagSet("tree", flag.ContinueOnError)
+	fl.SetOutput(ioutil.Discard)
+	v := new(flagValues)
+	fl.BoolVar(&v.a, "a", false, "")
+	fl.BoolVar(&v.a, "all", false, "")
+	fl.BoolVar(&v.d, "d", false, "")
+	fl.BoolVar(&v.d, "dirs-only", false, "")
+	fl.BoolVar(&v.f, "f", false, "")
+	fl.BoolVar(&v.f, "full-path", false, "")
+	fl.BoolVar(&v.ignorecase, "ignore-case", false, "")
+	fl.BoolVar(&v.matchpath, "match-path", false, "")
+	fl.BoolVar(&v.glob, "glob", false, "")
+	fl.BoolVar(&v.noreport, "noreport", false, "")
+	fl.BoolVar(&v.errsummary, "error-summary", false, "")
+	fl.StringVar(&v.errors, "errors", "text", "")
+	fl.BoolVar(&v.l, "l", false, "")
+	fl.BoolVar(&v.l, "follow", false, "")
+	fl.BoolVar(&v.deref, "dereference", false, "")
+	fl.IntVar(&v.L, "L", 3, "")
+	fl.IntVar(&v.L, "level", 3, "")
+	fl.StringVar(&v.P, "P", "", "")
+	fl.StringVar(&v.P, "pattern", "", "")
+	fl.StringVar(&v.I, "I", "", "")
+	fl.StringVar(&v.I, "ignore", "", "")
+	fl.StringVar(&v.o, "o", "", "")
+	fl.StringVar(&v.o, "output", "", "")
+	fl.BoolVar(&v.append, "append", false, "")
+	fl.StringVar(&v.snapshot, "snapshot", "", "")
+	fl.StringVar(&v.changes, "changes", "", "")
+	fl.BoolVar(&v.chjson, "changes-json", false, "")
+	fl.StringVar(&v.verify, "verify", "", "")
+	fl.BoolVar(&v.stdin, "stdin", false, "")
+	fl.BoolVar(&v.nul, "0", false, "")
+	fl.StringVar(&v.completion, "completion", "", "")
+	fl.StringVar(&v.minsize, "min-size", "", "")
+	fl.StringVar(&v.maxsize, "max-size", "", "")
+	fl.StringVar(&v.newer, "newer", "", "")
+	fl.StringVar(&v.older, "older", "", "")
+	fl.StringVar(&v.owner, "owner", "", "")
+	fl.StringVar(&v.group, "group", "", "")
+	fl.StringVar(&v.types, "type", "", "")
+	fl.StringVar(&v.perm, "perm", "", "")
+	fl.BoolVar(&v.empty, "empty", false, "")
+	fl.BoolVar(&v.broken, "broken", false, "")
+	fl.BoolVar(&v.executable, "executable", false, "")
+	fl.BoolVar(&v.hidden, "hidden", false, "")
+	fl.BoolVar(&v.hiddenf, "hidden-files", false, "")
+	fl.StringVar(&v.expr, "expr", "", "")
+	fl.IntVar(&v.fmindepth, "filter-min-depth", 0, "")
+	fl.IntVar(&v.fmaxdepth, "filter-max-depth", 0, "")
+	fl.BoolVar(&v.prune, "prune", false, "")
+	fl.IntVar(&v.filelimit, "filelimit", 0, "")
+	fl.IntVar(&v.maxentries, "max-entries", 0, "")
+	fl.IntVar(&v.maxlines, "max-lines", 0, "")
+	fl.BoolVar(&v.dedup, "dedup", false, "")
+	fl.BoolVar(&v.inodeorder, "inode-order", false, "")
+	fl.BoolVar(&v.s, "s", false, "")
+	fl.BoolVar(&v.s, "size", false, "")
+	fl.BoolVar(&v.h, "h", false, "")
+	fl.BoolVar(&v.h, "human", false, "")
+	fl.BoolVar(&v.blocks, "blocks", false, "")
+	fl.BoolVar(&v.disk, "disk-size", false, "")
+	fl.BoolVar(&v.uncomp, "uncompressed", false, "")
+	fl.BoolVar(&v.si, "si", false, "")
+	fl.BoolVar(&v.iec, "iec", false, "")
+	fl.IntVar(&v.prec, "precision", 0, "")
+	fl.BoolVar(&v.p, "p", false, "")
+	fl.BoolVar(&v.p, "perms", false, "")
+	fl.BoolVar(&v.u, "u", false, "")
+	fl.BoolVar(&v.u, "show-owner", false, "")
+	fl.BoolVar(&v.g, "g", false, "")
+	fl.BoolVar(&v.g, "show-group", false, "")
+	fl.BoolVar(&v.Q, "Q", false, "")
+	fl.BoolVar(&v.Q, "quote", false, "")
+	fl.BoolVar(&v.slash, "slash", false, "")
+	fl.BoolVar(&v.D, "D", false, "")
+	fl.BoolVar(&v.D, "date", false, "")
+	fl.StringVar(&v.timefmt, "timefmt", "", "")
+	fl.StringVar(&v.checksum, "checksum", "", "")
+	fl.IntVar(&v.hashw, "hash-workers", 0, "")
+	fl.BoolVar(&v.merkle, "merkle", false, "")
+	fl.BoolVar(&v.archives, "archives", false, "")
+	fl.BoolVar(&v.inodes, "inodes", false, "")
+	fl.BoolVar(&v.device, "device", false, "")
+	fl.BoolVar(&v.fstype, "fstype", false, "")
+	fl.BoolVar(&v.du, "fsusage", false, "")
+	fl.BoolVar(&v.mempty, "mark-empty", false, "")
+	fl.BoolVar(&v.entries, "entries", false, "")
+	fl.BoolVar(&v.nfiles, "file-count", false, "")
+	fl.StringVar(&v.warnsize, "warn-size", "", "")
+	fl.IntVar(&v.warnent, "warn-entries", 0, "")
+	fl.BoolVar(&v.audit, "audit", false, "")
+	fl.StringVar(&v.stale, "stale", "", "")
+	fl.BoolVar(&v.hardlink, "hardlinks", false, "")
+	fl.StringVar(&v.exec, "exec", "", "")
+	fl.IntVar(&v.execw, "exec-workers", 0, "")
+	fl.BoolVar(&v.U, "U", false, "")
+	fl.BoolVar(&v.U, "unsorted", false, "")
+	fl.BoolVar(&v.v, "v", false, "")
+	fl.BoolVar(&v.v, "version-sort", false, "")
+	fl.BoolVar(&v.t, "t", false, "")
+	fl.BoolVar(&v.t, "time-sort", false, "")
+	fl.BoolVar(&v.c, "c", false, "")
+	fl.BoolVar(&v.c, "ctime-sort", false, "")
+	fl.BoolVar(&v.r, "r", false, "")
+	fl.BoolVar(&v.r, "reverse", false, "")
+	fl.BoolVar(&v.dirsfirst, "dirsfirst", false, "")
+	fl.BoolVar(&v.fold, "sort-fold", false, "")
+	fl.BoolVar(&v.nodots, "sort-nodots", false, "")
+	fl.StringVar(&v.sort, "sort", "", "")
+	fl.BoolVar(&v.i, "i", false, "")
+	fl.BoolVar(&v.i, "no-indent", false, "")
+	fl.BoolVar(&v.C, "C", false, "")
+	fl.BoolVar(&v.C, "color", false, "")
+	fl.BoolVar(&v.highlight, "highlight", false, "")
+	fl.BoolVar(&v.magic, "magic", false, "")
+	fl.StringVar(&v.charset, "charset", "", "")
+	fl.IntVar(&v.indent, "indent", 0, "")
+	fl.IntVar(&v.namew, "name-width", 0, "")
+	fl.BoolVar(&v.icons, "icons", false, "")
+	fl.StringVar(&v.iconset, "icon-set", "", "")
+	fl.StringVar(&v.iconmap, "icon-map", "", "")
+	fl.BoolVar(&v.info, "info", false, "")
+	fl.BoolVar(&v.a11y, "accessible", false, "")
+	fl.BoolVar(&v.bidi, "bidi", false, "")
+	fl.BoolVar(&v.depthpfx, "depth-prefix", false, "")
+	fl.BoolVar(&v.mtree, "mtree", false, "")
+	fl.StringVar(&v.linkgraph, "link-graph", "", "")
+	fl.StringVar(&v.format, "format", "", "")
+	fl.BoolVar(&v.json, "J", false, "")
+	fl.BoolVar(&v.json, "json", false, "")
+	fl.BoolVar(&v.xml, "X", false, "")
+	fl.BoolVar(&v.xml, "xml", false, "")
+	fl.StringVar(&v.html, "H", "", "")
+	fl.StringVar(&v.html, "html", "", "")
+	fl.StringVar(&v.colors, "colors", "", "")
+	return fl, v
+}
+
+// Args are the arguments of cmd/tree that aren't options: the paths to
+// walk, and the files to read them from and write the output to. ParseFlags
+// leaves them to the caller, so a command line that doesn't parse or
+// validate neither consumes os.Stdin nor truncates its output files.
+type Args struct {
+	// Paths are the paths to walk, given after the flags.
+	Paths []string
+	// Stdin reads more paths from os.Stdin ('--stdin'), separated by
+	// NUL instead of newlines if Nul is set ('-0').
+	Stdin, Nul bool
+	// Out is the output file ('-o'), appended to if Append is set.
+	Out    string
+	Append bool
+	// Snapshot is the file to write the snapshot to ('--snapshot').
+	Snapshot string
+	// Completion is the shell to print the completion script of instead
+	// ('--completion').
+	Completion string
+}
+
+// ReadStdin appends the paths read from os.Stdin to Paths, if the Stdin
+// flag was given.
+func (a *Args) ReadStdin() error {
+	if !a.Stdin {
+		return nil
+	}
+	sep := byte('\n')
+	if a.Nul {
+		sep = 0
+	}
+	paths, err := ReadPaths(os.Stdin, sep)
+	if err != nil {
+		return err
+	}
+	a.Paths = append(a.Paths, paths...)
+	return nil
+}
+
+// Open opens the Out and Snapshot files, and sets them as the OutFile and
+// Snapshot options. It closes the ones it opened if one fails, and the
+// caller should close them after the walk.
+func (a *Args) Open(opts *Options) error {
+	var snapshot, out *os.File
+	var err error
+	if a.Snapshot != "" {
+		if snapshot, err = os.Create(a.Snapshot); err != nil {
+			return err
+		}
+	}
+	if a.Out != "" {
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if a.Append {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		}
+		if out, err = os.OpenFile(a.Out, flags, 0666); err != nil {
+			if snapshot != nil {
+				snapshot.Close()
+			}
+			return err
+		}
+	}
+	if snapshot != nil {
+		opts.Snapshot = snapshot
+	}
+	if out != nil {
+		opts.OutFile = out
+	}
+	return nil
+}
+
+// ParseFlags parses the command line flags of cmd/tree (see Usage), and
+// returns the options they describe, and the remaining arguments. It only
+// reads the '--changes' and '--verify' files; the caller reads os.Stdin
+// and opens the output files with the Args methods, and sets the Fs
+// option. OutFile is os.Stdout until then.
+// It returns flag.ErrHelp if '-help' was given.
+func ParseFlags(args []string) (*Options, *Args, error) {
+	fl, v := newFlagSet()
+	if err := fl.Parse(expandShortFlags(fl, args)); err != nil {
+		return nil, nil, err
+	}
+	// Check sort-type
+	if v.sort != "" {
+		switch v.sort {
+		case "version", "mtime", "ctime", "name", "size", "extension":
+		default:
+			return nil, nil, fmt.Errorf("sort type '%s' not valid, should be one of: "+
+				"name,version,size,mtime,ctime,extension", v.sort)
+		}
+	}
+	// Check errors format
+	switch v.errors {
+	case "text", "json", "inline":
+	default:
+		return nil, nil, fmt.Errorf("errors format '%s' not valid, should be one of: "+
+			"text,json,inline", v.errors)
+	}
+	// Check size range
+	minSize, err := ParseSize(v.minsize)
+	if err != nil {
+		return nil, nil, err
+	}
+	maxSize, err := ParseSize(v.maxsize)
+	if err != nil {
+		return nil, nil, err
+	}
+	warnSize, err := ParseSize(v.warnsize)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Check time range
+	now := time.Now()
+	newerThan, err := parseTime(v.newer, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	olderThan, err := parseTime(v.older, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	staleSince, err := parseTime(v.stale, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Check file types
+	fileTypes, err := ParseFileTypes(v.types)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Check permission bits
+	permBits, permMatch, err := ParsePerm(v.perm)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Check expression
+	var fileExpr Expr
+	if v.expr != "" {
+		if fileExpr, err = ParseExpr(v.expr); err != nil {
+			return nil, nil, err
+		}
+	}
+	opts := &Options{
+		OutFile: os.Stdout,
+		// List
+		All:          v.a,
+		DirsOnly:     v.d,
+		FullPath:     v.f,
+		DeepLevel:    v.L,
+		FollowLink:   v.l,
+		Dereference:  v.deref,
+		Pattern:      v.P,
+		IPattern:     v.I,
+		IgnoreCase:   v.ignorecase,
+		ErrorSummary: v.errsummary,
+		ErrJSON:      v.errors == "json",
+		NoReport:     v.noreport || v.mtree || v.linkgraph != "" || v.format != "" || v.json || v.xml || v.html != "" || v.chjson,
+		MatchPath:    v.matchpath,
+		Glob:         v.glob,
+		MinSize:      minSize,
+		MaxSize:      maxSize,
+		NewerThan:    newerThan,
+		OlderThan:    olderThan,
+		ChangeTime:   v.c,
+		Owner:        v.owner,
+		Group:        v.group,
+		Types:        fileTypes,
+		Perm:         permBits,
+		PermMatch:    permMatch,
+		EmptyOnly:    v.empty,
+		BrokenOnly:   v.broken,
+		ExecOnly:     v.executable,
+		HiddenOnly:   v.hidden,
+		HiddenFiles:  v.hiddenf,
+		Expr:         fileExpr,
+		// Filters depth
+		FilterMinDepth:     v.fmindepth,
+		FilterMaxDepth:     v.fmaxdepth,
+		Prune:              v.prune,
+		FileLimit:          v.filelimit,
+		MaxEntries:         v.maxentries,
+		MaxLines:           v.maxlines,
+		CollapseDuplicates: v.dedup,
+		InodeOrder:         v.inodeorder,
+		// Files
+		ByteSize:       v.s,
+		UnitSize:       v.h || v.si || v.iec,
+		SizeUnits:      sizeUnitsFlag(v.si, v.iec),
+		SizePrecision:  v.prec,
+		Blocks:         v.blocks,
+		DiskSize:       v.disk,
+		Uncompressed:   v.uncomp,
+		FileMode:       v.p,
+		ShowUid:        v.u,
+		ShowGid:        v.g,
+		LastMod:        v.D,
+		TimeFormat:     v.timefmt,
+		Checksum:       v.checksum,
+		HashWorkers:    v.hashw,
+		Merkle:         v.merkle,
+		Archives:       v.archives,
+		Quotes:         v.Q,
+		DirSlash:       v.slash,
+		Inodes:         v.inodes,
+		Device:         v.device,
+		FsType:         v.fstype,
+		FsUsage:        v.du,
+		MarkEmpty:      v.mempty,
+		EntryCount:     v.entries,
+		FileCount:      v.nfiles,
+		SizeThreshold:  warnSize,
+		EntryThreshold: v.warnent,
+		Audit:          v.audit,
+		StaleSince:     staleSince,
+		Hardlinks:      v.hardlink,
+		ExecWorkers:    v.execw,
+		// Sort
+		NoSort:      v.U,
+		ReverSort:   v.r,
+		DirSort:     v.dirsfirst,
+		VerSort:     v.v || v.sort == "version",
+		ModSort:     v.t || v.sort == "mtime",
+		CTimeSort:   v.c || v.sort == "ctime",
+		NameSort:    v.sort == "name",
+		SizeSort:    v.sort == "size",
+		ExtSort:     v.sort == "extension",
+		FoldSort:    v.fold,
+		DotlessSort: v.nodots,
+		// Graphics
+		NoIndent:    v.i,
+		Colorize:    v.C,
+		Highlight:   v.highlight,
+		MagicColor:  v.magic,
+		Charset:     v.charset,
+		IndentWidth: v.indent,
+		NameWidth:   v.namew,
+		Icons:       v.icons || v.iconset != "",
+		Info:        v.info,
+		Accessible:  v.a11y,
+		BidiIsolate: v.bidi,
+		DepthPrefix: v.depthpfx,
+		Mtree:       v.mtree,
+		LinkGraph:   strings.ToLower(v.linkgraph),
+		Format:      v.format,
+		JSON:        v.json,
+		XML:         v.xml,
+		HTML:        v.html,
+		ChangesJSON: v.chjson,
+	}
+	if v.errors != "inline" {
+		opts.ErrFile = os.Stderr
+	}
+	// Check colors
+	if v.colors != "" {
+		if opts.Color, err = ParseColors(v.colors); err != nil {
+			return nil, nil, err
+		}
+	}
+	// Check icons
+	if v.iconset != "" || v.iconmap != "" {
+		set := EmojiIcons
+		if v.iconset != "" {
+			if set = IconSets[v.iconset]; set == nil {
+				return nil, nil, fmt.Errorf("icon set '%s' not valid, should be one of: emoji,nerd", v.iconset)
+			}
+		}
+		if set, err = ParseIcons(set, v.iconmap); err != nil {
+			return nil, nil, err
+		}
+		opts.Icon = set.Icon
+	}
+	// External command
+	if args := strings.Fields(v.exec); len(args) > 0 {
+		opts.Exec = ExecCommand(args[0], args[1:]...)
+	}
+	// Snapshots
+	if v.changes != "" {
+		f, err := os.Open(v.changes)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts.Baseline, err = ReadSnapshot(f)
+		f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("snapshot '%s': %v", v.changes, err)
+		}
+		if opts.Baseline == nil {
+			opts.Baseline = []*Entry{}
+		}
+	}
+	if v.verify != "" {
+		f, err := os.Open(v.verify)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts.Manifest, err = ReadManifest(f)
+		f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("manifest '%s': %v", v.verify, err)
+		}
+		if opts.Manifest == nil {
+			opts.Manifest = []*Entry{}
+		}
+		if opts.Checksum == "" {
+			opts.Checksum = ManifestChecksum(opts.Manifest)
+		}
+	}
+	return opts, &Args{
+		Paths:      fl.Args(),
+		Stdin:      v.stdin,
+		Nul:        v.nul,
+		Out:        v.o,
+		Append:     v.append,
+		Snapshot:   v.snapshot,
+		Completion: v.completion,
+	}, nil
+}
+
+// ReadPaths reads the sep separated paths of r (e.g. '\n', or 0 for the
+// output of 'find -print0'), skipping the empty ones.
+func ReadPaths(r io.Reader, sep byte) ([]string, error) {
+	var paths []string
+	br := bufio.NewReader(r)
+	for {
+		path, err := br.ReadString(sep)
+		path = strings.TrimSuffix(path, string(sep))
+		if sep == '\n' {
+			path = strings.TrimSuffix(path, "\r")
+		}
+		if path != "" {
+			paths = append(paths, path)
+		}
+		if err == io.EOF {
+			return paths, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// expandShortFlags splits the combined short flags of args like
+// getopt(3), e.g. "-alf" into "-a", "-l", "-f", and "-aL2" into "-a",
+// "-L", "2".
+func expandShortFlags(fl *flag.FlagSet, args []string) []string {
+	var expanded []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || len(arg) < 2 || arg[0] != '-' {
+			return append(expanded, args[i:]...)
+		}
+		name := strings.TrimLeft(arg, "-")
+		if j := strings.IndexByte(name, '='); j >= 0 {
+			name = name[:j]
+		}
+		if f := fl.Lookup(name); f != nil || name == "help" || len(name) < 2 || arg[1] == '-' {
+			expanded = append(expanded, arg)
+			// Value of a non-boolean flag
+			if f != nil && !isBoolFlag(f) && !strings.Contains(arg, "=") && i+1 < len(args) {
+				i++
+				expanded = append(expanded, args[i])
+			}
+			continue
+		}
+		for j := 1; j < len(arg); j++ {
+			expanded = append(expanded, "-"+arg[j:j+1])
+			f := fl.Lookup(arg[j : j+1])
+			if f == nil || isBoolFlag(f) {
+				continue
+			}
+			// The rest of the argument, or the next one, is the value
+			if j+1 < len(arg) {
+				expanded = append(expanded, arg[j+1:])
+			} else if i+1 < len(args) {
+				i++
+				expanded = append(expanded, args[i])
+			}
+			break
+		}
+	}
+	return expanded
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// parseTime parses either a duration relative to now (e.g. "24h"), or
+// a date/timestamp.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s'", s)
+}
+
+// sizeUnitsFlag returns the SizeUnits of the --si and --iec flags.
+func sizeUnitsFlag(si, iec bool) string {
+	switch {
+	case si:
+		return "si"
+	case iec:
+		return "iec"
+	}
+	return ""
+}
@@ -0,0 +1,141 @@
+package tree
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	opts, args, err := ParseFlags([]string{"-a", "-L", "2", "--sort", "size", "--min-size", "1K", "--noreport", "a", "b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !opts.All || opts.DeepLevel != 2 || !opts.SizeSort || opts.MinSize != 1024 || !opts.NoReport {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+	if !reflect.DeepEqual(args.Paths, []string{"a", "b"}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+	for _, test := range []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"-alf", "dir"}, []string{"-a", "-l", "-f", "dir"}},
+		{[]string{"-aL2", "-dL", "3", "-P", "-ad"}, []string{"-a", "-L", "2", "-d", "-L", "3", "-P", "-ad"}},
+		{[]string{"--dirsfirst", "-inodes", "-L=2", "-help"}, []string{"--dirsfirst", "-inodes", "-L=2", "-help"}},
+		{[]string{"-a", "--", "-alf"}, []string{"-a", "--", "-alf"}},
+	} {
+		if actual := expandShortFlags(NewFlagSet(), test.args); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%v: got %v, expected %v", test.args, actual, test.expected)
+		}
+	}
+	opts, _, err = ParseFlags([]string{"--all", "--level", "5", "-dr"})
+	if err != nil || !opts.All || opts.DeepLevel != 5 || !opts.DirsOnly || !opts.ReverSort {
+		t.Errorf("unexpected options: %+v, %v", opts, err)
+	}
+	for _, test := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"-ax"}, "flag provided but not defined: -x"},
+		{[]string{"-x"}, "flag provided but not defined: -x"},
+		{[]string{"--sort", "foo"}, "sort type 'foo' not valid, should be one of: name,version,size,mtime,ctime,extension"},
+		{[]string{"--newer", "yesterday"}, "invalid time 'yesterday'"},
+		{[]string{"--expr", "-foo x"}, "unknown test '-foo' in expression"},
+		{[]string{"--icon-set", "foo"}, "icon set 'foo' not valid, should be one of: emoji,nerd"},
+		{[]string{"--icon-map", "di"}, "invalid icons entry 'di'"},
+	} {
+		if _, _, err := ParseFlags(test.args); err == nil || err.Error() != test.expected {
+			t.Errorf("%v: got error %v, expected %s", test.args, err, test.expected)
+		}
+	}
+	if _, _, err := ParseFlags([]string{"-help"}); err != flag.ErrHelp {
+		t.Errorf("expected flag.ErrHelp, got: %v", err)
+	}
+}
+
+func TestParseFlagsOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "out")
+	for _, test := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"-o", path}, "a\n"},
+		{[]string{"-o", path, "--append"}, "a\na\n"},
+		{[]string{"-o", path}, "a\n"},
+	} {
+		opts, args, err := ParseFlags(test.args)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if opts.OutFile != os.Stdout {
+			t.Errorf("%v: ParseFlags opened the output file", test.args)
+		}
+		if err := args.Open(opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		f := opts.OutFile.(*os.File)
+		f.WriteString("a\n")
+		f.Close()
+		if b, _ := ioutil.ReadFile(path); string(b) != test.expected {
+			t.Errorf("%v: got %q, expected %q", test.args, b, test.expected)
+		}
+	}
+}
+
+func TestReadPaths(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		sep      byte
+		expected []string
+	}{
+		{"a\nb c\r\n\nd", '\n', []string{"a", "b c", "d"}},
+		{"a\x00b\nc\x00\x00", 0, []string{"a", "b\nc"}},
+		{"", '\n', nil},
+	} {
+		paths, err := ReadPaths(strings.NewReader(test.input), test.sep)
+		if err != nil || !reflect.DeepEqual(paths, test.expected) {
+			t.Errorf("%q: got %q, %v, expected %q", test.input, paths, err, test.expected)
+		}
+	}
+}
+
+func TestArgsOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	out, snapshot := filepath.Join(dir, "out"), filepath.Join(dir, "snapshot")
+	if err := ioutil.WriteFile(out, []byte("a\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	opts, args, err := ParseFlags([]string{"-o", out, "--snapshot", snapshot})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b, _ := ioutil.ReadFile(out); string(b) != "a\n" {
+		t.Errorf("ParseFlags truncated the output file: %q", b)
+	}
+	if _, err := os.Stat(snapshot); !os.IsNotExist(err) {
+		t.Errorf("ParseFlags created the snapshot file: %v", err)
+	}
+	// A failing output file doesn't leave the snapshot set.
+	args.Out = filepath.Join(dir, "missing", "out")
+	if err := args.Open(opts); err == nil {
+		t.Fatal("expected an error for the missing output dir")
+	}
+	if opts.OutFile != os.Stdout || opts.Snapshot != nil {
+		t.Errorf("unexpected files after a failed Open: %v, %v", opts.OutFile, opts.Snapshot)
+	}
+}
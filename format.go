@@ -0,0 +1,77 @@
+package tree
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Formatter prints a visited tree in an output format, see RegisterFormat
+// and the Format option.
+type Formatter interface {
+	Format(w io.Writer, node *Node, opts *Options) error
+}
+
+// FormatterFunc is a function used as a Formatter.
+type FormatterFunc func(w io.Writer, node *Node, opts *Options) error
+
+// Format calls f.
+func (f FormatterFunc) Format(w io.Writer, node *Node, opts *Options) error {
+	return f(w, node, opts)
+}
+
+// formats are the registered Formatters, by name.
+var formats = struct {
+	sync.RWMutex
+	m map[string]Formatter
+}{m: make(map[string]Formatter)}
+
+// RegisterFormat makes a Formatter available under the given name, for
+// the Format option. It's meant to be called from the init function of
+// the packages providing formats, e.g. an org-mode one, so a command
+// importing them can select it by name. Like database/sql.Register, it
+// panics if the name is already registered or the Formatter is nil.
+func RegisterFormat(name string, f Formatter) {
+	formats.Lock()
+	defer formats.Unlock()
+	if f == nil {
+		panic("tree: RegisterFormat formatter is nil")
+	}
+	if _, dup := formats.m[name]; dup {
+		panic("tree: RegisterFormat called twice for format " + name)
+	}
+	formats.m[name] = f
+}
+
+// Formats returns the sorted names of the registered formats.
+func Formats() []string {
+	formats.RLock()
+	defer formats.RUnlock()
+	names := make([]string, 0, len(formats.m))
+	for name := range formats.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupFormat returns the Formatter registered under name, or nil.
+func lookupFormat(name string) Formatter {
+	formats.RLock()
+	defer formats.RUnlock()
+	return formats.m[name]
+}
+
+// printFormat prints the visited node with the Formatter of the Format
+// option.
+func (node *Node) printFormat(opts *Options) {
+	f := lookupFormat(opts.Format)
+	if f == nil {
+		opts.warn("unknown format", node.path, fmt.Errorf("format '%s' not registered", opts.Format))
+		return
+	}
+	if err := f.Format(opts.OutFile, node, opts); err != nil {
+		opts.warn("format failed", node.path, err)
+	}
+}
@@ -0,0 +1,39 @@
+package tree
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestRegisterFormat(t *testing.T) {
+	RegisterFormat("test-paths", FormatterFunc(func(w io.Writer, node *Node, opts *Options) error {
+		for _, n := range node.Flatten() {
+			fmt.Fprintf(w, "%d %s\n", n.Depth(), n.Path())
+		}
+		return nil
+	}))
+	found := false
+	for _, name := range Formats() {
+		found = found || name == "test-paths"
+	}
+	if !found {
+		t.Errorf("Formats: got %v, expected test-paths", Formats())
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic on a duplicate format")
+			}
+		}()
+		RegisterFormat("test-paths", FormatterFunc(nil))
+	}()
+	root := &file{name: "root", files: []*file{{name: "a"}, {name: "b", files: []*file{{name: "c"}}}}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"format", &Options{Fs: fs, OutFile: out, Format: "test-paths"}, `0 root
+1 root/a
+1 root/b
+2 root/b/c
+`, 1, 2}})
+}
@@ -0,0 +1,93 @@
+package tree
+
+import (
+	"fmt"
+	"io"
+)
+
+// FsInfo describes the filesystem a path resides on.
+type FsInfo struct {
+	// Type is the filesystem type name, e.g. "ext4", "nfs" or "tmpfs".
+	Type string
+	// Total, Free and Avail are the filesystem capacity in bytes. Avail
+	// is the free space available to unprivileged users.
+	Total uint64
+	Free  uint64
+	Avail uint64
+}
+
+// Used returns the number of used bytes.
+func (info *FsInfo) Used() uint64 {
+	return info.Total - info.Free
+}
+
+// FsStater may be implemented by an Fs that is able to report
+// filesystem information for a given path. It's used by the 'FsType'
+// and 'FsUsage' options.
+type FsStater interface {
+	Statfs(path string) (*FsInfo, error)
+}
+
+// Mount is a distinct filesystem encountered during the walk.
+type Mount struct {
+	// Path is the first visited path residing on the filesystem,
+	// either the root of the walk or a mount point.
+	Path string
+	*FsInfo
+}
+
+// statfs returns the filesystem information of the given path, or nil
+// if the Fs does not support it.
+func (opts *Options) statfs(path string) *FsInfo {
+	sfs, ok := opts.Fs.(FsStater)
+	if !ok {
+		return nil
+	}
+	info, err := sfs.Statfs(path)
+	if err != nil {
+		return nil
+	}
+	return info
+}
+
+// crossesMount reports whether node resides on a different device than
+// its parent.
+func (node *Node) crossesMount(parent *Node) bool {
+	ok1, _, pdev, _, _ := getStat(parent)
+	ok2, _, dev, _, _ := getStat(node)
+	return ok1 && ok2 && pdev != dev
+}
+
+// Mounts returns the distinct filesystems encountered while visiting
+// the node, in visit order. Visit must be called with 'FsType' or
+// 'FsUsage' option.
+func (node *Node) Mounts() []Mount {
+	return node.appendMounts(nil, make(map[string]bool))
+}
+
+// appendMounts appends the mounts of node that are not in seen.
+func (node *Node) appendMounts(mounts []Mount, seen map[string]bool) []Mount {
+	if node.fsinfo != nil {
+		key := node.path
+		if ok, _, dev, _, _ := getStat(node); ok {
+			key = fmt.Sprintf("dev:%d", dev)
+		}
+		if !seen[key] {
+			seen[key] = true
+			mounts = append(mounts, Mount{node.path, node.fsinfo})
+		}
+	}
+	for _, nnode := range node.nodes {
+		mounts = nnode.appendMounts(mounts, seen)
+	}
+	return mounts
+}
+
+// FprintMounts writes a df-like capacity report of the given mounts.
+func FprintMounts(w io.Writer, mounts []Mount) {
+	fmt.Fprintf(w, "%-8s %5s %5s %5s  %s\n", "Type", "Size", "Used", "Avail", "Mounted on")
+	for _, m := range mounts {
+		fmt.Fprintf(w, "%-8s %5s %5s %5s  %s\n", m.Type, formatBytes(int64(m.Total)),
+			formatBytes(int64(m.Used())), formatBytes(int64(m.Avail)), m.Path)
+	}
+}
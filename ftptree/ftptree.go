@@ -0,0 +1,353 @@
+// Package ftptree implements a tree.Fs of an FTP or FTPS server.
+//
+// The directories are listed with MLSD if the server supports it, or by
+// parsing the Unix and MS-DOS formats of LIST otherwise, e.g.:
+//
+//	fs, err := ftptree.Dial("ftp.example.com:21", nil)
+//	if err != nil {
+//		return err
+//	}
+//	defer fs.Close()
+//	if err := fs.Login("anonymous", "anonymous"); err != nil {
+//		return err
+//	}
+//	opts := &tree.Options{Fs: fs, OutFile: os.Stdout}
+//	inf := tree.New("/pub")
+//	inf.Visit(opts)
+//	inf.Print(opts)
+package ftptree
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/textproto"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FS is the file-system of an FTP server. Each directory is listed once,
+// and then served from memory. The server is used by one request at a
+// time, so the files opened with Open must be closed before the FS is
+// used again.
+type FS struct {
+	conn net.Conn
+	text *textproto.Conn
+	tls  *tls.Config
+	mlsd bool
+
+	mu   sync.Mutex
+	dirs map[string]*listing
+}
+
+// listing is the content of a directory.
+type listing struct {
+	names []string
+	files map[string]*fileInfo
+}
+
+// Dial connects to an FTP server. If config is set, the connection is
+// secured with explicit FTPS (AUTH TLS), the data connections too.
+func Dial(addr string, config *tls.Config) (*FS, error) {
+	conn, err := net.DialTimeout("tcp", addr, 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	fs := &FS{conn: conn, text: textproto.NewConn(conn), tls: config}
+	if _, _, err := fs.text.ReadResponse(220); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if config != nil {
+		if _, err := fs.cmd(234, "AUTH TLS"); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if config.ServerName == "" {
+			config = config.Clone()
+			config.ServerName, _, _ = net.SplitHostPort(addr)
+			fs.tls = config
+		}
+		fs.conn = tls.Client(conn, fs.tls)
+		fs.text = textproto.NewConn(fs.conn)
+	}
+	return fs, nil
+}
+
+// Login authenticates the user, and prepares the session for the
+// listings.
+func (fs *FS) Login(user, password string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	code, msg, err := fs.cmdAny("USER %s", user)
+	switch {
+	case err != nil:
+		return err
+	case code == 331:
+		if _, err := fs.cmd(230, "PASS %s", password); err != nil {
+			return err
+		}
+	case code != 230:
+		return &textproto.Error{Code: code, Msg: msg}
+	}
+	if fs.tls != nil {
+		if _, err := fs.cmd(200, "PBSZ 0"); err != nil {
+			return err
+		}
+		if _, err := fs.cmd(200, "PROT P"); err != nil {
+			return err
+		}
+	}
+	if _, err := fs.cmd(200, "TYPE I"); err != nil {
+		return err
+	}
+	if _, msg, err := fs.cmdAny("FEAT"); err == nil {
+		for _, line := range strings.Split(msg, "\n") {
+			if strings.EqualFold(strings.TrimSpace(line), "MLSD") ||
+				strings.HasPrefix(strings.ToUpper(strings.TrimSpace(line)), "MLST ") {
+				fs.mlsd = true
+			}
+		}
+	}
+	return nil
+}
+
+// Close ends the session.
+func (fs *FS) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.cmdAny("QUIT")
+	return fs.conn.Close()
+}
+
+// Stat returns the FileInfo of a path, without following symbolic links.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	name = path.Clean(name)
+	if name == "/" || name == "." {
+		return &fileInfo{name: name, mode: os.ModeDir | 0755}, nil
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	l, err := fs.list(path.Dir(name))
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	fi, ok := l.files[path.Base(name)]
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return fi, nil
+}
+
+// ReadDir returns the names of the entries of a directory.
+func (fs *FS) ReadDir(name string) ([]string, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	l, err := fs.list(path.Clean(name))
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return l.names, nil
+}
+
+// Readlink returns the target of a symbolic link, see tree.LinkReader.
+func (fs *FS) Readlink(name string) (string, error) {
+	fi, err := fs.Stat(name)
+	if err != nil {
+		return "", err
+	}
+	if fi := fi.(*fileInfo); fi.mode&os.ModeSymlink != 0 && fi.link != "" {
+		return fi.link, nil
+	}
+	return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrInvalid}
+}
+
+// Open retrieves the content of a file, see tree.FileOpener. The FS
+// can't be used until the file is closed.
+func (fs *FS) Open(name string) (io.ReadCloser, error) {
+	fs.mu.Lock()
+	data, err := fs.transfer("RETR %s", name)
+	if err != nil {
+		fs.mu.Unlock()
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &file{fs: fs, data: data}, nil
+}
+
+// file is the content of a file being retrieved.
+type file struct {
+	fs   *FS
+	data net.Conn
+	once sync.Once
+}
+
+func (f *file) Read(b []byte) (int, error) { return f.data.Read(b) }
+
+func (f *file) Close() error {
+	err := errors.New("file already closed")
+	f.once.Do(func() {
+		defer f.fs.mu.Unlock()
+		f.data.Close()
+		_, _, err = f.fs.text.ReadResponse(2)
+		// An early close may abort the transfer
+		if e, ok := err.(*textproto.Error); ok && e.Code/100 == 4 {
+			err = nil
+		}
+	})
+	return err
+}
+
+// list returns the listing of a directory. fs.mu must be held.
+func (fs *FS) list(dir string) (*listing, error) {
+	if l, ok := fs.dirs[dir]; ok {
+		return l, nil
+	}
+	verb := "LIST"
+	if fs.mlsd {
+		verb = "MLSD"
+	}
+	data, err := fs.transfer("%s %s", verb, dir)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(data)
+	data.Close()
+	if _, _, rerr := fs.text.ReadResponse(2); err == nil {
+		err = rerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	l := &listing{files: make(map[string]*fileInfo)}
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+		var fi *fileInfo
+		if fs.mlsd {
+			fi = parseMLSD(line)
+		} else {
+			fi = parseList(line, time.Now())
+		}
+		if fi == nil || fi.name == "." || fi.name == ".." {
+			continue
+		}
+		if _, ok := l.files[fi.name]; !ok {
+			l.names = append(l.names, fi.name)
+		}
+		l.files[fi.name] = fi
+	}
+	sort.Strings(l.names)
+	if fs.dirs == nil {
+		fs.dirs = make(map[string]*listing)
+	}
+	fs.dirs[dir] = l
+	return l, nil
+}
+
+// transfer opens a passive data connection, and sends the command that
+// uses it.
+func (fs *FS) transfer(format string, args ...interface{}) (net.Conn, error) {
+	addr, err := fs.passive()
+	if err != nil {
+		return nil, err
+	}
+	data, err := net.DialTimeout("tcp", addr, 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fs.cmd(1, format, args...); err != nil {
+		data.Close()
+		return nil, err
+	}
+	if fs.tls != nil {
+		data = tls.Client(data, fs.tls)
+	}
+	return data, nil
+}
+
+// passive returns the address of a passive data connection, with EPSV
+// or PASV.
+func (fs *FS) passive() (string, error) {
+	host, _, _ := net.SplitHostPort(fs.conn.RemoteAddr().String())
+	if msg, err := fs.cmd(229, "EPSV"); err == nil {
+		// e.g. "Entering Extended Passive Mode (|||6446|)"
+		start, end := strings.Index(msg, "(|||"), strings.LastIndex(msg, "|)")
+		if start < 0 || end < start+4 {
+			return "", fmt.Errorf("invalid EPSV response %q", msg)
+		}
+		return net.JoinHostPort(host, msg[start+4:end]), nil
+	}
+	msg, err := fs.cmd(227, "PASV")
+	if err != nil {
+		return "", err
+	}
+	// e.g. "Entering Passive Mode (192,168,1,2,19,34)"
+	start, end := strings.Index(msg, "("), strings.Index(msg, ")")
+	if start < 0 || end < start {
+		return "", fmt.Errorf("invalid PASV response %q", msg)
+	}
+	parts := strings.Split(msg[start+1:end], ",")
+	if len(parts) != 6 {
+		return "", fmt.Errorf("invalid PASV response %q", msg)
+	}
+	hi, err1 := strconv.Atoi(parts[4])
+	lo, err2 := strconv.Atoi(parts[5])
+	if err1 != nil || err2 != nil {
+		return "", fmt.Errorf("invalid PASV response %q", msg)
+	}
+	// The advertised host is ignored, it's often a private one
+	return net.JoinHostPort(host, strconv.Itoa(hi<<8|lo)), nil
+}
+
+// cmd sends a command, and reads its response, expecting the given code.
+func (fs *FS) cmd(expect int, format string, args ...interface{}) (string, error) {
+	id, err := fs.text.Cmd(format, args...)
+	if err != nil {
+		return "", err
+	}
+	fs.text.StartResponse(id)
+	defer fs.text.EndResponse(id)
+	_, msg, err := fs.text.ReadResponse(expect)
+	return msg, err
+}
+
+// cmdAny sends a command, and returns its response whatever its code.
+func (fs *FS) cmdAny(format string, args ...interface{}) (int, string, error) {
+	id, err := fs.text.Cmd(format, args...)
+	if err != nil {
+		return 0, "", err
+	}
+	fs.text.StartResponse(id)
+	defer fs.text.EndResponse(id)
+	code, msg, err := fs.text.ReadResponse(0)
+	if _, ok := err.(*textproto.Error); ok {
+		err = nil
+	}
+	return code, msg, err
+}
+
+// fileInfo is the FileInfo of a listed entry.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+	link    string
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,175 @@
+package ftptree
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a8m/tree"
+)
+
+// listings of the mock server, in the Unix LIST and the MLSD formats.
+var (
+	lists = map[string]string{
+		"/": "drwxr-xr-x   3 ftp      ftp          4096 Jan  2  2019 pub\r\n",
+		"/pub": "total 3\r\n" +
+			"drwxr-xr-x   2 ftp      ftp          4096 Jan  2  2019 docs\r\n" +
+			"-rw-r--r--   1 ftp      ftp           123 Jan  2  2019 README.txt\r\n" +
+			"lrwxrwxrwx   1 ftp      ftp             4 Jan  2  2019 latest -> docs\r\n",
+		"/pub/docs": "-rw-r--r--   1 ftp      ftp            42 Jan  2  2019 a file.txt\r\n",
+	}
+	mlsds = map[string]string{
+		"/": "type=dir;modify=20190102000000; pub\r\n",
+		"/pub": "type=cdir;modify=20190102000000; .\r\n" +
+			"type=dir;modify=20190102000000;UNIX.mode=0750; docs\r\n" +
+			"type=file;size=123;modify=20190102000000; README.txt\r\n" +
+			"type=OS.unix=slink:docs;modify=20190102000000; latest\r\n",
+		"/pub/docs": "type=file;size=42;modify=20190102000000; a file.txt\r\n",
+	}
+)
+
+// serve runs a mock FTP server, supporting MLSD if mlsd is set.
+func serve(t *testing.T, mlsd bool) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		conn, err := ln.Accept()
+		ln.Close()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 ready\r\n")
+		var data net.Listener
+		send := func(content string) {
+			fmt.Fprint(conn, "150 opening\r\n")
+			if c, err := data.Accept(); err == nil {
+				c.Write([]byte(content))
+				c.Close()
+			}
+			data.Close()
+			fmt.Fprint(conn, "226 done\r\n")
+		}
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+			arg := ""
+			if len(fields) > 1 {
+				arg = fields[1]
+			}
+			switch fields[0] {
+			case "USER":
+				fmt.Fprint(conn, "331 password\r\n")
+			case "PASS":
+				fmt.Fprint(conn, "230 logged in\r\n")
+			case "TYPE":
+				fmt.Fprint(conn, "200 ok\r\n")
+			case "FEAT":
+				if mlsd {
+					fmt.Fprint(conn, "211-Features:\r\n MLST type*;size*;modify*;\r\n211 End\r\n")
+				} else {
+					fmt.Fprint(conn, "211-Features:\r\n SIZE\r\n211 End\r\n")
+				}
+			case "EPSV":
+				data, _ = net.Listen("tcp", "127.0.0.1:0")
+				fmt.Fprintf(conn, "229 Entering Extended Passive Mode (|||%d|)\r\n", data.Addr().(*net.TCPAddr).Port)
+			case "LIST":
+				send(lists[arg])
+			case "MLSD":
+				send(mlsds[arg])
+			case "RETR":
+				send("content of " + arg)
+			case "QUIT":
+				fmt.Fprint(conn, "221 bye\r\n")
+				return
+			default:
+				fmt.Fprint(conn, "502 not implemented\r\n")
+			}
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestFS(t *testing.T) {
+	for _, mlsd := range []bool{false, true} {
+		fs, err := Dial(serve(t, mlsd), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.Login("anonymous", "guest"); err != nil {
+			t.Fatal(err)
+		}
+		b := new(bytes.Buffer)
+		opts := &tree.Options{Fs: fs, OutFile: b, ByteSize: true, FollowLink: true}
+		inf := tree.New("/pub")
+		inf.Visit(opts)
+		inf.Print(opts)
+		// The link resolves to a sibling directory, it's followed
+		expected := `[        207]  /pub
+├── [        123]  README.txt
+├── [         42]  docs
+│   └── [         42]  a file.txt
+└── [          4]  latest -> docs
+    └── [         42]  a file.txt
+`
+		if mlsd {
+			expected = strings.Replace(expected, "[          4]  latest", "[          0]  latest", 1)
+		}
+		if b.String() != expected {
+			t.Errorf("mlsd=%v, got:\n%s\nexpected:\n%s", mlsd, b, expected)
+		}
+		r, err := fs.Open("/pub/README.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := ioutil.ReadAll(r)
+		if err := r.Close(); err != nil || string(content) != "content of /pub/README.txt" {
+			t.Errorf("unexpected content %q, %v", content, err)
+		}
+		fi, err := fs.Stat("/pub/docs")
+		if err != nil || !fi.IsDir() {
+			t.Errorf("expect docs to be a directory, got %v, %v", fi, err)
+		}
+		if mlsd && fi.Mode().Perm() != 0750 {
+			t.Errorf("expect the UNIX.mode permissions, got %v", fi.Mode())
+		}
+		fs.Close()
+	}
+}
+
+func TestParseList(t *testing.T) {
+	now := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		line string
+		name string
+		size int64
+		mode os.FileMode
+		time time.Time
+	}{
+		{"-rwsr-xr-x 1 root root 1024 Feb 10 12:30 su", "su", 1024, 0755 | os.ModeSetuid, time.Date(2020, 2, 10, 12, 30, 0, 0, time.UTC)},
+		{"-rw-r--r-- 1 ftp 12 Dec 24 08:00 no group", "no group", 12, 0644, time.Date(2019, 12, 24, 8, 0, 0, 0, time.UTC)},
+		{"drwxrwxrwt 9 root root 4096 Jan  2  2018 tmp", "tmp", 4096, 0777 | os.ModeDir | os.ModeSticky, time.Date(2018, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"01-02-20  03:04PM       <DIR>          Program Files", "Program Files", 0, 0755 | os.ModeDir, time.Date(2020, 1, 2, 15, 4, 0, 0, time.UTC)},
+		{"01-02-20  03:04AM               1234 setup.exe", "setup.exe", 1234, 0644, time.Date(2020, 1, 2, 3, 4, 0, 0, time.UTC)},
+	} {
+		fi := parseList(test.line, now)
+		if fi == nil || fi.name != test.name || fi.size != test.size || fi.mode != test.mode || !fi.modTime.Equal(test.time) {
+			t.Errorf("parseList(%q) = %+v", test.line, fi)
+		}
+	}
+	if fi := parseList("total 42", now); fi != nil {
+		t.Errorf("expect nil for a total line, got %+v", fi)
+	}
+}
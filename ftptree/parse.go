@@ -0,0 +1,177 @@
+package ftptree
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseMLSD parses a line of a MLSD listing, e.g.
+// "type=file;size=1024;modify=20200102030405;UNIX.mode=0644; notes.txt".
+// It returns nil for the invalid lines.
+func parseMLSD(line string) *fileInfo {
+	i := strings.Index(line, "; ")
+	if i < 0 {
+		return nil
+	}
+	fi := &fileInfo{name: line[i+2:], mode: 0644}
+	perm := os.FileMode(0)
+	for _, fact := range strings.Split(line[:i], ";") {
+		j := strings.IndexByte(fact, '=')
+		if j < 0 {
+			continue
+		}
+		key, value := strings.ToLower(fact[:j]), fact[j+1:]
+		switch key {
+		case "type":
+			switch t := strings.ToLower(value); {
+			case t == "dir" || t == "cdir" || t == "pdir":
+				fi.mode = os.ModeDir | 0755
+				if t != "dir" {
+					fi.name = map[string]string{"cdir": ".", "pdir": ".."}[t]
+				}
+			case strings.HasPrefix(t, "os.unix=slink"), strings.HasPrefix(t, "os.unix=symlink"):
+				fi.mode = os.ModeSymlink | 0777
+				if k := strings.IndexByte(value, ':'); k >= 0 {
+					fi.link = value[k+1:]
+				}
+			}
+		case "size", "sizd":
+			fi.size, _ = strconv.ParseInt(value, 10, 64)
+		case "modify":
+			fi.modTime = parseMLSDTime(value)
+		case "unix.mode":
+			if m, err := strconv.ParseUint(value, 8, 32); err == nil {
+				perm = os.FileMode(m) & os.ModePerm
+			}
+		}
+	}
+	if perm != 0 && fi.mode&os.ModeSymlink == 0 {
+		fi.mode = fi.mode&os.ModeType | perm
+	}
+	return fi
+}
+
+// parseMLSDTime parses a time of MLSD, e.g. "20200102030405.123".
+func parseMLSDTime(s string) time.Time {
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s = s[:i]
+	}
+	t, _ := time.Parse("20060102150405", s)
+	return t
+}
+
+// parseList parses a line of a LIST listing, in the Unix format, e.g.
+// "drwxr-xr-x 2 user group 4096 Jan  2 03:04 docs", or the MS-DOS one,
+// e.g. "01-02-20  03:04AM  <DIR>  docs". The year of the recent Unix
+// dates is guessed from now. It returns nil for the invalid lines, like
+// the "total 42" ones.
+func parseList(line string, now time.Time) *fileInfo {
+	fields := strings.Fields(line)
+	if len(fields) >= 4 && len(fields[0]) == 8 && fields[0][2] == '-' {
+		return parseDOSList(line, fields)
+	}
+	if len(fields) < 9 || len(fields[0]) < 10 {
+		return nil
+	}
+	fi := &fileInfo{}
+	switch fields[0][0] {
+	case '-':
+	case 'd':
+		fi.mode = os.ModeDir
+	case 'l':
+		fi.mode = os.ModeSymlink
+	case 'p':
+		fi.mode = os.ModeNamedPipe
+	case 's':
+		fi.mode = os.ModeSocket
+	case 'c':
+		fi.mode = os.ModeDevice | os.ModeCharDevice
+	case 'b':
+		fi.mode = os.ModeDevice
+	default:
+		return nil
+	}
+	for i, c := range fields[0][1:10] {
+		if c != '-' {
+			fi.mode |= 1 << uint(8-i)
+		}
+		switch {
+		case i == 2 && (c == 's' || c == 'S'):
+			fi.mode |= os.ModeSetuid
+		case i == 5 && (c == 's' || c == 'S'):
+			fi.mode |= os.ModeSetgid
+		case i == 8 && (c == 't' || c == 'T'):
+			fi.mode |= os.ModeSticky
+		}
+		if c == 'S' || c == 'T' {
+			fi.mode &^= 1 << uint(8-i)
+		}
+	}
+	// The group may be missing, find the size before the date
+	date := 4
+	for ; date < len(fields)-3; date++ {
+		if _, err := time.Parse("Jan", fields[date]); err == nil {
+			break
+		}
+	}
+	if date >= len(fields)-3 {
+		return nil
+	}
+	size, err := strconv.ParseInt(fields[date-1], 10, 64)
+	if err != nil {
+		// The devices have "major, minor" instead
+		size = 0
+	}
+	fi.size = size
+	fi.modTime = parseListTime(fields[date:date+3], now)
+	// The name is the rest of the line, after the date fields
+	name := line
+	for _, f := range fields[:date+3] {
+		name = strings.TrimLeft(name, " \t")
+		name = name[len(f):]
+	}
+	fi.name = strings.TrimLeft(name, " \t")
+	if fi.mode&os.ModeSymlink != 0 {
+		if i := strings.Index(fi.name, " -> "); i >= 0 {
+			fi.name, fi.link = fi.name[:i], fi.name[i+4:]
+		}
+	}
+	return fi
+}
+
+// parseListTime parses the date of a Unix LIST line, e.g. "Jan 2 03:04"
+// for the last 6 months, or "Jan 2 2019".
+func parseListTime(f []string, now time.Time) time.Time {
+	if strings.Contains(f[2], ":") {
+		t, err := time.Parse("Jan 2 15:04 2006", strings.Join(f, " ")+" "+strconv.Itoa(now.Year()))
+		if err == nil && t.After(now.AddDate(0, 1, 0)) {
+			t = t.AddDate(-1, 0, 0)
+		}
+		return t
+	}
+	t, _ := time.Parse("Jan 2 2006", strings.Join(f, " "))
+	return t
+}
+
+// parseDOSList parses a line of a MS-DOS LIST listing.
+func parseDOSList(line string, fields []string) *fileInfo {
+	t, err := time.Parse("01-02-06 03:04PM", fields[0]+" "+fields[1])
+	if err != nil {
+		return nil
+	}
+	fi := &fileInfo{modTime: t, mode: 0644}
+	if fields[2] == "<DIR>" {
+		fi.mode = os.ModeDir | 0755
+	} else if fi.size, err = strconv.ParseInt(fields[2], 10, 64); err != nil {
+		return nil
+	}
+	name := line
+	for _, f := range fields[:3] {
+		name = strings.TrimLeft(name, " \t")
+		name = name[len(f):]
+	}
+	fi.name = strings.TrimLeft(name, " \t")
+	return fi
+}
@@ -0,0 +1,229 @@
+// Package gcstree implements a tree.Fs of a Google Cloud Storage bucket.
+//
+// The directories are emulated by listing the objects with a "/"
+// delimiter, using the JSON API, e.g.:
+//
+//	fs := gcstree.New("my-bucket")
+//	fs.Client = client // An authorized client, for private buckets
+//	opts := &tree.Options{Fs: fs, OutFile: os.Stdout}
+//	inf := tree.New("logs")
+//	inf.Visit(opts)
+//	inf.Print(opts)
+package gcstree
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultEndpoint is the default endpoint of the JSON API.
+const DefaultEndpoint = "https://storage.googleapis.com/storage/v1"
+
+// FS is the file-system of a bucket. The paths are the names of the
+// objects, and "." or "" is the root of the bucket. Each directory is
+// listed once, and then served from memory.
+type FS struct {
+	Bucket string
+	// Client sends the requests, http.DefaultClient by default. It must
+	// be authorized (e.g. by golang.org/x/oauth2/google) unless the
+	// bucket is public.
+	Client   *http.Client
+	Endpoint string
+
+	mu   sync.Mutex
+	dirs map[string]*listing
+}
+
+// listing is the content of a directory.
+type listing struct {
+	names []string
+	files map[string]*fileInfo
+}
+
+// New returns the FS of the given bucket.
+func New(bucket string) *FS {
+	return &FS{Bucket: bucket}
+}
+
+// Stat returns the FileInfo of an object or a directory.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	name = clean(name)
+	if name == "" {
+		return &fileInfo{name: fs.Bucket, mode: os.ModeDir | 0755}, nil
+	}
+	l, err := fs.list(parent(name))
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	fi, ok := l.files[path.Base(name)]
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return fi, nil
+}
+
+// ReadDir returns the names of the objects and of the directories below a
+// directory.
+func (fs *FS) ReadDir(name string) ([]string, error) {
+	l, err := fs.list(clean(name))
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return l.names, nil
+}
+
+// Open reads the content of an object, see tree.FileOpener.
+func (fs *FS) Open(name string) (io.ReadCloser, error) {
+	u := fmt.Sprintf("%s/b/%s/o/%s?alt=media", fs.endpoint(), url.PathEscape(fs.Bucket), url.PathEscape(clean(name)))
+	resp, err := fs.get(u)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return resp.Body, nil
+}
+
+// list returns the listing of a directory, "" for the root.
+func (fs *FS) list(dir string) (*listing, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if l, ok := fs.dirs[dir]; ok {
+		return l, nil
+	}
+	prefix := dir
+	if prefix != "" {
+		prefix += "/"
+	}
+	l := &listing{files: make(map[string]*fileInfo)}
+	q := url.Values{"delimiter": {"/"}, "prefix": {prefix}, "fields": {"items(name,size,updated),prefixes,nextPageToken"}}
+	for {
+		resp, err := fs.get(fmt.Sprintf("%s/b/%s/o?%s", fs.endpoint(), url.PathEscape(fs.Bucket), q.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Items []struct {
+				Name    string    `json:"name"`
+				Size    string    `json:"size"`
+				Updated time.Time `json:"updated"`
+			} `json:"items"`
+			Prefixes      []string `json:"prefixes"`
+			NextPageToken string   `json:"nextPageToken"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range page.Prefixes {
+			name := strings.TrimSuffix(strings.TrimPrefix(p, prefix), "/")
+			if name != "" {
+				l.files[name] = &fileInfo{name: name, mode: os.ModeDir | 0755}
+			}
+		}
+		for _, item := range page.Items {
+			name := strings.TrimPrefix(item.Name, prefix)
+			// Skip the placeholders of the directories, e.g. "dir/"
+			if name == "" || strings.HasSuffix(name, "/") {
+				continue
+			}
+			size, _ := strconv.ParseInt(item.Size, 10, 64)
+			if _, ok := l.files[name]; !ok {
+				l.files[name] = &fileInfo{name: name, size: size, mode: 0644, modTime: item.Updated}
+			}
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		q.Set("pageToken", page.NextPageToken)
+	}
+	if len(l.files) == 0 && dir != "" && !fs.listedDir(dir) {
+		return nil, os.ErrNotExist
+	}
+	for name := range l.files {
+		l.names = append(l.names, name)
+	}
+	sort.Strings(l.names)
+	if fs.dirs == nil {
+		fs.dirs = make(map[string]*listing)
+	}
+	fs.dirs[dir] = l
+	return l, nil
+}
+
+// listedDir reports whether dir was listed as a directory by its parent,
+// which is how the empty directories are known.
+func (fs *FS) listedDir(dir string) bool {
+	l, ok := fs.dirs[parent(dir)]
+	if !ok {
+		return false
+	}
+	fi, ok := l.files[path.Base(dir)]
+	return ok && fi.IsDir()
+}
+
+// get sends a GET request, and fails on the non-2xx responses.
+func (fs *FS) get(u string) (*http.Response, error) {
+	client := fs.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, os.ErrNotExist
+		}
+		return nil, fmt.Errorf("gcs: %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	return resp, nil
+}
+
+func (fs *FS) endpoint() string {
+	if fs.Endpoint != "" {
+		return strings.TrimSuffix(fs.Endpoint, "/")
+	}
+	return DefaultEndpoint
+}
+
+// clean returns the object name of a path, "" for the root.
+func clean(name string) string {
+	return strings.Trim(path.Clean("/"+name), "/")
+}
+
+// parent returns the parent directory of an object name, "" for the root.
+func parent(name string) string {
+	if d := path.Dir(name); d != "." {
+		return d
+	}
+	return ""
+}
+
+// fileInfo is the FileInfo of an object or a directory.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() interface{}   { return nil }
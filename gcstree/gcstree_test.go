@@ -0,0 +1,116 @@
+package gcstree
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/a8m/tree"
+)
+
+// objects of the mock bucket, and their content
+var objects = map[string]string{
+	"README":            "hello",
+	"logs/":             "",
+	"logs/a.log":        "a log",
+	"logs/2020/b.log":   "b",
+	"logs/2020/c.log":   "c",
+	"src/main.go":       "package main",
+	"src/cmd/x/main.go": "package main",
+}
+
+// server serves the JSON API of the objects, with pages of 1 item.
+func server(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const base = "/b/bucket/o"
+		if r.URL.Path != base {
+			content, ok := objects[strings.TrimPrefix(r.URL.Path, base+"/")]
+			if !ok || r.URL.Query().Get("alt") != "media" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte(content))
+			return
+		}
+		prefix := r.URL.Query().Get("prefix")
+		var names []string
+		prefixes := make(map[string]bool)
+		for name := range objects {
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			if i := strings.IndexByte(name[len(prefix):], '/'); i >= 0 {
+				prefixes[name[:len(prefix)+i+1]] = true
+			} else {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		start, _ := strconv.Atoi(r.URL.Query().Get("pageToken"))
+		page := map[string]interface{}{}
+		if start == 0 {
+			var ps []string
+			for p := range prefixes {
+				ps = append(ps, p)
+			}
+			page["prefixes"] = ps
+		}
+		var items []map[string]string
+		for i := start; i < len(names) && i < start+1; i++ {
+			items = append(items, map[string]string{
+				"name": names[i], "size": strconv.Itoa(len(objects[names[i]])), "updated": "2020-01-02T03:04:05.000Z",
+			})
+		}
+		page["items"] = items
+		if start+1 < len(names) {
+			page["nextPageToken"] = strconv.Itoa(start + 1)
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+}
+
+func TestFS(t *testing.T) {
+	srv := server(t)
+	defer srv.Close()
+	fs := &FS{Bucket: "bucket", Endpoint: srv.URL}
+	b := new(bytes.Buffer)
+	opts := &tree.Options{Fs: fs, OutFile: b, ByteSize: true}
+	inf := tree.New(".")
+	if d, f := inf.Visit(opts); d != 5 || f != 6 {
+		t.Errorf("expect 5 dirs and 6 files, got %d and %d", d, f)
+	}
+	inf.Print(opts)
+	expected := `[         36]  .
+├── [          5]  README
+├── [          7]  logs
+│   ├── [          2]  2020
+│   │   ├── [          1]  b.log
+│   │   └── [          1]  c.log
+│   └── [          5]  a.log
+└── [         24]  src
+    ├── [         12]  cmd
+    │   └── [         12]  x
+    │       └── [         12]  main.go
+    └── [         12]  main.go
+`
+	if b.String() != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", b, expected)
+	}
+	r, err := fs.Open("logs/a.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if content, _ := ioutil.ReadAll(r); string(content) != "a log" {
+		t.Errorf("unexpected content %q", content)
+	}
+	if _, err := fs.Stat("nope/x"); err == nil {
+		t.Error("expect an error for a missing object")
+	}
+}
@@ -0,0 +1,93 @@
+package tree
+
+import (
+	"regexp"
+	"strings"
+)
+
+// globToRegexp converts a wildcard pattern to an anchored regular
+// expression. Besides '*', '?' and '[...]', it supports '**' that matches
+// any number of directories, '{a,b}' alternatives, and GNU tree's '|'
+// separated list of patterns.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^(")
+	braces := 0
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				// "**/" matches zero or more directories.
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					b.WriteString("(.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			j := i + 1
+			if j < len(glob) && (glob[j] == '!' || glob[j] == '^') {
+				j++
+			}
+			if j < len(glob) && glob[j] == ']' {
+				j++
+			}
+			for j < len(glob) && glob[j] != ']' {
+				j++
+			}
+			if j >= len(glob) {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : j]
+			if class[0] == '!' {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i = j
+		case '{':
+			braces++
+			b.WriteString("(")
+		case '}':
+			if braces > 0 {
+				braces--
+				b.WriteString(")")
+			} else {
+				b.WriteString(`\}`)
+			}
+		case ',':
+			if braces > 0 {
+				b.WriteString("|")
+			} else {
+				b.WriteByte(c)
+			}
+		case '|':
+			if braces > 0 {
+				b.WriteString(`\|`)
+			} else {
+				b.WriteString("|")
+			}
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			} else {
+				b.WriteString(`\\`)
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	for ; braces > 0; braces-- {
+		b.WriteString(")")
+	}
+	b.WriteString(")$")
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package tree
+
+import (
+	"regexp"
+	"testing"
+)
+
+var globTests = []struct {
+	glob    string
+	path    string
+	matched bool
+}{
+	{"*.go", "node.go", true},
+	{"*.go", "cmd/tree.go", false},
+	{"**/*.go", "node.go", true},
+	{"**/*.go", "cmd/tree/tree.go", true},
+	{"src/**/*_test.go", "src/a/b/node_test.go", true},
+	{"src/**/*_test.go", "src/node_test.go", true},
+	{"src/**/*_test.go", "lib/src/node_test.go", false},
+	{"src/**", "src/a/b", true},
+	{"?.txt", "a.txt", true},
+	{"?.txt", "ab.txt", false},
+	{"[abc].txt", "b.txt", true},
+	{"[!abc].txt", "b.txt", false},
+	{"[!abc].txt", "d.txt", true},
+	{"*.{go,md}", "README.md", true},
+	{"*.{go,md}", "a.txt", false},
+	{"*.go|*.md", "README.md", true},
+	{"a+b(c).txt", "a+b(c).txt", true},
+	{`\*.txt`, "*.txt", true},
+	{`\*.txt`, "a.txt", false},
+	{"[a", "[a", true},
+}
+
+func TestGlob(t *testing.T) {
+	for _, test := range globTests {
+		re, err := regexp.Compile(globToRegexp(test.glob))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.glob, err)
+			continue
+		}
+		if matched := re.MatchString(test.path); matched != test.matched {
+			t.Errorf("%s: match %q = %v, expected %v", test.glob, test.path, matched, test.matched)
+		}
+	}
+}
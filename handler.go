@@ -0,0 +1,97 @@
+package tree
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Handler returns an http.Handler that serves the tree of the requested
+// subpath of root, as JSON (see Document) if the request accepts
+// "application/json" or has a "format=json" query, and as an HTML list
+// of links otherwise. The given options are used for each request,
+// except for OutFile.
+func Handler(root string, opts *Options) http.Handler {
+	return &handler{root: root, opts: opts}
+}
+
+type handler struct {
+	root string
+	opts *Options
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upath := path.Clean("/" + r.URL.Path)
+	opts := *h.opts
+	opts.OutFile = ioutil.Discard
+	inf := New(filepath.Join(h.root, filepath.FromSlash(upath)))
+	d, f := inf.Visit(&opts)
+	if _, ok := inf.err.(*PatternError); ok {
+		http.Error(w, inf.err.Error(), http.StatusBadRequest)
+		return
+	}
+	if inf.err != nil {
+		http.Error(w, "404 page not found", http.StatusNotFound)
+		return
+	}
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		root := NewEntry(inf)
+		root.Name = upath
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&Document{
+			Version: SchemaVersion,
+			Tree:    []*Entry{root},
+			Report:  &Report{Directories: d, Files: f},
+		})
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	writeHTMLHead(w, upath, &opts)
+	fmt.Fprintf(w, "<h1>%s</h1>\n", html.EscapeString(upath))
+	writeHTMLList(w, inf, strings.TrimSuffix(upath, "/"), &opts)
+	fmt.Fprintf(w, "<p>%d directories, %d files</p>\n</body>\n</html>\n", d, f)
+}
+
+// writeHTMLHead writes the start of an HTML page, up to its body, with
+// the stylesheet of the colors if the Colorize option is set.
+func writeHTMLHead(w io.Writer, title string, opts *Options) {
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n", html.EscapeString(title))
+	if opts.Colorize {
+		fmt.Fprintf(w, "<style>\n%s</style>\n", HTMLStylesheet())
+	}
+	fmt.Fprint(w, "</head>\n<body>\n")
+}
+
+// writeHTMLList writes the children of node as a nested list of links,
+// relative to the given URL path. The names are colored with the
+// Colorize option.
+func writeHTMLList(w io.Writer, node *Node, upath string, opts *Options) {
+	if len(node.nodes) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "<ul>")
+	for _, nnode := range node.nodes {
+		if nnode.err != nil {
+			fmt.Fprintf(w, "<li>%s [%s]</li>\n", html.EscapeString(filepath.Base(nnode.path)), html.EscapeString(nnode.err.Error()))
+			continue
+		}
+		name, href := htmlName(nnode, nnode.Name(), opts), upath+"/"+url.PathEscape(nnode.Name())
+		if nnode.IsDir() {
+			name, href = name+"/", href+"/"
+		}
+		fmt.Fprintf(w, "<li><a href=\"%s\">%s</a>", html.EscapeString(href), name)
+		if len(nnode.nodes) > 0 {
+			fmt.Fprintln(w)
+			writeHTMLList(w, nnode, strings.TrimSuffix(href, "/"), opts)
+		}
+		fmt.Fprintln(w, "</li>")
+	}
+	fmt.Fprintln(w, "</ul>")
+}
@@ -0,0 +1,68 @@
+package tree
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a b", size: 1},
+			{name: "c", files: []*file{{name: "<d>", size: 2}}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	h := Handler("root", &Options{Fs: fs})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	expected := `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>/</title>
+</head>
+<body>
+<h1>/</h1>
+<ul>
+<li><a href="/a%20b">a b</a></li>
+<li><a href="/c/">c/</a>
+<ul>
+<li><a href="/c/%3Cd%3E">&lt;d&gt;</a></li>
+</ul>
+</li>
+</ul>
+<p>1 directories, 2 files</p>
+</body>
+</html>
+`
+	if actual := w.Body.String(); actual != expected {
+		t.Errorf("html:\ngot:\n%s\nexpected:\n%s", actual, expected)
+	}
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/c/../../c", nil)
+	r.Header.Set("Accept", "application/json")
+	h.ServeHTTP(w, r)
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("json: unexpected content type %q", ct)
+	}
+	var doc Document
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != SchemaVersion || len(doc.Tree) != 1 || doc.Tree[0].Name != "/c" ||
+		len(doc.Tree[0].Contents) != 1 || doc.Tree[0].Contents[0].Path != "root/c/<d>" {
+		t.Errorf("json: unexpected document %+v", doc)
+	}
+	if doc.Report == nil || doc.Report.Files != 1 {
+		t.Errorf("json: unexpected report %+v", doc.Report)
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("json: unexpected status %d", w.Code)
+	}
+}
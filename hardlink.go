@@ -0,0 +1,99 @@
+package tree
+
+import (
+	"fmt"
+	"io"
+)
+
+// HardlinkGroup is the paths of a tree sharing the same file, i.e. the
+// same inode of the same device.
+type HardlinkGroup struct {
+	Device, Inode uint64
+	// Links is the number of hard links to the file, including the ones
+	// out of the tree.
+	Links uint64
+	Size  int64
+	// Paths are the listed links, in display order.
+	Paths []string
+}
+
+// Saved returns the space the listed links save, compared to copies.
+func (g *HardlinkGroup) Saved() int64 {
+	return g.Size * int64(len(g.Paths)-1)
+}
+
+// HardlinkGroups returns the regular files of a visited tree that have
+// several hard links, grouped by inode in display order.
+func (node *Node) HardlinkGroups() []HardlinkGroup {
+	return appendHardlinks(nil, node.hardlinks(nil))
+}
+
+// hardlinks appends the groups of one link of each of the files with
+// several hard links below the node.
+func (node *Node) hardlinks(groups []HardlinkGroup) []HardlinkGroup {
+	if node.FileInfo != nil && node.err == nil && !node.archived && node.info().Mode().IsRegular() {
+		fi := node.info()
+		nlink, ok := getLinks(fi)
+		if ok && nlink > 1 {
+			_, inode, device, _, _ := getStat(fi)
+			groups = append(groups, HardlinkGroup{device, inode, nlink, fi.Size(), []string{node.path}})
+		}
+	}
+	for _, nnode := range node.nodes {
+		groups = nnode.hardlinks(groups)
+	}
+	return groups
+}
+
+// appendHardlinks merges the given groups into groups, by inode.
+func appendHardlinks(groups, more []HardlinkGroup) []HardlinkGroup {
+	type key struct{ device, inode uint64 }
+	index := make(map[key]int)
+	for i, g := range groups {
+		index[key{g.Device, g.Inode}] = i
+	}
+	for _, g := range more {
+		k := key{g.Device, g.Inode}
+		if i, ok := index[k]; ok {
+			groups[i].Paths = append(groups[i].Paths, g.Paths...)
+			continue
+		}
+		index[k] = len(groups)
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// FprintHardlinks writes a report of the given groups, e.g:
+//
+//	2 hardlinked files, 4 paths, 3.0M saved
+//	  inode 1234 (2 links, 1024K)
+//	    root/bin/gzip
+//	    root/bin/gunzip
+//	  inode 5678 (3 links, 2 listed, 2.0M)
+//	    root/lib/a
+//	    root/backup/a
+func FprintHardlinks(w io.Writer, groups []HardlinkGroup) {
+	if len(groups) == 0 {
+		fmt.Fprintln(w, "no hardlinked files")
+		return
+	}
+	var paths int
+	var saved int64
+	for i := range groups {
+		paths += len(groups[i].Paths)
+		saved += groups[i].Saved()
+	}
+	fmt.Fprintf(w, "%s, %s, %s saved\n", plural(len(groups), "hardlinked file", "hardlinked files"),
+		plural(paths, "path", "paths"), formatBytes(saved))
+	for _, g := range groups {
+		links := plural(int(g.Links), "link", "links")
+		if int(g.Links) != len(g.Paths) {
+			links += fmt.Sprintf(", %d listed", len(g.Paths))
+		}
+		fmt.Fprintf(w, "  inode %d (%s, %s)\n", g.Inode, links, formatBytes(g.Size))
+		for _, p := range g.Paths {
+			fmt.Fprintf(w, "    %s\n", p)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package tree
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestHardlinks(t *testing.T) {
+	gzip := &syscall.Stat_t{Ino: 1234, Nlink: 2}
+	lib := &syscall.Stat_t{Ino: 5678, Nlink: 3}
+	root := &file{name: "root", mode: os.ModeDir | 0755, files: []*file{
+		{name: "backup", mode: os.ModeDir | 0755, files: []*file{
+			{name: "a", size: 2 << 20, stat: lib},
+		}},
+		{name: "bin", mode: os.ModeDir | 0755, files: []*file{
+			{name: "gunzip", size: 1 << 20, stat: gzip},
+			{name: "gzip", size: 1 << 20, stat: gzip},
+			{name: "ls", size: 100, stat: &syscall.Stat_t{Ino: 1, Nlink: 1}},
+		}},
+		{name: "lib", mode: os.ModeDir | 0755, files: []*file{
+			{name: "a", size: 2 << 20, stat: lib},
+		}},
+	}}
+	fs.clean().addFile(root.name, root)
+	inf := New("root")
+	inf.Visit(&Options{Fs: fs, OutFile: out, Hardlinks: true})
+	b := new(bytes.Buffer)
+	FprintHardlinks(b, inf.HardlinkGroups())
+	expected := `2 hardlinked files, 4 paths, 3.0M saved
+  inode 5678 (3 links, 2 listed, 2.0M)
+    root/backup/a
+    root/lib/a
+  inode 1234 (2 links, 1024K)
+    root/bin/gunzip
+    root/bin/gzip
+`
+	if b.String() != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", b, expected)
+	}
+}
@@ -0,0 +1,139 @@
+package tree
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// checksums are the hash functions of the Checksum option.
+var checksums = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+// checksumKey is the Meta key of the checksums.
+const checksumKey = "checksum"
+
+// largeHashFile is the size from which files are large for the hashing
+// scheduler: at most half of the workers read large files at once, so the
+// small ones keep flowing instead of queueing behind a few huge reads.
+const largeHashFile = 64 << 20
+
+// hashFiles computes the checksums of the regular files of the visited
+// tree of root with a pool of HashWorkers workers, and attaches them to
+// the nodes. The largest files are scheduled first, so the pool doesn't
+// end up waiting on a single worker reading a big file last.
+func (opts *Options) hashFiles(root *Node) {
+	newHash, ok := checksums[strings.ToLower(opts.Checksum)]
+	if !ok {
+		return
+	}
+	fo, ok := opts.Fs.(FileOpener)
+	if !ok {
+		return
+	}
+	files := root.Flatten().Filter(func(n *Node) bool {
+		return n.FileInfo != nil && n.err == nil && !n.IsDir() && !n.archived && n.info().Mode().IsRegular()
+	})
+	sort.SliceStable(files, func(i, j int) bool { return files[i].info().Size() > files[j].info().Size() })
+	workers := opts.HashWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	large := make(chan struct{}, (workers+1)/2)
+	work := make(chan *Node)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for node := range work {
+				if node.info().Size() >= largeHashFile {
+					large <- struct{}{}
+					opts.hashFile(fo, node, newHash())
+					<-large
+				} else {
+					opts.hashFile(fo, node, newHash())
+				}
+			}
+		}()
+	}
+	for _, node := range files {
+		work <- node
+	}
+	close(work)
+	wg.Wait()
+	if opts.Merkle {
+		hashDir(root, newHash)
+	}
+}
+
+// hashDir computes the Merkle checksum of a visited directory, from the
+// ones of its children, and returns it.
+func hashDir(node *Node, newHash func() hash.Hash) string {
+	nodes := make([]*Node, len(node.nodes))
+	copy(nodes, node.nodes)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name() < nodes[j].Name() })
+	h := newHash()
+	for _, nnode := range nodes {
+		if nnode.err != nil || nnode.FileInfo == nil {
+			continue
+		}
+		kind, sum := "f", nnode.Checksum()
+		switch {
+		case nnode.isDir():
+			kind, sum = "d", hashDir(nnode, newHash)
+		case nnode.Mode()&os.ModeSymlink != 0:
+			kind, sum = "l", nnode.link
+		}
+		// NUL separated, as it can't appear in names
+		io.WriteString(h, kind+"\x00"+nnode.Name()+"\x00"+sum+"\x00")
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	node.SetMeta(checksumKey, sum)
+	return sum
+}
+
+// Checksum returns the checksum of a file computed with the Checksum
+// option, or the one of a directory with the Merkle option, or "" if it
+// wasn't computed.
+func (node *Node) Checksum() string {
+	sum, _ := node.Meta(checksumKey).(string)
+	return sum
+}
+
+// hashFile computes the checksum of a file with h.
+func (opts *Options) hashFile(fo FileOpener, node *Node, h hash.Hash) {
+	r, err := fo.Open(node.path)
+	if err != nil {
+		opts.warn("cannot hash file", node.path, err)
+		node.hashErr = err
+		return
+	}
+	defer r.Close()
+	if _, err := io.Copy(h, r); err != nil {
+		opts.warn("cannot hash file", node.path, err)
+		node.hashErr = err
+		return
+	}
+	node.SetMeta(checksumKey, hex.EncodeToString(h.Sum(nil)))
+}
+
+// checksumColumn returns the checksum column of a node, blank for the
+// directories without the Merkle option and the files that couldn't be
+// hashed.
+func (opts *Options) checksumColumn(node *Node) string {
+	sum, _ := node.Meta(checksumKey).(string)
+	width := 2 * checksums[strings.ToLower(opts.Checksum)]().Size()
+	return sum + strings.Repeat(" ", width-len(sum))
+}
@@ -0,0 +1,37 @@
+package tree
+
+import (
+	"bufio"
+	"path/filepath"
+	"strings"
+)
+
+// hiddenName is the name of the files read by the HiddenFiles option.
+const hiddenName = ".hidden"
+
+// readHidden returns the names listed in the .hidden file of a directory,
+// or nil if the Fs can't open files.
+func (opts *Options) readHidden(dir string) map[string]bool {
+	fo, ok := opts.Fs.(FileOpener)
+	if !ok {
+		return nil
+	}
+	path := filepath.Join(dir, hiddenName)
+	r, err := fo.Open(path)
+	if err != nil {
+		opts.warn("cannot read hidden file", path, err)
+		return nil
+	}
+	defer r.Close()
+	names := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if name := strings.TrimRight(scanner.Text(), "\r"); name != "" {
+			names[strings.TrimSuffix(name, "/")] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		opts.warn("cannot read hidden file", path, err)
+	}
+	return names
+}
@@ -0,0 +1,11 @@
+//+build !windows
+
+package tree
+
+import "os"
+
+// hiddenAttr reports whether fi is hidden by an attribute, which only
+// Windows has.
+func hiddenAttr(fi os.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,15 @@
+//+build windows
+
+package tree
+
+import (
+	"os"
+	"syscall"
+)
+
+// hiddenAttr reports whether fi has the hidden or the system attribute,
+// the entries that explorer and dir don't show by default.
+func hiddenAttr(fi os.FileInfo) bool {
+	attrs, ok := fi.Sys().(*syscall.Win32FileAttributeData)
+	return ok && attrs.FileAttributes&(syscall.FILE_ATTRIBUTE_HIDDEN|syscall.FILE_ATTRIBUTE_SYSTEM) != 0
+}
@@ -0,0 +1,88 @@
+package tree
+
+import (
+	"bytes"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// execKey is the Meta key of the Exec annotations.
+const execKey = "exec"
+
+// execFiles runs the Exec option on the regular files of the visited
+// tree of root, with at most ExecWorkers concurrent calls, and attaches
+// the annotations to the nodes.
+func (opts *Options) execFiles(root *Node) {
+	if opts.Exec == nil {
+		return
+	}
+	files := root.Flatten().Filter(func(n *Node) bool {
+		return n.FileInfo != nil && n.err == nil && !n.IsDir() && !n.archived && n.info().Mode().IsRegular()
+	})
+	workers := opts.ExecWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	work := make(chan *Node)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for node := range work {
+				annotation, err := opts.Exec(node)
+				if err != nil {
+					opts.warn("exec failed", node.path, err)
+					annotation = "exec: " + err.Error()
+				}
+				if annotation != "" {
+					node.SetMeta(execKey, annotation)
+				}
+			}
+		}()
+	}
+	for _, node := range files {
+		work <- node
+	}
+	close(work)
+	wg.Wait()
+}
+
+// Annotation returns the annotation of the Exec option of a file, or "".
+func (node *Node) Annotation() string {
+	s, _ := node.Meta(execKey).(string)
+	return s
+}
+
+// ExecCommand returns an Exec option running the given command on each
+// file, with its path in place of the "{}" arguments like find -exec, or
+// appended if there are none. The annotation is its trimmed standard
+// output on a single line. As scanners and linters report their findings
+// with a failure status, it's an error only if the command couldn't run
+// or failed silently.
+func ExecCommand(name string, args ...string) func(*Node) (string, error) {
+	return func(node *Node) (string, error) {
+		cargs := make([]string, 0, len(args)+1)
+		var replaced bool
+		for _, arg := range args {
+			if strings.Contains(arg, "{}") {
+				arg, replaced = strings.Replace(arg, "{}", node.path, -1), true
+			}
+			cargs = append(cargs, arg)
+		}
+		if !replaced {
+			cargs = append(cargs, node.path)
+		}
+		var stdout bytes.Buffer
+		cmd := exec.Command(name, cargs...)
+		cmd.Stdout = &stdout
+		err := cmd.Run()
+		out := strings.Join(strings.Fields(stdout.String()), " ")
+		if _, ok := err.(*exec.ExitError); ok && out != "" {
+			err = nil
+		}
+		return out, err
+	}
+}
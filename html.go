@@ -0,0 +1,34 @@
+package tree
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// printHTML prints the visited node as an HTML page, like GNU tree -H:
+// the root and each entry below it are links relative to the base HREF of
+// the HTML option, and the directories are nested lists. The names are
+// colored with the Colorize option, see HTMLColor.
+func (node *Node) printHTML(opts *Options) {
+	w := opts.OutFile
+	base := strings.TrimSuffix(opts.HTML, "/")
+	writeHTMLHead(w, node.path, opts)
+	fmt.Fprintf(w, "<h1><a href=\"%s\">%s</a></h1>\n", html.EscapeString(base+"/"), htmlName(node, node.path, opts))
+	writeHTMLList(w, node, base, opts)
+	dirs, files := node.counts()
+	report := plural(dirs, "directory", "directories")
+	if !opts.DirsOnly {
+		report += ", " + plural(files, "file", "files")
+	}
+	fmt.Fprintf(w, "<p>%s</p>\n</body>\n</html>\n", report)
+}
+
+// htmlName returns the HTML of the name of a node, colored with the
+// Colorize option.
+func htmlName(node *Node, name string, opts *Options) string {
+	if !opts.Colorize || node.FileInfo == nil {
+		return html.EscapeString(name)
+	}
+	return ANSIToHTML(opts.colorize(node, name))
+}
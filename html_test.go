@@ -0,0 +1,60 @@
+package tree
+
+import "testing"
+
+func TestHTML(t *testing.T) {
+	root := &file{name: "root", files: []*file{
+		{name: "a b"},
+		{name: "c", files: []*file{{name: "<d>"}}},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"html", &Options{Fs: fs, OutFile: out, HTML: "https://example.com/files/"}, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>root</title>
+</head>
+<body>
+<h1><a href="https://example.com/files/">root</a></h1>
+<ul>
+<li><a href="https://example.com/files/a%20b">a b</a></li>
+<li><a href="https://example.com/files/c/">c/</a>
+<ul>
+<li><a href="https://example.com/files/c/%3Cd%3E">&lt;d&gt;</a></li>
+</ul>
+</li>
+</ul>
+<p>1 directory, 2 files</p>
+</body>
+</html>
+`, 1, 2},
+		{"html-dirs", &Options{Fs: fs, OutFile: out, HTML: ".", DirsOnly: true}, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>root</title>
+</head>
+<body>
+<h1><a href="./">root</a></h1>
+<ul>
+<li><a href="./c/">c/</a></li>
+</ul>
+<p>1 directory</p>
+</body>
+</html>
+`, 1, 0}})
+}
+
+func TestHTMLColor(t *testing.T) {
+	root := &file{name: "root", files: []*file{{name: "a.zip"}}}
+	fs.clean().addFile(root.name, root)
+	inf := New(root.name)
+	inf.Visit(&Options{Fs: fs, OutFile: out})
+	if s := HTMLColor(inf, "root"); s != `<span class="sgr-1 sgr-34">root</span>` {
+		t.Errorf("HTMLColor: got %q", s)
+	}
+	if s := HTMLColor(inf.nodes[0], "a<b>.zip"); s != `<span class="sgr-1 sgr-31">a&lt;b&gt;.zip</span>` {
+		t.Errorf("HTMLColor: got %q", s)
+	}
+}
@@ -0,0 +1,191 @@
+package tree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IconSet maps the entries to the icons printed before their names, see
+// the Icons option. The maps can be extended, or copied to build new sets.
+type IconSet struct {
+	// Dir, File, Link, Exec and Other are the icons of the directories,
+	// regular files, symbolic links, executables, and of the other types.
+	Dir, File, Link, Exec, Other string
+	// Names are the icons of regular files by name, e.g. "Makefile", and
+	// Exts by lowercase extension, e.g. ".go". Names take precedence.
+	Names map[string]string
+	Exts  map[string]string
+}
+
+// Icon returns the icon of the node.
+func (s *IconSet) Icon(node *Node) string {
+	mode := node.Mode()
+	switch {
+	case node.IsDir() || mode&os.ModeDir != 0:
+		return s.Dir
+	case mode&os.ModeSymlink != 0:
+		return s.Link
+	case !mode.IsRegular():
+		return s.Other
+	}
+	if icon, ok := s.Names[node.Name()]; ok {
+		return icon
+	}
+	if icon, ok := s.Exts[strings.ToLower(filepath.Ext(node.Name()))]; ok {
+		return icon
+	}
+	if isExecutable(node) {
+		return s.Exec
+	}
+	return s.File
+}
+
+// EmojiIcons is the default IconSet, made of emoji.
+var EmojiIcons = &IconSet{
+	Dir:   "📁",
+	File:  "📄",
+	Link:  "🔗",
+	Exec:  "⚡",
+	Other: "🔌",
+	Names: map[string]string{
+		"Dockerfile": "🐳",
+		"Makefile":   "🔨",
+		"LICENSE":    "📜",
+	},
+	Exts: iconExts(map[string][]string{
+		"🐹":  {".go"},
+		"🐍":  {".py"},
+		"🦀":  {".rs"},
+		"💎":  {".rb"},
+		"☕":  {".java", ".jar"},
+		"📜":  {".js", ".ts", ".jsx", ".tsx"},
+		"🐚":  {".sh", ".bash", ".zsh", ".fish"},
+		"🌐":  {".html", ".htm", ".css"},
+		"📝":  {".md", ".txt", ".rst"},
+		"📕":  {".pdf"},
+		"🔧":  {".json", ".yaml", ".yml", ".toml", ".ini", ".conf", ".xml"},
+		"🖼️": {".png", ".jpg", ".jpeg", ".gif", ".bmp", ".svg", ".webp", ".ico", ".tif", ".tiff"},
+		"🎵":  {".mp3", ".wav", ".flac", ".ogg", ".m4a"},
+		"🎬":  {".mp4", ".mkv", ".avi", ".mov", ".webm", ".wmv"},
+		"📦":  {".zip", ".tar", ".gz", ".tgz", ".bz2", ".xz", ".7z", ".rar", ".zst", ".deb", ".rpm"},
+		"🔒":  {".pem", ".key", ".crt", ".gpg"},
+	}),
+}
+
+// iconExts inverts a table of icons to their extensions.
+func iconExts(icons map[string][]string) map[string]string {
+	exts := make(map[string]string)
+	for icon, list := range icons {
+		for _, ext := range list {
+			exts[ext] = icon
+		}
+	}
+	return exts
+}
+
+// icon returns the icon of the node, see the Icons option.
+func (opts *Options) icon(node *Node) string {
+	if opts.Icon != nil {
+		return opts.Icon(node)
+	}
+	return EmojiIcons.Icon(node)
+}
+
+// NerdIcons is an IconSet of Nerd Font glyphs, like lsd or eza, for the
+// terminals using a patched font.
+var NerdIcons = &IconSet{
+	Dir:   "\uf115",
+	File:  "\uf15b",
+	Link:  "\uf0c1",
+	Exec:  "\uf489",
+	Other: "\uf1e6",
+	Names: map[string]string{
+		"Dockerfile": "\uf308",
+		"Makefile":   "\ue779",
+		"LICENSE":    "\uf02d",
+		".gitignore": "\uf1d3",
+	},
+	Exts: iconExts(map[string][]string{
+		"\ue627": {".go"},
+		"\ue606": {".py"},
+		"\ue7a8": {".rs"},
+		"\ue21e": {".rb"},
+		"\ue256": {".java", ".jar"},
+		"\ue74e": {".js", ".jsx"},
+		"\ue628": {".ts", ".tsx"},
+		"\ue61e": {".c", ".h"},
+		"\ue61d": {".cpp", ".cc", ".hpp"},
+		"\uf489": {".sh", ".bash", ".zsh", ".fish"},
+		"\uf13b": {".html", ".htm"},
+		"\ue749": {".css"},
+		"\uf48a": {".md", ".rst"},
+		"\uf15c": {".txt"},
+		"\uf1c1": {".pdf"},
+		"\ue60b": {".json"},
+		"\ue615": {".yaml", ".yml", ".toml", ".ini", ".conf", ".xml"},
+		"\uf1c5": {".png", ".jpg", ".jpeg", ".gif", ".bmp", ".svg", ".webp", ".ico", ".tif", ".tiff"},
+		"\uf1c7": {".mp3", ".wav", ".flac", ".ogg", ".m4a"},
+		"\uf03d": {".mp4", ".mkv", ".avi", ".mov", ".webm", ".wmv"},
+		"\uf410": {".zip", ".tar", ".gz", ".tgz", ".bz2", ".xz", ".7z", ".rar", ".zst", ".deb", ".rpm"},
+		"\uf084": {".pem", ".key", ".crt", ".gpg"},
+	}),
+}
+
+// IconSets are the IconSets selectable by name, e.g. with the --icon-set
+// flag.
+var IconSets = map[string]*IconSet{
+	"emoji": EmojiIcons,
+	"nerd":  NerdIcons,
+}
+
+// Copy returns a copy of the set, whose maps can be changed without
+// altering the set.
+func (s *IconSet) Copy() *IconSet {
+	c := *s
+	c.Names, c.Exts = make(map[string]string), make(map[string]string)
+	for k, v := range s.Names {
+		c.Names[k] = v
+	}
+	for k, v := range s.Exts {
+		c.Exts[k] = v
+	}
+	return &c
+}
+
+// ParseIcons returns a copy of base extended with the given LS_COLORS like
+// specification, e.g. "di=D:*.go=G:Makefile=M". The keys are di, fi, ln,
+// ex and ot (the other types), '*' extensions, and file names otherwise.
+func ParseIcons(base *IconSet, spec string) (*IconSet, error) {
+	s := base.Copy()
+	for _, entry := range strings.Split(spec, ":") {
+		if entry == "" {
+			continue
+		}
+		i := strings.IndexByte(entry, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid icons entry '%s'", entry)
+		}
+		key, icon := entry[:i], entry[i+1:]
+		switch key {
+		case "di":
+			s.Dir = icon
+		case "fi":
+			s.File = icon
+		case "ln":
+			s.Link = icon
+		case "ex":
+			s.Exec = icon
+		case "ot":
+			s.Other = icon
+		default:
+			if key[0] == '*' {
+				s.Exts[strings.ToLower(key[1:])] = icon
+			} else {
+				s.Names[key] = icon
+			}
+		}
+	}
+	return s, nil
+}
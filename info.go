@@ -0,0 +1,146 @@
+package tree
+
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileOpener is implemented by the Fs that can read the content of files,
+// e.g. for the Info option.
+type FileOpener interface {
+	Open(path string) (io.ReadCloser, error)
+}
+
+// infoName is the name of the files read by the Info option.
+const infoName = ".info"
+
+// infoFile are the comments of a .info file, like GNU tree's: lines
+// starting at column 0 are wildcard patterns, matching the names below the
+// directory of the file or, if they contain a '/', the paths relative to
+// it. The tab-indented lines that follow them are the comment of the
+// entries they match. Lines starting with '#' are ignored, e.g.:
+//
+//	*.go
+//	cmd/*
+//		Go sources.
+type infoFile struct {
+	dir   string
+	rules []infoRule
+}
+
+type infoRule struct {
+	patterns []infoPattern
+	comment  []string
+}
+
+// infoPattern matches the base names, or the relative paths if the
+// pattern contains a '/'.
+type infoPattern struct {
+	re     *regexp.Regexp
+	byPath bool
+}
+
+// readInfo reads the .info file of the directory, or returns nil if it
+// couldn't be read.
+func (opts *Options) readInfo(dir string) *infoFile {
+	fo, ok := opts.Fs.(FileOpener)
+	if !ok {
+		return nil
+	}
+	path := filepath.Join(dir, infoName)
+	r, err := fo.Open(path)
+	if err != nil {
+		opts.warn("cannot read info file", path, err)
+		return nil
+	}
+	defer r.Close()
+	info := &infoFile{dir: dir}
+	var rule *infoRule
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case line == "" || line[0] == '#':
+		case line[0] == '\t':
+			if rule != nil {
+				rule.comment = append(rule.comment, strings.TrimSpace(line))
+			}
+		default:
+			if rule == nil || len(rule.comment) > 0 {
+				info.rules = append(info.rules, infoRule{})
+				rule = &info.rules[len(info.rules)-1]
+			}
+			line = strings.TrimSuffix(line, "/")
+			re, err := regexp.Compile(globToRegexp(line))
+			if err != nil {
+				opts.warn("invalid info pattern", path, err)
+				continue
+			}
+			rule.patterns = append(rule.patterns, infoPattern{re, strings.Contains(line, "/")})
+		}
+	}
+	return info
+}
+
+// comment returns the comment of the given path, or nil if no pattern
+// matches it.
+func (info *infoFile) comment(path string) []string {
+	rel, err := filepath.Rel(info.dir, path)
+	if err != nil {
+		return nil
+	}
+	rel = filepath.ToSlash(rel)
+	for _, rule := range info.rules {
+		for _, p := range rule.patterns {
+			if p.byPath && p.re.MatchString(rel) || !p.byPath && p.re.MatchString(filepath.Base(path)) {
+				return rule.comment
+			}
+		}
+	}
+	return nil
+}
+
+// infoComment returns the comment of the node in the .info files of its
+// ancestors, the closest one taking precedence.
+func (node *Node) infoComment() []string {
+	for _, info := range node.infos {
+		if c := info.comment(node.path); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// infoBraces are the brackets of the comments printed by the Info
+// option: for a single line, and for the first, middle and last lines.
+var infoBraces = map[string][4]string{
+	"utf-8": {"{ ", "⎧ ", "⎪ ", "⎩ "},
+	"ascii": {"{ ", "/ ", "| ", "\\ "},
+}
+
+// printComment prints the .info comment of the node, below its line and
+// above its children.
+func (node *Node) printComment(indent string, opts *Options) {
+	braces, ok := infoBraces[strings.ToLower(opts.Charset)]
+	if !ok {
+		braces = infoBraces["utf-8"]
+	}
+	if opts.NoIndent {
+		indent = ""
+	}
+	for i, line := range node.comment {
+		brace := braces[2]
+		switch {
+		case len(node.comment) == 1:
+			brace = braces[0]
+		case i == 0:
+			brace = braces[1]
+		case i == len(node.comment)-1:
+			brace = braces[3]
+		}
+		io.WriteString(opts.OutFile, indent+brace+line+"\n")
+	}
+}
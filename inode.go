@@ -0,0 +1,37 @@
+package tree
+
+import "sort"
+
+// InodeReader is implemented by the Fs that can list the inode numbers
+// of the entries of a directory without stat'ing them, see the
+// InodeOrder option.
+type InodeReader interface {
+	ReadDirInodes(path string) (names []string, inodes []uint64, err error)
+}
+
+// listDir reads the directory at path, in inode order if the InodeOrder
+// option is set and the Fs supports it.
+func (opts *Options) listDir(path string) ([]string, error) {
+	ir, ok := opts.Fs.(InodeReader)
+	if !opts.InodeOrder || !ok {
+		return opts.Fs.ReadDir(path)
+	}
+	names, inodes, err := ir.ReadDirInodes(path)
+	if err != nil {
+		return nil, err
+	}
+	sort.Stable(byInode{names, inodes})
+	return names, nil
+}
+
+type byInode struct {
+	names  []string
+	inodes []uint64
+}
+
+func (b byInode) Len() int           { return len(b.names) }
+func (b byInode) Less(i, j int) bool { return b.inodes[i] < b.inodes[j] }
+func (b byInode) Swap(i, j int) {
+	b.names[i], b.names[j] = b.names[j], b.names[i]
+	b.inodes[i], b.inodes[j] = b.inodes[j], b.inodes[i]
+}
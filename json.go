@@ -0,0 +1,119 @@
+package tree
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// jsonEntry is a visited node in the output of the JSON option, in the
+// format of GNU tree -J.
+type jsonEntry struct {
+	Type     string       `json:"type"`
+	Name     string       `json:"name"`
+	Target   string       `json:"target,omitempty"`
+	Mode     string       `json:"mode,omitempty"`
+	Perm     string       `json:"prot,omitempty"`
+	Size     int64        `json:"size"`
+	Time     string       `json:"time,omitempty"`
+	Error    string       `json:"error,omitempty"`
+	Contents []*jsonEntry `json:"-"`
+}
+
+// jsonReport is the last value of the output of the JSON option.
+type jsonReport struct {
+	Type        string `json:"type"`
+	Directories int    `json:"directories"`
+	Files       *int   `json:"files,omitempty"`
+}
+
+// printJSON prints the visited roots as a JSON array, in the format of
+// GNU tree -J: the nested objects of the entries, with the contents of
+// the directories, followed by a report of the counts. The mode is in
+// octal, and the time in the layout of the TimeFormat option.
+func printJSON(opts *Options, roots []*Node) {
+	w := opts.OutFile
+	report := &jsonReport{Type: "report"}
+	var files int
+	fmt.Fprintln(w, "[")
+	for _, root := range roots {
+		d, f := root.counts()
+		report.Directories, files = report.Directories+d, files+f
+		writeJSONEntry(w, root.jsonEntry(opts), "  ")
+		fmt.Fprintln(w, ",")
+	}
+	if !opts.DirsOnly {
+		report.Files = &files
+	}
+	b, _ := json.Marshal(report)
+	fmt.Fprintf(w, "  %s\n]\n", b)
+}
+
+// writeJSONEntry writes an entry on its own line, and its contents on the
+// next ones, one more level indented.
+func writeJSONEntry(w io.Writer, e *jsonEntry, indent string) {
+	b, _ := json.Marshal(e)
+	if len(e.Contents) == 0 {
+		fmt.Fprintf(w, "%s%s", indent, b)
+		return
+	}
+	fmt.Fprintf(w, "%s%s,\"contents\":[\n", indent, b[:len(b)-1])
+	for i, c := range e.Contents {
+		writeJSONEntry(w, c, indent+"  ")
+		if i < len(e.Contents)-1 {
+			fmt.Fprint(w, ",")
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "%s]}", indent)
+}
+
+// jsonEntry returns the entry of a visited node and its children.
+func (node *Node) jsonEntry(opts *Options) *jsonEntry {
+	e := &jsonEntry{Name: filepath.Base(node.path)}
+	if node.depth == 0 {
+		e.Name = node.path
+	}
+	if node.err != nil {
+		e.Error = errReason(node.err)
+	}
+	if node.FileInfo == nil {
+		e.Type = "other"
+		return e
+	}
+	fi := node.info()
+	e.Type = typeNames[fileType(node.Mode())]
+	e.Size = fi.Size()
+	if node.IsDir() {
+		e.Type = "directory"
+		e.Size, _ = node.dirSize()
+	}
+	if node.Mode()&os.ModeSymlink != 0 {
+		e.Target = node.link
+	}
+	e.Mode, e.Perm = fmt.Sprintf("%04o", mtreePerm(fi.Mode())), fi.Mode().String()
+	e.Time = fi.ModTime().Format(opts.timeFormat())
+	for _, nnode := range node.nodes {
+		e.Contents = append(e.Contents, nnode.jsonEntry(opts))
+	}
+	return e
+}
+
+// counts returns the number of directories and files listed below the
+// visited node.
+func (node *Node) counts() (dirs, files int) {
+	for _, nnode := range node.nodes {
+		d, f := nnode.counts()
+		switch {
+		case nnode.FileInfo == nil:
+		case nnode.isDir():
+			d++
+		default:
+			f++
+		}
+		dirs, files = dirs+d, files+f
+	}
+	return
+}
@@ -0,0 +1,59 @@
+package tree
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSON(t *testing.T) {
+	mtime := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	root := &file{name: "root", lastMod: mtime, files: []*file{
+		{name: "a", size: 8, lastMod: mtime, mode: 0644},
+		{name: "b", lastMod: mtime, files: []*file{{name: "c", size: 2, lastMod: mtime, mode: 0600}}},
+		{name: "d", lastMod: mtime, files: []*file{}},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"json", &Options{Fs: fs, OutFile: out, JSON: true}, `[
+  {"type":"directory","name":"root","mode":"0000","prot":"----------","size":10,"time":"May 01 12:00","contents":[
+    {"type":"file","name":"a","mode":"0644","prot":"-rw-r--r--","size":8,"time":"May 01 12:00"},
+    {"type":"directory","name":"b","mode":"0000","prot":"----------","size":2,"time":"May 01 12:00","contents":[
+      {"type":"file","name":"c","mode":"0600","prot":"-rw-------","size":2,"time":"May 01 12:00"}
+    ]},
+    {"type":"directory","name":"d","mode":"0000","prot":"----------","size":0,"time":"May 01 12:00"}
+  ]},
+  {"type":"report","directories":2,"files":2}
+]
+`, 2, 2},
+		{"json-dirs", &Options{Fs: fs, OutFile: out, JSON: true, DirsOnly: true, TimeFormat: "2006"}, `[
+  {"type":"directory","name":"root","mode":"0000","prot":"----------","size":0,"time":"2020","contents":[
+    {"type":"directory","name":"b","mode":"0000","prot":"----------","size":0,"time":"2020"},
+    {"type":"directory","name":"d","mode":"0000","prot":"----------","size":0,"time":"2020"}
+  ]},
+  {"type":"report","directories":2}
+]
+`, 2, 0}})
+}
+
+func TestRunJSON(t *testing.T) {
+	defer out.clear()
+	fs.clean().addFile("a", &file{name: "a", files: []*file{{name: "x"}}})
+	fs.addFile("b", &file{name: "b", files: []*file{{name: "y", files: []*file{}}}})
+	Run([]string{"a", "b"}, &Options{Fs: fs, OutFile: out, JSON: true})
+	var values []map[string]interface{}
+	if err := json.Unmarshal([]byte(out.str), &values); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.str)
+	}
+	if len(values) != 3 || values[0]["name"] != "a" || values[1]["name"] != "b" {
+		t.Fatalf("expected both roots in a single array, got:\n%s", out.str)
+	}
+	if r := values[2]; r["type"] != "report" || r["directories"] != 1.0 || r["files"] != 1.0 {
+		t.Errorf("unexpected report: %v", r)
+	}
+	// It's not a snapshot of the Entry schema
+	if _, err := ReadSnapshot(strings.NewReader(out.str)); err == nil {
+		t.Error("expected ReadSnapshot to reject the JSON output")
+	}
+}
@@ -0,0 +1,51 @@
+package tree
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// LinkReader is implemented by the Fs that can read symbolic links, e.g.
+// the remote ones. The links of the other Fs are read from the OS.
+type LinkReader interface {
+	Readlink(path string) (string, error)
+}
+
+// maxLinks is the maximum number of links resolved by evalSymlinks.
+const maxLinks = 255
+
+// readlink returns the target of a symbolic link.
+func (opts *Options) readlink(path string) (string, error) {
+	if lr, ok := opts.Fs.(LinkReader); ok {
+		return lr.Readlink(path)
+	}
+	return os.Readlink(path)
+}
+
+// evalSymlinks returns the path a symbolic link resolves to, like
+// filepath.EvalSymlinks but with the Fs if it implements LinkReader.
+func (opts *Options) evalSymlinks(path string) (string, error) {
+	lr, ok := opts.Fs.(LinkReader)
+	if !ok {
+		return filepath.EvalSymlinks(path)
+	}
+	for i := 0; i < maxLinks; i++ {
+		fi, err := opts.Fs.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		if fi.Mode()&os.ModeSymlink == 0 {
+			return path, nil
+		}
+		link, err := lr.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(path), link)
+		}
+		path = link
+	}
+	return "", &os.PathError{Op: "eval", Path: path, Err: errors.New("too many links")}
+}
@@ -0,0 +1,105 @@
+package tree
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// linkGraphFormats are the formats of the LinkGraph option.
+var linkGraphFormats = map[string]bool{"dot": true, "json": true}
+
+// LinkEdge is a symbolic link of a tree, and where it leads.
+type LinkEdge struct {
+	// Path is the path of the link, and Link its content.
+	Path string `json:"path"`
+	Link string `json:"link"`
+	// Target is the path the link points to, which may be another link,
+	// and Resolved the one it leads to after all the links, or "" if it's
+	// Broken.
+	Target   string `json:"target"`
+	Resolved string `json:"resolved,omitempty"`
+	Broken   bool   `json:"broken,omitempty"`
+	// External is set if the link leads out of the root.
+	External bool `json:"external,omitempty"`
+}
+
+// LinkGraph returns the symbolic links of a visited tree, in display
+// order.
+func (node *Node) LinkGraph(opts *Options) []LinkEdge {
+	roots := []string{absPath(node.path)}
+	if real, err := opts.evalSymlinks(node.path); err == nil {
+		roots = append(roots, absPath(real))
+	}
+	var edges []LinkEdge
+	node.appendLinks(&edges, roots)
+	return edges
+}
+
+func (node *Node) appendLinks(edges *[]LinkEdge, roots []string) {
+	if node.FileInfo != nil && node.err == nil && !node.archived && node.info().Mode()&os.ModeSymlink != 0 {
+		e := LinkEdge{Path: node.path, Link: node.link, Target: node.link, Resolved: node.resolved, Broken: node.broken}
+		if !filepath.IsAbs(node.link) {
+			e.Target = filepath.Join(filepath.Dir(node.path), node.link)
+		}
+		end := e.Resolved
+		if e.Broken {
+			end = e.Target
+		}
+		e.External = true
+		for _, root := range roots {
+			if rel, err := filepath.Rel(root, absPath(end)); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				e.External = false
+			}
+		}
+		*edges = append(*edges, e)
+	}
+	for _, nnode := range node.nodes {
+		nnode.appendLinks(edges, roots)
+	}
+}
+
+// absPath returns the absolute form of a path, or the path itself if the
+// working directory is unknown.
+func absPath(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return p
+}
+
+// printLinkGraph prints the symbolic links of the visited node as a
+// Graphviz digraph, the broken edges dashed in red and the external ones
+// in blue, or as a JSON line of the root and its LinkEdges.
+func (node *Node) printLinkGraph(opts *Options) {
+	edges := node.LinkGraph(opts)
+	if opts.LinkGraph == "json" {
+		if edges == nil {
+			edges = []LinkEdge{}
+		}
+		json.NewEncoder(opts.OutFile).Encode(struct {
+			Root  string     `json:"root"`
+			Links []LinkEdge `json:"links"`
+		}{node.path, edges})
+		return
+	}
+	fmt.Fprintf(opts.OutFile, "digraph %s {\n", dotQuote(node.path))
+	for _, e := range edges {
+		var attrs string
+		switch {
+		case e.Broken:
+			attrs = ` [style=dashed, color=red, label="broken"]`
+		case e.External:
+			attrs = ` [color=blue, label="external"]`
+		}
+		fmt.Fprintf(opts.OutFile, "\t%s -> %s%s;\n", dotQuote(e.Path), dotQuote(e.Target), attrs)
+	}
+	fmt.Fprintln(opts.OutFile, "}")
+}
+
+// dotQuote returns a DOT double-quoted string.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
@@ -0,0 +1,75 @@
+package tree
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// osFs is the os filesystem, as the mock one has no symbolic links.
+type osFs struct{}
+
+func (osFs) Stat(path string) (os.FileInfo, error) { return os.Lstat(path) }
+func (osFs) ReadDir(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	names, err := f.Readdirnames(-1)
+	sort.Strings(names)
+	return names, err
+}
+
+func TestLinkGraph(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	os.MkdirAll(filepath.Join(root, "lib"), 0755)
+	ioutil.WriteFile(filepath.Join(root, "lib", "libz.so.1.2"), nil, 0644)
+	ioutil.WriteFile(filepath.Join(dir, "outside"), nil, 0644)
+	for link, target := range map[string]string{
+		"lib/libz.so.1": "libz.so.1.2",
+		"lib/libz.so":   "libz.so.1",
+		"broken":        "missing",
+		"external":      "../outside",
+	} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Skip(err)
+		}
+	}
+	for _, test := range []struct {
+		format   string
+		expected string
+	}{
+		{"dot", `digraph "root" {
+	"root/broken" -> "root/missing" [style=dashed, color=red, label="broken"];
+	"root/external" -> "outside" [color=blue, label="external"];
+	"root/lib/libz.so" -> "root/lib/libz.so.1";
+	"root/lib/libz.so.1" -> "root/lib/libz.so.1.2";
+}
+`},
+		{"json", `{"root":"root","links":[` +
+			`{"path":"root/broken","link":"missing","target":"root/missing","broken":true},` +
+			`{"path":"root/external","link":"../outside","target":"outside","resolved":"outside","external":true},` +
+			`{"path":"root/lib/libz.so","link":"libz.so.1","target":"root/lib/libz.so.1","resolved":"root/lib/libz.so.1.2"},` +
+			`{"path":"root/lib/libz.so.1","link":"libz.so.1.2","target":"root/lib/libz.so.1.2","resolved":"root/lib/libz.so.1.2"}]}
+`},
+	} {
+		b := new(bytes.Buffer)
+		opts := &Options{Fs: osFs{}, OutFile: b, LinkGraph: test.format}
+		inf := New(root)
+		inf.Visit(opts)
+		inf.Print(opts)
+		if got := strings.Replace(b.String(), dir+"/", "", -1); got != test.expected {
+			t.Errorf("%s: got:\n%s\nexpected:\n%s", test.format, got, test.expected)
+		}
+	}
+}
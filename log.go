@@ -0,0 +1,25 @@
+package tree
+
+// Logger is used to report non-fatal events of a walk, such as
+// unreadable entries, broken links and skipped directories (see the
+// 'Logger' option). A *slog.Logger satisfies it, e.g.:
+//
+//	opts := &tree.Options{Fs: fs, OutFile: out, Logger: slog.Default()}
+//
+// Events are logged with a "path" attribute, and an "error" attribute
+// when there's one.
+type Logger interface {
+	Warn(msg string, args ...interface{})
+}
+
+// warn logs an event if the 'Logger' option is set.
+func (opts *Options) warn(msg, path string, err error) {
+	if opts.Logger == nil {
+		return
+	}
+	if err != nil {
+		opts.Logger.Warn(msg, "path", path, "error", err)
+	} else {
+		opts.Logger.Warn(msg, "path", path)
+	}
+}
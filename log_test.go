@@ -0,0 +1,66 @@
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+type fakeLogger struct {
+	lines []string
+}
+
+func (l *fakeLogger) Warn(msg string, args ...interface{}) {
+	line := msg
+	for i := 0; i+1 < len(args); i += 2 {
+		line += fmt.Sprintf(" %v=%v", args[i], args[i+1])
+	}
+	l.lines = append(l.lines, line)
+}
+
+// errFs fails the calls on the given paths.
+type errFs struct {
+	*MockFs
+	errs map[string]error
+}
+
+func (fs *errFs) Stat(path string) (os.FileInfo, error) {
+	if err := fs.errs[path]; err != nil {
+		return nil, err
+	}
+	return fs.MockFs.Stat(path)
+}
+
+func (fs *errFs) ReadDir(path string) ([]string, error) {
+	if err := fs.errs["readdir:"+path]; err != nil {
+		return nil, err
+	}
+	return fs.MockFs.ReadDir(path)
+}
+
+func TestLogger(t *testing.T) {
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a"},
+			{name: "b", files: []*file{}},
+			{name: "c", mode: os.ModeSymlink},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	efs := &errFs{fs, map[string]error{
+		"root/a":         errors.New("permission denied"),
+		"readdir:root/b": errors.New("permission denied"),
+	}}
+	logger := new(fakeLogger)
+	inf := New(root.name)
+	inf.Visit(&Options{Fs: efs, OutFile: out, Logger: logger})
+	expected := `cannot stat path=root/a error=permission denied
+cannot read directory path=root/b error=permission denied
+broken symbolic link path=root/c error=`
+	if actual := strings.Join(logger.lines, "\n"); !strings.HasPrefix(actual, expected) {
+		t.Errorf("\ngot:\n%s\nexpected:\n%s", actual, expected)
+	}
+}
@@ -0,0 +1,102 @@
+package tree
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// magicSize is the number of bytes sniffed by the MagicColor option, up
+// to the tar header's magic.
+const magicSize = 262
+
+// magicExecs are the signatures of the executable files.
+var magicExecs = [][]byte{
+	[]byte("\x7fELF"),
+	[]byte("#!"),
+	[]byte("MZ"),               // PE
+	[]byte("\xfe\xed\xfa\xce"), // Mach-O 32-bit
+	[]byte("\xfe\xed\xfa\xcf"), // Mach-O 64-bit
+	[]byte("\xce\xfa\xed\xfe"),
+	[]byte("\xcf\xfa\xed\xfe"),
+}
+
+// magicArchives are the signatures of the archives, and the extensions
+// they're colored like.
+var magicArchives = []struct {
+	magic []byte
+	ext   string
+}{
+	{[]byte("\x1f\x8b"), ".gz"},
+	{[]byte("BZh"), ".bz2"},
+	{[]byte("\xfd7zXZ\x00"), ".xz"},
+	{[]byte("\x28\xb5\x2f\xfd"), ".zst"},
+	{[]byte("PK\x03\x04"), ".zip"},
+	{[]byte("7z\xbc\xaf\x27\x1c"), ".7z"},
+	{[]byte("!<arch>\n"), ".deb"},
+	{[]byte("\xed\xab\xee\xdb"), ".rpm"},
+}
+
+// magicInfo is the FileInfo of a sniffed file, with the name and mode it's
+// colored by.
+type magicInfo struct {
+	os.FileInfo
+	name string
+	mode os.FileMode
+}
+
+func (fi magicInfo) Name() string      { return fi.name }
+func (fi magicInfo) Mode() os.FileMode { return fi.mode }
+
+// magicNode returns the node to color instead of node with the MagicColor
+// option: a regular file sniffed as an executable gets an execute bit,
+// and an archive the extension of its format. Other nodes are returned
+// as is.
+func (opts *Options) magicNode(node *Node) *Node {
+	fo, ok := opts.Fs.(FileOpener)
+	if !ok || node.FileInfo == nil || !node.Mode().IsRegular() || node.IsDir() || node.archived {
+		return node
+	}
+	r, err := fo.Open(node.path)
+	if err != nil {
+		return node
+	}
+	defer r.Close()
+	head := make([]byte, magicSize)
+	n, _ := io.ReadFull(r, head)
+	head = head[:n]
+	fi := magicInfo{node.FileInfo, node.Name(), node.Mode()}
+	switch ext := magicArchive(head); {
+	case ext != "":
+		fi.name += ext
+	case isMagicExec(head):
+		fi.mode |= modeExecute
+	default:
+		return node
+	}
+	return &Node{FileInfo: fi, path: node.path, depth: node.depth}
+}
+
+// isMagicExec reports whether head starts with an executable signature.
+func isMagicExec(head []byte) bool {
+	for _, magic := range magicExecs {
+		if bytes.HasPrefix(head, magic) {
+			return true
+		}
+	}
+	return false
+}
+
+// magicArchive returns the extension of the archive format of head, or ""
+// if it's not an archive.
+func magicArchive(head []byte) string {
+	for _, a := range magicArchives {
+		if bytes.HasPrefix(head, a.magic) {
+			return a.ext
+		}
+	}
+	if len(head) >= 262 && bytes.HasPrefix(head[257:], []byte("ustar")) {
+		return ".tar"
+	}
+	return ""
+}
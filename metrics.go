@@ -0,0 +1,163 @@
+package tree
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Metrics collects traversal statistics of the walks it's given to (see
+// the 'Metrics' option). It's safe for concurrent use, and it serves the
+// collected values in the Prometheus text exposition format, so it can be
+// mounted as a scrape endpoint, e.g.:
+//
+//	m := tree.NewMetrics()
+//	http.Handle("/metrics", m)
+//	opts := &tree.Options{Fs: fs, OutFile: out, Metrics: m}
+type Metrics struct {
+	mu      sync.Mutex
+	entries uint64
+	errors  uint64
+	bytes   uint64
+	walks   summary
+	calls   map[string]*summary
+}
+
+// summary is a count and a sum of observed durations.
+type summary struct {
+	count uint64
+	sum   time.Duration
+}
+
+// NewMetrics returns a new Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{calls: make(map[string]*summary)}
+}
+
+// MetricsSnapshot is a point in time copy of the collected metrics.
+type MetricsSnapshot struct {
+	Entries  uint64
+	Errors   uint64
+	Bytes    uint64
+	Walks    uint64
+	WalkTime time.Duration
+	// Calls and CallTime are the number and duration of the Fs calls,
+	// by method name ("Stat", "ReadDir", ...).
+	Calls    map[string]uint64
+	CallTime map[string]time.Duration
+}
+
+// Snapshot returns the current values of the metrics.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := MetricsSnapshot{
+		Entries:  m.entries,
+		Errors:   m.errors,
+		Bytes:    m.bytes,
+		Walks:    m.walks.count,
+		WalkTime: m.walks.sum,
+		Calls:    make(map[string]uint64),
+		CallTime: make(map[string]time.Duration),
+	}
+	for name, c := range m.calls {
+		s.Calls[name], s.CallTime[name] = c.count, c.sum
+	}
+	return s
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s := m.Snapshot()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	counter := func(name, help string, v uint64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, v)
+	}
+	counter("tree_entries_visited_total", "Number of visited entries.", s.Entries)
+	counter("tree_errors_total", "Number of entries that couldn't be read.", s.Errors)
+	counter("tree_bytes_total", "Sum of the visited files sizes.", s.Bytes)
+	fmt.Fprintf(w, "# HELP tree_walk_duration_seconds Duration of the walks.\n# TYPE tree_walk_duration_seconds summary\n")
+	fmt.Fprintf(w, "tree_walk_duration_seconds_sum %g\ntree_walk_duration_seconds_count %d\n", s.WalkTime.Seconds(), s.Walks)
+	fmt.Fprintf(w, "# HELP tree_fs_call_duration_seconds Duration of the Fs calls.\n# TYPE tree_fs_call_duration_seconds summary\n")
+	var names []string
+	for name := range s.Calls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "tree_fs_call_duration_seconds_sum{call=%q} %g\n", name, s.CallTime[name].Seconds())
+		fmt.Fprintf(w, "tree_fs_call_duration_seconds_count{call=%q} %d\n", name, s.Calls[name])
+	}
+}
+
+func (m *Metrics) observeCall(name string, d time.Duration) {
+	m.mu.Lock()
+	c, ok := m.calls[name]
+	if !ok {
+		c = new(summary)
+		m.calls[name] = c
+	}
+	c.count++
+	c.sum += d
+	m.mu.Unlock()
+}
+
+func (m *Metrics) observeWalk(d time.Duration) {
+	m.mu.Lock()
+	m.walks.count++
+	m.walks.sum += d
+	m.mu.Unlock()
+}
+
+func (m *Metrics) observeEntry(fi os.FileInfo, err error) {
+	m.mu.Lock()
+	m.entries++
+	if err != nil {
+		m.errors++
+	} else if !fi.IsDir() {
+		m.bytes += uint64(fi.Size())
+	}
+	m.mu.Unlock()
+}
+
+// stat calls Fs.Stat, and records it if the 'Metrics' option is set.
+func (opts *Options) stat(path string) (os.FileInfo, error) {
+	if opts.Metrics == nil {
+		return opts.Fs.Stat(path)
+	}
+	start := time.Now()
+	fi, err := opts.Fs.Stat(path)
+	opts.Metrics.observeCall("Stat", time.Since(start))
+	opts.Metrics.observeEntry(fi, err)
+	return fi, err
+}
+
+// readDir calls Fs.ReadDir, and records it if the 'Metrics' or 'Tracer'
+// options are set.
+func (opts *Options) readDir(path string) ([]string, error) {
+	if opts.Metrics == nil && opts.Tracer == nil {
+		return opts.listDir(path)
+	}
+	var span Span
+	if opts.Tracer != nil {
+		span = opts.Tracer.StartSpan(SpanReadDir, path)
+	}
+	start := time.Now()
+	names, err := opts.listDir(path)
+	if span != nil {
+		span.End(len(names), err)
+	}
+	if opts.Metrics == nil {
+		return names, err
+	}
+	opts.Metrics.observeCall("ReadDir", time.Since(start))
+	if err != nil {
+		opts.Metrics.mu.Lock()
+		opts.Metrics.errors++
+		opts.Metrics.mu.Unlock()
+	}
+	return names, err
+}
@@ -0,0 +1,42 @@
+package tree
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a", size: 10},
+			{name: "b", files: []*file{{name: "c", size: 5}}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	m := NewMetrics()
+	opts := &Options{Fs: fs, OutFile: out, Metrics: m}
+	New(root.name).Visit(opts)
+	s := m.Snapshot()
+	if s.Entries != 4 || s.Errors != 0 || s.Bytes != 15 || s.Walks != 1 {
+		t.Errorf("unexpected snapshot: %+v", s)
+	}
+	if s.Calls["Stat"] != 4 || s.Calls["ReadDir"] != 2 {
+		t.Errorf("unexpected calls: %+v", s.Calls)
+	}
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	for _, line := range []string{
+		"# TYPE tree_entries_visited_total counter",
+		"tree_entries_visited_total 4",
+		"tree_bytes_total 15",
+		"tree_walk_duration_seconds_count 1",
+		`tree_fs_call_duration_seconds_count{call="ReadDir"} 2`,
+		`tree_fs_call_duration_seconds_count{call="Stat"} 4`,
+	} {
+		if !strings.Contains(w.Body.String(), line+"\n") {
+			t.Errorf("missing line %q in:\n%s", line, w.Body.String())
+		}
+	}
+}
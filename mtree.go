@@ -0,0 +1,100 @@
+package tree
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// mtreeTypes are the mtree(5) type keywords of each FileType.
+var mtreeTypes = map[FileType]string{
+	TypeFile:        "file",
+	TypeSymlink:     "link",
+	TypeSocket:      "socket",
+	TypeFifo:        "fifo",
+	TypeBlockDevice: "block",
+	TypeCharDevice:  "char",
+}
+
+// printMtree prints the visited node and its children as an mtree(5)
+// manifest, in the full path form of bsdtar --format=mtree. The
+// unreadable entries are left out.
+func (node *Node) printMtree(opts *Options) {
+	if node.depth == 0 {
+		fmt.Fprintln(opts.OutFile, "#mtree")
+	}
+	if node.err != nil || node.FileInfo == nil {
+		return
+	}
+	fmt.Fprintln(opts.OutFile, node.mtreeLine(opts))
+	// The followed links and the archives are listed as themselves
+	if !node.IsDir() {
+		return
+	}
+	for _, nnode := range node.nodes {
+		nnode.printMtree(opts)
+	}
+}
+
+// mtreeLine returns the path and the keywords of a node.
+func (node *Node) mtreeLine(opts *Options) string {
+	name := "."
+	if node.depth > 0 {
+		name = "./" + node.relPath()
+	}
+	fi := node.FileInfo
+	mode := fi.Mode()
+	line := []string{mtreeEscape(name)}
+	if node.IsDir() {
+		line = append(line, "type=dir")
+	} else if typ, ok := mtreeTypes[fileType(mode)]; ok {
+		line = append(line, "type="+typ)
+	}
+	if ok, _, _, uid, gid := getStat(fi); ok {
+		line = append(line, fmt.Sprintf("uid=%d", uid), fmt.Sprintf("gid=%d", gid))
+	}
+	line = append(line, fmt.Sprintf("mode=%04o", mtreePerm(mode)))
+	if mode.IsRegular() {
+		line = append(line, fmt.Sprintf("size=%d", fi.Size()))
+	}
+	t := fi.ModTime()
+	line = append(line, fmt.Sprintf("time=%d.%09d", t.Unix(), t.Nanosecond()))
+	if mode&os.ModeSymlink != 0 && node.link != "" {
+		line = append(line, "link="+mtreeEscape(node.link))
+	}
+	if sum := node.Checksum(); sum != "" && !node.IsDir() {
+		line = append(line, strings.ToLower(opts.Checksum)+"digest="+sum)
+	}
+	return strings.Join(line, " ")
+}
+
+// mtreePerm returns the permission bits of a mode, with the setuid,
+// setgid and sticky ones as in chmod(1).
+func mtreePerm(mode os.FileMode) uint32 {
+	perm := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		perm |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		perm |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		perm |= 01000
+	}
+	return perm
+}
+
+// mtreeEscape escapes the white spaces, the non-printable and non-ASCII
+// bytes, and the characters special to mtree(5) as octal sequences,
+// like strsvis(3) with VIS_WHITE | VIS_OCTAL | VIS_GLOB.
+func mtreeEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c <= ' ' || c >= 0x7f || strings.IndexByte(`\#*?[`, c) >= 0 {
+			fmt.Fprintf(&b, `\%03o`, c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
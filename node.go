@@ -7,10 +7,11 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
-	"regexp"
-	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
 // Node represent some node in the tree
@@ -21,7 +22,70 @@ type Node struct {
 	depth  int
 	err    error
 	nodes  Nodes
-	vpaths map[string]bool
+	vpaths map[string]bool // visited directories, see visitKey
+	// the directories of the current branch, see follow
+	ancestors map[string]bool
+	// the compiled filter options of the walk
+	filters *walkFilters
+	fsinfo  *FsInfo
+	empty   bool
+	broken  bool
+	hidden  bool
+	// excluded by the Filter option
+	excluded bool
+	// scanned dirs and files, including the filtered ones
+	sdirs, sfiles int
+	// not descended because of the DeepLevel option
+	truncated bool
+	limited   int
+	// already visited, see the CollapseDuplicates option
+	duplicate bool
+	// size is the cumulative size of a directory, and sizeErr the last
+	// error encountered while computing it.
+	size    int64
+	sizeErr error
+	// hashErr is the error of the Checksum option, if the file couldn't
+	// be read.
+	hashErr error
+	// disk is the cumulative allocated size of a directory, including the
+	// directories themselves like du.
+	disk int64
+	// the target of a symlink, and its stat with the Dereference option
+	link   string
+	target os.FileInfo
+	// the path a symlink resolves to, or "" if it's broken
+	resolved string
+	// a symlink whose target directory was visited as its children, or
+	// not because it's an ancestor; see the FollowLink option
+	followed  bool
+	recursive bool
+	// the target directory visited by follow in place of a symlink, that
+	// isn't the walk root even at depth 0 (of a symlinked root)
+	viaLink bool
+	// the format of an archive listed as a subtree, and whether the node
+	// is one of its entries; see the Archives option
+	archive  string
+	archived bool
+	// the uncompressed size of a compressed file, see the Uncompressed
+	// option
+	usize int64
+	// number of changes printed with the Manifest option
+	mismatches int
+	// the risks found by the Audit option
+	risks []Risk
+	// a file unused since the StaleSince option
+	stale bool
+	// number of entries of a directory, or -1 if it wasn't read
+	entries int
+	// number of files listed below a directory
+	nfiles int
+	// the .info files of the ancestors, closest first, and the comment
+	// of the node in them; see the Info option
+	infos   []*infoFile
+	comment []string
+	// user data, see SetMeta
+	metaMu sync.Mutex
+	meta   map[string]interface{}
 }
 
 // List of nodes
@@ -40,26 +104,205 @@ type Fs interface {
 type Options struct {
 	Fs      Fs
 	OutFile io.Writer
-	// List
+	// Metrics collects traversal statistics, if set.
+	Metrics *Metrics
+	// Tracer traces the directory reads, if set.
+	Tracer Tracer
+	// Logger logs non-fatal events, if set.
+	Logger Logger
+	// ErrorSummary omits the errors from the printed tree, so they can be
+	// reported after it, see Node.Errors and FprintErrors.
+	ErrorSummary bool
+	// ErrFile, if set, receives the errors while the tree is printed,
+	// and the tree shows only the names of the entries that couldn't be
+	// read. ErrJSON writes them as JSON lines, e.g:
+	//
+	//	{"path":"root/a","dir":true,"error":"permission denied"}
+	ErrFile io.Writer
+	ErrJSON bool
+	// NoReport turns off the directory and file counts, that the command
+	// line prints after the tree listing.
+	NoReport bool
+	// List. All lists the hidden entries too: the dotfiles, and on Windows
+	// the ones with the hidden or system attribute.
 	All        bool
 	DirsOnly   bool
 	FullPath   bool
 	IgnoreCase bool
 	FollowLink bool
-	DeepLevel  int
-	Pattern    string
-	IPattern   string
+	// Dereference prints the metadata (size, mode, times...) of the
+	// symlink targets on the lines of the links, like ls -L.
+	Dereference bool
+	DeepLevel   int
+	Pattern     string
+	IPattern    string
+	// MatchPath matches Pattern and IPattern against the path relative
+	// to the root, rather than the base name.
+	MatchPath bool
+	// Glob interprets Pattern and IPattern as wildcard patterns with
+	// '**' support (e.g. "src/**/*_test.go"), rather than regular
+	// expressions.
+	Glob    bool
+	MinSize int64
+	MaxSize int64
+	// NewerThan and OlderThan restrict the listed files to a time range
+	// of last modification, or status change if ChangeTime is set.
+	NewerThan  time.Time
+	OlderThan  time.Time
+	ChangeTime bool
+	// Owner and Group restrict the listed files to the given user and
+	// group. Both accept either a name or a numeric id, the names are
+	// resolved once per walk, and an unknown one is an error.
+	Owner string
+	Group string
+	// Types restricts the listed files to the given kinds, e.g.
+	// TypeSymlink. Directories are always listed.
+	Types FileType
+	// Perm and PermMatch restrict the listed files by permission
+	// bits, e.g. os.ModeSetuid with PermAll lists setuid files.
+	Perm      os.FileMode
+	PermMatch PermMatch
+	// EmptyOnly lists only empty files and directories, and the
+	// directories leading to them.
+	EmptyOnly bool
+	// BrokenOnly lists only dangling symbolic links.
+	BrokenOnly bool
+	// ExecOnly lists only executable files.
+	ExecOnly bool
+	// HiddenOnly lists only hidden entries, their content, and the
+	// directories leading to them.
+	HiddenOnly bool
+	// HiddenFiles hides the names listed in the .hidden file of each
+	// directory too, one per line, as the file managers of macOS and GNOME
+	// do. The Fs must implement FileOpener.
+	HiddenFiles bool
+	// Expr restricts the listed files to the ones matching the given
+	// expression, see ParseExpr.
+	Expr Expr
+	// FilterMinDepth and FilterMaxDepth restrict the file filters above
+	// to entries within the given depth range (inclusive), e.g. a minimum
+	// depth of 3 keeps the first two levels complete. Zero means unbounded.
+	FilterMinDepth int
+	FilterMaxDepth int
+	// Prune removes the directories that are left with no entries after
+	// filtering, e.g. to show only the subtrees with matching files.
+	Prune bool
+	// FileLimit skips the content of the directories with more than
+	// FileLimit entries. Zero means unlimited.
+	FileLimit int
+	// MaxEntries limits the number of entries printed in each directory,
+	// and MaxLines the number of entries printed in all, the root
+	// included. The rest of a directory is replaced with a marker, e.g.
+	// "… and 12 more". They're still counted. Zero means unlimited.
+	MaxEntries int
+	MaxLines   int
+	// CollapseDuplicates lists the content of the directories that are
+	// reached more than once (e.g. through bind mounts) only the first
+	// time, and marks the next ones with [already shown]. Their content
+	// isn't counted again.
+	CollapseDuplicates bool
+	// InodeOrder visits the entries of each directory in inode order,
+	// which is much faster on some filesystems and disks for very large
+	// directories, if the Fs implements InodeReader. They're still sorted
+	// for display.
+	InodeOrder bool
+	// RecursionLimit is the depth at which the walk stops descending,
+	// including the followed symbolic links, and reports an error. It
+	// defaults to DefaultRecursionLimit.
+	RecursionLimit int
+	// Filter is called for every entry (except the root) after it was
+	// stat'ed. Returning false excludes the entry, and skips the traversal
+	// of directories.
+	Filter func(node *Node) bool
+	// Decorators compute extra columns, printed after the other
+	// properties. They run once the tree is visited, and their values are
+	// attached to the nodes, see Node.Meta.
+	Decorators []Decorator
+	// Exec is called for every regular file once the tree is visited,
+	// after the checksums, and its annotation is printed after the name,
+	// e.g. [clean]. It's called by ExecWorkers goroutines at most,
+	// GOMAXPROCS by default. See ExecCommand to run external commands.
+	Exec        func(n *Node) (annotation string, err error)
+	ExecWorkers int
 	// File
 	ByteSize bool
 	UnitSize bool
-	FileMode bool
-	ShowUid  bool
-	ShowGid  bool
-	LastMod  bool
+	// SizeUnits is the suffix style of the UnitSize sizes: "" for K, M,
+	// G..., "iec" for KiB, MiB, GiB..., or "si" for kB, MB, GB... in
+	// powers of 1000.
+	SizeUnits string
+	// SizePrecision is the number of decimals of the UnitSize sizes. By
+	// default, one decimal is printed under 10 only, and a negative value
+	// prints none.
+	SizePrecision int
+	// Blocks prints the disk usage of each entry in 1K blocks, like du -k.
+	// Directories include their own blocks and the ones of their entries.
+	Blocks bool
+	// DiskSize prints the allocated size of each entry next to its
+	// apparent one (in bytes, or human readable with UnitSize), which
+	// exposes sparse files and the waste of partially used blocks.
+	DiskSize bool
+	// Uncompressed prints the uncompressed size of the .gz, .zst and .xz
+	// files next to their size, as recorded in their headers: modulo 4G
+	// for gzip, and only if the zstd frames have it. The Fs must
+	// implement FileOpener.
+	Uncompressed bool
+	FileMode     bool
+	ShowUid      bool
+	ShowGid      bool
+	LastMod      bool
+	// TimeFormat is the layout of the LastMod dates, defaults to
+	// "Jan 02 15:04".
+	TimeFormat string
+	// Checksum prints the checksum of the regular files, computed with the
+	// hash function "md5", "sha1" or "sha256", and attaches it to the nodes
+	// as the "checksum" Meta. The Fs must implement FileOpener.
+	Checksum string
+	// HashWorkers is the number of files hashed concurrently, GOMAXPROCS
+	// by default.
+	HashWorkers int
+	// Merkle attaches a checksum to the directories too: the hash of the
+	// names, types and checksums of their listed children, sorted by name.
+	// Two trees with the same content have the same root checksum.
+	Merkle bool
+	// Archives lists the content of the .zip, .jar, .tar, .tar.gz and .tgz
+	// files as their children, marked with e.g. [zip archive]. The Fs must
+	// implement FileOpener.
+	Archives bool
 	Quotes   bool
-	Inodes   bool
-	Device   bool
-	// Sort
+	// DirSlash appends a '/' to the names of the directories.
+	DirSlash  bool
+	Inodes    bool
+	Device    bool
+	FsType    bool
+	FsUsage   bool
+	MarkEmpty bool
+	// EntryCount prints the number of entries of each directory, e.g.
+	// [12 entries], including the ones that aren't listed.
+	EntryCount bool
+	// FileCount prints the number of files listed below each directory,
+	// recursively, e.g. [1024 files].
+	FileCount bool
+	// SizeThreshold and EntryThreshold highlight the directories whose
+	// cumulative size or number of entries exceeds them, e.g. [over 1.0G],
+	// in ThresholdStyle with Colorize. Zero disables them.
+	SizeThreshold  int64
+	EntryThreshold int
+	// Audit marks the risky entries, e.g. [setuid]: the world-writable
+	// directories without the sticky bit, the world-writable files, the
+	// setuid and setgid files, and the entries owned by an unknown UID or
+	// by nobody. See AuditFindings.
+	Audit bool
+	// StaleSince reports the regular files neither modified nor accessed
+	// since then, as cleanup candidates. See StaleGroups.
+	StaleSince time.Time
+	// Hardlinks reports the files with several hard links, the paths of
+	// each and the space they save. See HardlinkGroups.
+	Hardlinks bool
+	// Sort. The entries are sorted by name when no sort option is set,
+	// and NoSort keeps them in the order of the Fs ReadDir, i.e. the raw
+	// readdir order for ostree.FS. DirSort isn't a sort of its own, it
+	// lists the directories before the files, each sorted by the others.
 	NoSort    bool
 	VerSort   bool
 	ModSort   bool
@@ -67,10 +310,91 @@ type Options struct {
 	NameSort  bool
 	SizeSort  bool
 	CTimeSort bool
+	// ExtSort sorts by extension, and then by name, see ExtSort.
+	ExtSort   bool
 	ReverSort bool
+	// FoldSort compares the names case-insensitively, and DotlessSort
+	// ignores their leading dots, when sorting by name.
+	FoldSort    bool
+	DotlessSort bool
 	// Graphics
 	NoIndent bool
 	Colorize bool
+	// Highlight the part of the names that matches the Pattern option,
+	// when Colorize is set.
+	Highlight bool
+	// MagicColor colors the regular files by their content too: the ones
+	// starting with an executable signature (ELF, Mach-O, PE or a shebang)
+	// like the executables, and the archives like their format extension.
+	// The Fs must implement FileOpener.
+	MagicColor bool
+	// Charset of the indentation lines, "utf-8" (the default) or "ascii".
+	Charset string
+	// IndentWidth is the width of each indentation level, 4 by default.
+	// The connectors are resized to fit, e.g. "├─ " for 3.
+	IndentWidth int
+	// NameWidth shortens the names longer than it with a middle ellipsis,
+	// keeping their extension, e.g. "verylongna…me.tar.gz" for 20.
+	NameWidth int
+	// Icons prefixes the names with an icon of their type or extension,
+	// given by Icon, or EmojiIcons by default.
+	Icons bool
+	Icon  func(*Node) string
+	// Info prints the comments of the .info files below the entries they
+	// match, like GNU tree's --info. The Fs must implement FileOpener.
+	Info bool
+	// Accessible prints the entries for screen readers: each line starts
+	// with its level (e.g. "level 2: main.go") instead of the indentation
+	// lines, and the types shown by the colors are also spelled out.
+	Accessible bool
+	// BidiIsolate wraps the names containing right-to-left characters
+	// (Hebrew, Arabic...) in Unicode isolation marks, so they don't
+	// reorder the indentation lines and the properties around them.
+	BidiIsolate bool
+	// DepthPrefix prefixes each line with the depth of its entry, the
+	// root's being 0, for the scripts parsing the output.
+	DepthPrefix bool
+	// Mtree prints the tree as a BSD mtree(5) manifest instead, in the
+	// full path form, with the digests of the Checksum option.
+	Mtree bool
+	// LinkGraph prints the graph of the symbolic links instead of the
+	// tree, in the "dot" (Graphviz) or "json" format: an edge from each
+	// link to its target, marked if it's broken or leads out of the tree.
+	// See LinkGraph.
+	LinkGraph string
+	// Format prints the tree with the Formatter registered under that
+	// name instead, see RegisterFormat.
+	Format string
+	// JSON prints the tree as JSON instead, in the format of GNU tree -J:
+	// an array of the nested entries, with their type, size, mode and
+	// modification time, followed by a report. Run prints all the roots
+	// in the same array.
+	JSON bool
+	// XML prints the tree as an XML document instead, in the format of
+	// GNU tree -X, with the attributes of the FileMode, ShowUid, ShowGid,
+	// ByteSize, LastMod, Inodes and Device options.
+	XML bool
+	// HTML prints the tree as an HTML page instead, like GNU tree -H: the
+	// entries are links relative to that base HREF (e.g. "." or
+	// "https://example.com/files"), in the nested lists of their
+	// directories.
+	HTML string
+	// Snapshot receives a snapshot of each printed tree, see
+	// WriteSnapshot.
+	Snapshot io.Writer
+	// Baseline are the roots of a snapshot, see ReadSnapshot. If set, the
+	// changes since the snapshot are printed instead of the tree, as a
+	// tree of the changed entries, or as NDJSON lines of Change with
+	// ChangesJSON.
+	Baseline []*Entry
+	// Manifest are the roots of a manifest, see ReadManifest. If set, the
+	// tree is verified against it instead of printed: the missing, extra
+	// and mismatched entries are printed like the Baseline changes. The
+	// checksums are verified if the Checksum option matches the manifest
+	// digests.
+	Manifest []*Entry
+	// ChangesJSON prints the Baseline or Manifest changes as NDJSON lines.
+	ChangesJSON bool
 	// Color defaults to ANSIColor()
 	Color func(*Node, string) string
 }
@@ -85,78 +409,228 @@ func (opts *Options) color(node *Node, s string) string {
 
 // New get path and create new node(root).
 func New(path string) *Node {
-	return &Node{path: path, vpaths: make(map[string]bool)}
+	return &Node{path: path, vpaths: make(map[string]bool), ancestors: make(map[string]bool)}
 }
 
 // Visit all files under the given node.
 func (node *Node) Visit(opts *Options) (dirs, files int) {
-	// visited paths
-	if path, err := filepath.Abs(node.path); err == nil {
-		path = filepath.Clean(path)
-		node.vpaths[path] = true
+	root := node.walkRoot()
+	// walk duration
+	if opts.Metrics != nil && root {
+		start := time.Now()
+		defer func() { opts.Metrics.observeWalk(time.Since(start)) }()
+	}
+	// Exec and then the Decorators run once the whole tree is visited,
+	// after the checksums
+	if root {
+		defer opts.decorate(node)
+		defer opts.execFiles(node)
+	}
+	if root && opts.Checksum != "" {
+		defer opts.hashFiles(node)
+	}
+	// Invalid filters fail the walk, rather than listing everything.
+	// They're compiled once, for all the nodes.
+	if root {
+		wf, err := opts.compileFilters()
+		if err != nil {
+			opts.warn("invalid filter", node.path, err)
+			node.err = err
+			return
+		}
+		node.filters = wf
 	}
 	// stat
-	fi, err := opts.Fs.Stat(node.path)
+	fi, err := opts.stat(node.path)
 	if err != nil {
+		opts.warn("cannot stat", node.path, err)
 		node.err = err
 		return
 	}
 	node.FileInfo = fi
+	// The entries hidden by their attributes are skipped like the dotfiles
+	if node.depth != 0 && hiddenAttr(fi) {
+		node.hidden = true
+		if !opts.All && !opts.HiddenOnly {
+			node.excluded = true
+			return
+		}
+	}
+	// Info option
+	if opts.Info && node.depth != 0 {
+		node.comment = node.infoComment()
+	}
+	// visited directories
+	if fi.IsDir() {
+		node.disk = diskSize(fi)
+		key := visitKey(node.path, fi)
+		node.duplicate = opts.CollapseDuplicates && node.vpaths[key]
+		node.vpaths[key] = true
+		if !node.ancestors[key] {
+			node.ancestors[key] = true
+			defer delete(node.ancestors, key)
+		}
+	}
+	if !fi.IsDir() {
+		node.sfiles = 1
+	} else if node.depth != 0 {
+		node.sdirs = 1
+	}
+	// Filter option
+	if opts.Filter != nil && node.depth != 0 && !opts.Filter(node) {
+		node.excluded = true
+		return
+	}
+	if opts.Audit {
+		node.risks = auditRisks(fi)
+	}
+	if !opts.StaleSince.IsZero() {
+		node.stale = isStale(fi, opts.StaleSince)
+	}
 	if !fi.IsDir() {
+		node.empty = fi.Mode().IsRegular() && fi.Size() == 0
+		if fi.Mode()&os.ModeSymlink != 0 {
+			node.link, _ = opts.readlink(node.path)
+			target, err := opts.evalSymlinks(node.path)
+			node.resolved = target
+			if node.broken = err != nil; node.broken {
+				opts.warn("broken symbolic link", node.path, err)
+			} else if opts.Dereference || opts.FollowLink {
+				tfi, _ := opts.Fs.Stat(target)
+				if opts.Dereference {
+					node.target = tfi
+				}
+				// Follow symbolic links like directories
+				if opts.FollowLink && tfi != nil && tfi.IsDir() {
+					return node.follow(opts, target, tfi)
+				}
+			}
+		}
+		// Uncompressed option
+		if opts.Uncompressed && fi.Mode().IsRegular() {
+			node.usize = opts.uncompressedSize(node)
+		}
+		// Archives option
+		if format := archiveFormat(node.Name()); opts.Archives && format != "" && fi.Mode().IsRegular() {
+			dirs, files = node.expandArchive(opts, format)
+			return dirs, files + 1
+		}
 		return 0, 1
 	}
+	node.entries = -1
 	// increase dirs only if it's a dir, but not the root.
 	if node.depth != 0 {
 		dirs++
+	} else if opts.FsType || opts.FsUsage {
+		node.fsinfo = opts.statfs(node.path)
+	}
+	// CollapseDuplicates option
+	if node.duplicate {
+		return
+	}
+	// Recursion limit
+	if node.depth >= opts.recursionLimit() {
+		opts.warn("recursion limit reached", node.path, nil)
+		node.err = ErrRecursionLimit
+		return
 	}
 	// DeepLevel option
 	if opts.DeepLevel > 0 && opts.DeepLevel <= node.depth {
+		node.truncated = true
+		node.sizeErr = errors.New("Depth too high")
+		if opts.EntryCount {
+			if names, err := opts.readDir(node.path); err == nil {
+				node.entries = len(names)
+			}
+		}
 		return
 	}
-	names, err := opts.Fs.ReadDir(node.path)
+	names, err := opts.readDir(node.path)
 	if err != nil {
+		opts.warn("cannot read directory", node.path, err)
 		node.err = err
 		return
 	}
+	node.empty = len(names) == 0
+	node.entries = len(names)
+	// Info option
+	if opts.Info {
+		for _, name := range names {
+			if name == infoName {
+				if info := opts.readInfo(node.path); info != nil {
+					node.infos = append([]*infoFile{info}, node.infos...)
+				}
+				break
+			}
+		}
+	}
+	// HiddenFiles option
+	var listed map[string]bool
+	if opts.HiddenFiles {
+		for _, name := range names {
+			if name == hiddenName {
+				listed = opts.readHidden(node.path)
+				break
+			}
+		}
+	}
+	// FileLimit option
+	if opts.FileLimit > 0 && len(names) > opts.FileLimit {
+		node.limited = len(names)
+		return
+	}
 	node.nodes = make(Nodes, 0)
 	for _, name := range names {
+		hidden := strings.HasPrefix(name, ".") || listed[name]
 		// "all" option
-		if !opts.All && strings.HasPrefix(name, ".") {
+		if !opts.All && !opts.HiddenOnly && hidden {
 			continue
 		}
 		nnode := &Node{
-			path:   filepath.Join(node.path, name),
-			depth:  node.depth + 1,
-			vpaths: node.vpaths,
+			path:      filepath.Join(node.path, name),
+			depth:     node.depth + 1,
+			vpaths:    node.vpaths,
+			ancestors: node.ancestors,
+			filters:   node.filters,
+			hidden:    node.hidden || hidden,
+			infos:     node.infos,
 		}
 		d, f := nnode.Visit(opts)
-		if nnode.err == nil && !nnode.IsDir() {
+		node.sdirs, node.sfiles = node.sdirs+nnode.sdirs, node.sfiles+nnode.sfiles
+		if nnode.excluded {
+			continue
+		}
+		// Filesystem of mount points
+		if (opts.FsType || opts.FsUsage) && nnode.err == nil && nnode.IsDir() && nnode.crossesMount(node) {
+			nnode.fsinfo = opts.statfs(nnode.path)
+		}
+		if nnode.err == nil && !nnode.isDir() {
 			// "dirs only" option
 			if opts.DirsOnly {
 				continue
 			}
-			var rePrefix string
-			if opts.IgnoreCase {
-				rePrefix = "(?i)"
-			}
-			// Pattern matching
-			if opts.Pattern != "" {
-				re, err := regexp.Compile(rePrefix + opts.Pattern)
-				if err == nil && !re.MatchString(name) {
-					continue
-				}
-			}
-			// IPattern matching
-			if opts.IPattern != "" {
-				re, err := regexp.Compile(rePrefix + opts.IPattern)
-				if err == nil && re.MatchString(name) {
-					continue
-				}
+			// Filters
+			if opts.inFilterDepth(nnode.depth) && !nnode.match(opts) {
+				continue
 			}
 		}
+		// "empty only" option
+		if opts.EmptyOnly && nnode.err == nil && !nnode.empty && len(nnode.nodes) == 0 {
+			continue
+		}
+		// Prune option, the directories that weren't read aren't empty
+		if opts.Prune && nnode.err == nil && nnode.isDir() && len(nnode.nodes) == 0 && !nnode.unread() {
+			continue
+		}
+		// "hidden only" option
+		if opts.HiddenOnly && nnode.err == nil && !nnode.hidden && len(nnode.nodes) == 0 {
+			continue
+		}
 		node.nodes = append(node.nodes, nnode)
+		node.addSize(nnode)
 		dirs, files = dirs+d, files+f
 	}
+	node.nfiles = files
 	// Sorting
 	if !opts.NoSort {
 		node.sort(opts)
@@ -164,6 +638,51 @@ func (node *Node) Visit(opts *Options) (dirs, files int) {
 	return
 }
 
+// follow visits the target directory of a symlink as the children of the
+// node, unless it's one of its ancestors. The followed subtrees are
+// counted, sized and limited by DeepLevel like the other directories.
+func (node *Node) follow(opts *Options, target string, fi os.FileInfo) (dirs, files int) {
+	if node.ancestors[visitKey(target, fi)] {
+		node.recursive = true
+		opts.warn("recursive symbolic link not followed", node.path, nil)
+		return 0, 1
+	}
+	inf := &Node{
+		path:      target,
+		depth:     node.depth,
+		vpaths:    node.vpaths,
+		ancestors: node.ancestors,
+		filters:   node.filters,
+		hidden:    node.hidden,
+		infos:     node.infos,
+		viaLink:   true,
+	}
+	dirs, files = inf.Visit(opts)
+	node.followed = true
+	node.nodes, node.entries, node.nfiles, node.empty = inf.nodes, inf.entries, inf.nfiles, inf.empty
+	node.size, node.disk, node.sizeErr = inf.size, inf.disk, inf.sizeErr
+	node.sdirs, node.sfiles = inf.sdirs, inf.sfiles
+	node.truncated, node.limited = inf.truncated, inf.limited
+	return
+}
+
+// walkRoot reports whether the node is the root of the walk, that runs
+// the work done once per walk (e.g. the Exec option).
+func (node *Node) walkRoot() bool {
+	return node.depth == 0 && !node.viaLink
+}
+
+// isDir reports whether the node is listed as a directory: a directory,
+// or a followed symlink to one.
+func (node *Node) isDir() bool {
+	return node.IsDir() || node.followed
+}
+
+// listedDir is isDir for the nodes that may not have been stat'ed.
+func (node *Node) listedDir() bool {
+	return node.FileInfo != nil && node.isDir()
+}
+
 func (node *Node) sort(opts *Options) {
 	var fn SortFunc
 	switch {
@@ -171,22 +690,33 @@ func (node *Node) sort(opts *Options) {
 		fn = ModSort
 	case opts.CTimeSort:
 		fn = CTimeSort
-	case opts.DirSort:
-		fn = DirSort
 	case opts.VerSort:
 		fn = VerSort
 	case opts.SizeSort:
 		fn = SizeSort
+	case opts.ExtSort:
+		fn = ExtSort
 	case opts.NameSort:
-		fn = NameSort
+		fn = opts.nameSort()
 	default:
-		fn = NameSort // Default should be sorted, not unsorted.
+		fn = opts.nameSort() // Default should be sorted, not unsorted.
 	}
 	if fn != nil {
+		less := ByFunc{node.nodes, fn}.less
+		// DirSort option, the directories are grouped before the files
+		if opts.DirSort {
+			sorted := less
+			less = func(a, b *Node) bool {
+				if da, db := a.listedDir(), b.listedDir(); da != db {
+					return da
+				}
+				return sorted(a, b)
+			}
+		}
 		if opts.ReverSort {
-			sort.Sort(sort.Reverse(ByFunc{node.nodes, fn}))
+			sortNodes(node.nodes, func(a, b *Node) bool { return less(b, a) })
 		} else {
-			sort.Sort(ByFunc{node.nodes, fn})
+			sortNodes(node.nodes, less)
 		}
 	}
 }
@@ -196,45 +726,262 @@ func (node *Node) Path() string {
 	return node.path
 }
 
-// Print nodes based on the given configuration.
-func (node *Node) Print(opts *Options) { node.print("", opts) }
+// relPath returns the slash-separated path of the node, relative to the
+// root of the tree.
+func (node *Node) relPath() string {
+	parts := strings.Split(filepath.ToSlash(node.path), "/")
+	if node.depth < len(parts) {
+		parts = parts[len(parts)-node.depth:]
+	}
+	return strings.Join(parts, "/")
+}
+
+// Depth returns the Node's depth in the tree. The root's depth is 0.
+func (node *Node) Depth() int {
+	return node.depth
+}
+
+// Scanned returns the number of directories and files that were scanned
+// while visiting the node, including the ones that were filtered out.
+func (node *Node) Scanned() (dirs, files int) {
+	return node.sdirs, node.sfiles
+}
+
+// unread reports whether a visited directory wasn't read, because of the
+// DeepLevel, FileLimit or CollapseDuplicates options.
+func (node *Node) unread() bool {
+	return node.truncated || node.limited > 0 || node.duplicate
+}
 
-func dirRecursiveSize(opts *Options, node *Node) (size int64, err error) {
-	if opts.DeepLevel > 0 && node.depth >= opts.DeepLevel {
-		err = errors.New("Depth too high")
+// Truncated reports whether some directories were not descended because
+// of the 'DeepLevel' option while visiting the node.
+func (node *Node) Truncated() bool {
+	if node.truncated {
+		return true
+	}
+	for _, nnode := range node.nodes {
+		if nnode.Truncated() {
+			return true
+		}
 	}
+	return false
+}
 
+// BrokenLinks returns the number of dangling symbolic links found while
+// visiting the node.
+func (node *Node) BrokenLinks() (n int) {
+	if node.broken {
+		n++
+	}
 	for _, nnode := range node.nodes {
-		if nnode.err != nil {
-			err = nnode.err
-			continue
+		n += nnode.BrokenLinks()
+	}
+	return
+}
+
+// Print nodes based on the given configuration.
+func (node *Node) Print(opts *Options) {
+	node.writeSnapshot(opts)
+	if opts.JSON {
+		printJSON(opts, []*Node{node})
+		return
+	}
+	if opts.Mtree {
+		node.printMtree(opts)
+		return
+	}
+	if opts.LinkGraph != "" {
+		node.printLinkGraph(opts)
+		return
+	}
+	if opts.Format != "" {
+		node.printFormat(opts)
+		return
+	}
+	if opts.XML {
+		node.printXML(opts)
+		return
+	}
+	if opts.HTML != "" {
+		node.printHTML(opts)
+		return
+	}
+	if opts.Baseline != nil {
+		node.printChanges(opts, opts.Baseline, diffChange)
+		return
+	}
+	if opts.Manifest != nil {
+		node.mismatches = node.printChanges(opts, opts.Manifest, verifyChange(opts.Checksum))
+		return
+	}
+	fmt.Fprint(opts.OutFile, opts.linePrefix(node.depth))
+	// MaxLines option
+	var left *int
+	if opts.MaxLines > 0 {
+		n := opts.MaxLines - 1
+		left = &n
+	}
+	node.print("", opts, left)
+}
+
+// writeSnapshot writes the snapshot of the Snapshot option, if set.
+func (node *Node) writeSnapshot(opts *Options) {
+	if opts.Snapshot != nil {
+		if err := WriteSnapshot(opts.Snapshot, node); err != nil {
+			opts.warn("snapshot not written", node.path, err)
 		}
+	}
+}
 
-		if !nnode.IsDir() {
-			size += nnode.Size()
-		} else {
-			nsize, e := dirRecursiveSize(opts, nnode)
-			size += nsize
-			if e != nil {
-				err = e
-			}
+// dirSize returns the cumulative size of the files of a visited
+// directory, and the last error encountered while computing it.
+func (node *Node) dirSize() (int64, error) {
+	return node.size, node.sizeErr
+}
+
+// addSize adds the size of a listed child to the cumulative size of the
+// directory.
+func (node *Node) addSize(nnode *Node) {
+	switch {
+	case nnode.err != nil:
+		node.sizeErr = nnode.err
+	case !nnode.isDir():
+		node.size += nnode.info().Size()
+		node.disk += nnode.diskUsage()
+	default:
+		node.size += nnode.size
+		node.disk += nnode.disk
+		if nnode.sizeErr != nil {
+			node.sizeErr = nnode.sizeErr
 		}
 	}
-	return
 }
 
-func (node *Node) print(indent string, opts *Options) {
-	if node.err != nil {
-		err := node.err.Error()
-		if msgs := strings.Split(err, ": "); len(msgs) > 1 {
-			err = msgs[1]
+// subSize removes the size of a child from the cumulative size of the
+// directory, see addSize.
+func (node *Node) subSize(nnode *Node) {
+	switch {
+	case nnode.err != nil:
+	case !nnode.isDir():
+		node.size -= nnode.info().Size()
+		node.disk -= nnode.diskUsage()
+	default:
+		node.size -= nnode.size
+		node.disk -= nnode.disk
+	}
+}
+
+// diskUsage returns the allocated size of a file, or the cumulative one of
+// a visited directory.
+func (node *Node) diskUsage() int64 {
+	if node.IsDir() {
+		return node.disk
+	}
+	return diskSize(node.info())
+}
+
+// info returns the FileInfo the metadata of the node is printed from: its
+// own, or the one of its target with the Dereference option.
+func (node *Node) info() os.FileInfo {
+	if node.target != nil {
+		return node.target
+	}
+	return node.FileInfo
+}
+
+// diskSize returns the space allocated to a file, or its size if the Fs
+// doesn't report it.
+func diskSize(fi os.FileInfo) int64 {
+	if blocks, ok := getBlocks(fi); ok {
+		return blocks * 512
+	}
+	return fi.Size()
+}
+
+// blocksColumn formats the disk usage of the Blocks option, in 1K blocks
+// rounded up.
+func blocksColumn(disk int64) string {
+	return fmt.Sprintf("%8d", (disk+KB-1)/KB)
+}
+
+// print prints the node and its children, at most *left of them if left
+// isn't nil; see the MaxLines option.
+func (node *Node) print(indent string, opts *Options, left *int) {
+	node.printEntry(indent, opts)
+	lines := opts.lines()
+	nodes, more := node.nodes, 0
+	// MaxEntries option
+	if opts.MaxEntries > 0 && len(nodes) > opts.MaxEntries {
+		nodes, more = nodes[:opts.MaxEntries], len(nodes)-opts.MaxEntries
+	}
+	for i, nnode := range nodes {
+		if left != nil && *left == 0 {
+			more += len(nodes) - i
+			break
+		}
+		lead, add := opts.connector(indent, nnode.depth, i == len(nodes)-1 && more == 0, lines)
+		fmt.Fprint(opts.OutFile, lead)
+		if left != nil {
+			*left--
+		}
+		nnode.print(indent+add, opts, left)
+	}
+	if more > 0 {
+		node.printMore(indent, more, opts)
+	}
+}
+
+// linePrefix returns the start of the line of an entry at the given
+// depth, with the DepthPrefix and Accessible options.
+func (opts *Options) linePrefix(depth int) string {
+	var s string
+	if opts.DepthPrefix {
+		s += fmt.Sprintf("%d ", depth)
+	}
+	if opts.Accessible {
+		s += fmt.Sprintf("level %d: ", depth)
+	}
+	return s
+}
+
+// connector returns the start of the line of a child entry at the given
+// depth, the last one of its parent or not, with its indentation line,
+// and what its own children are indented with in addition to indent.
+func (opts *Options) connector(indent string, depth int, last bool, lines indentLines) (lead, add string) {
+	lead = opts.linePrefix(depth)
+	switch {
+	case opts.NoIndent || opts.Accessible:
+		return lead, ""
+	case last:
+		return lead + indent + lines.last, strings.Repeat(" ", utf8.RuneCountInString(lines.vertical))
+	}
+	return lead + indent + lines.branch, lines.vertical
+}
+
+// printEntry prints the line of the node, and its Info comment.
+func (node *Node) printEntry(indent string, opts *Options) {
+	if node.err != nil && opts.ErrFile != nil && !opts.ErrorSummary {
+		opts.writeErr(node)
+	}
+	if node.err != nil && (opts.ErrorSummary || opts.ErrFile != nil) {
+		// Errors are reported apart from the tree, just print the name of
+		// the entries that couldn't be stat'ed.
+		if node.FileInfo == nil {
+			name := filepath.Base(node.path)
+			if node.depth == 0 || opts.FullPath {
+				name = node.path
+			}
+			fmt.Fprintln(opts.OutFile, name)
+			return
 		}
-		fmt.Printf("%s [%s]\n", node.path, err)
+	} else if node.err != nil {
+		fmt.Fprintf(opts.OutFile, "%s [%s]\n", node.path, errReason(node.err))
 		return
 	}
 	if !node.IsDir() {
 		var props []string
-		ok, inode, device, uid, gid := getStat(node)
+		fi := node.info()
+		ok, inode, device, uid, gid := getStat(fi)
 		// inodes
 		if ok && opts.Inodes {
 			props = append(props, fmt.Sprintf("%d", inode))
@@ -245,7 +992,7 @@ func (node *Node) print(indent string, opts *Options) {
 		}
 		// Mode
 		if opts.FileMode {
-			props = append(props, node.Mode().String())
+			props = append(props, fi.Mode().String())
 		}
 		// Owner/Uid
 		if ok && opts.ShowUid {
@@ -262,43 +1009,67 @@ func (node *Node) print(indent string, opts *Options) {
 			gidStr := strconv.Itoa(int(gid))
 			props = append(props, fmt.Sprintf("%-4s", gidStr))
 		}
+		// Blocks
+		if opts.Blocks {
+			props = append(props, blocksColumn(node.diskUsage()))
+		}
 		// Size
-		if opts.ByteSize || opts.UnitSize {
-			var size string
-			if opts.UnitSize {
-				size = fmt.Sprintf("%4s", formatBytes(node.Size()))
-			} else {
-				size = fmt.Sprintf("%11d", node.Size())
-			}
-			props = append(props, size)
+		if opts.ByteSize || opts.UnitSize || opts.DiskSize || opts.Uncompressed {
+			props = append(props, opts.sizeColumn(fi.Size()))
+		}
+		if opts.DiskSize {
+			props = append(props, opts.sizeColumn(node.diskUsage()))
+		}
+		if opts.Uncompressed {
+			props = append(props, opts.uncompressedColumn(node))
 		}
 		// Last modification
 		if opts.LastMod {
-			props = append(props, node.ModTime().Format("Jan 02 15:04"))
+			props = append(props, fi.ModTime().Format(opts.timeFormat()))
+		}
+		// Checksum
+		if opts.Checksum != "" {
+			props = append(props, opts.checksumColumn(node))
 		}
+		// Decorators
+		props = append(props, opts.decorations(node)...)
 		// Print properties
 		if len(props) > 0 {
 			fmt.Fprintf(opts.OutFile, "[%s]  ", strings.Join(props, " "))
 		}
 	} else {
 		var props []string
+		// Blocks
+		if opts.Blocks {
+			props = append(props, blocksColumn(node.diskUsage()))
+		}
 		// Size
-		if opts.ByteSize || opts.UnitSize {
+		if opts.ByteSize || opts.UnitSize || opts.DiskSize || opts.Uncompressed {
 			var size string
-			rsize, err := dirRecursiveSize(opts, node)
+			rsize, err := node.dirSize()
 			if err != nil && rsize <= 0 {
 				if opts.UnitSize {
-					size = "????"
+					size = strings.Repeat("?", opts.sizeWidth())
 				} else {
 					size = "???????????"
 				}
-			} else if opts.UnitSize {
-				size = fmt.Sprintf("%4s", formatBytes(rsize))
 			} else {
-				size = fmt.Sprintf("%11d", rsize)
+				size = opts.sizeColumn(rsize)
 			}
 			props = append(props, size)
 		}
+		if opts.DiskSize {
+			props = append(props, opts.sizeColumn(node.diskUsage()))
+		}
+		if opts.Uncompressed {
+			props = append(props, opts.uncompressedColumn(node))
+		}
+		// Checksum
+		if opts.Checksum != "" {
+			props = append(props, opts.checksumColumn(node))
+		}
+		// Decorators
+		props = append(props, opts.decorations(node)...)
 		// Print properties
 		if len(props) > 0 {
 			fmt.Fprintf(opts.OutFile, "[%s]  ", strings.Join(props, " "))
@@ -311,61 +1082,257 @@ func (node *Node) print(indent string, opts *Options) {
 	} else {
 		name = node.Name()
 	}
+	// Name width
+	if opts.NameWidth > 0 {
+		name = shortenName(name, opts.NameWidth)
+	}
+	// Bidi isolation
+	if opts.BidiIsolate {
+		name = isolateBidi(name)
+	}
 	// Quotes
 	if opts.Quotes {
 		name = fmt.Sprintf("\"%s\"", name)
 	}
 	// Colorize
 	if opts.Colorize {
-		name = opts.color(node, name)
+		name = opts.colorize(node, name)
+	}
+	// Directory slash
+	if opts.DirSlash && node.depth > 0 && node.IsDir() {
+		name += "/"
+	}
+	// Accessible option
+	if opts.Accessible {
+		if label := typeLabel(node); label != "" {
+			name += " (" + label + ")"
+		}
+	}
+	// Icons
+	if opts.Icons {
+		if icon := opts.icon(node); icon != "" {
+			name = icon + " " + name
+		}
+	}
+	// Archive marker
+	if node.archive != "" {
+		name += " [" + node.archive + " archive]"
+	}
+	// Duplicate marker
+	if node.duplicate {
+		name += " [already shown]"
+	}
+	// FileLimit marker
+	if node.limited > 0 {
+		name += fmt.Sprintf(" [%d entries exceeds filelimit, not opening dir]", node.limited)
+	}
+	// Entry count
+	if opts.EntryCount && node.isDir() && node.entries >= 0 {
+		name += entryCount(node.entries)
+	}
+	// File count
+	if opts.FileCount && node.isDir() && node.nodes != nil {
+		name += fileCount(node.nfiles)
+	}
+	// Empty marker
+	if opts.MarkEmpty && node.empty {
+		name += " [empty]"
+	}
+	// Threshold markers
+	if opts.SizeThreshold > 0 || opts.EntryThreshold > 0 {
+		name += opts.thresholdMarker(node)
+	}
+	// Audit markers
+	if node.risks != nil {
+		name += opts.auditMarker(node)
+	}
+	// Exec annotation
+	if s := node.Annotation(); s != "" {
+		name += " [" + s + "]"
+	}
+	// Filesystem type
+	if opts.FsType && node.fsinfo != nil {
+		name = fmt.Sprintf("%s [%s]", name, node.fsinfo.Type)
 	}
 	// IsSymlink
 	if node.Mode()&os.ModeSymlink == os.ModeSymlink {
-		vtarget, err := os.Readlink(node.path)
-		if err != nil {
+		vtarget := node.link
+		if vtarget == "" {
 			vtarget = node.path
 		}
-		targetPath, err := filepath.EvalSymlinks(node.path)
+		targetPath, err := opts.evalSymlinks(node.path)
 		if err != nil {
 			targetPath = vtarget
 		}
 		fi, err := opts.Fs.Stat(targetPath)
+		if opts.BidiIsolate {
+			vtarget = isolateBidi(vtarget)
+		}
 		if opts.Colorize && fi != nil {
 			vtarget = opts.color(&Node{FileInfo: fi, path: vtarget}, vtarget)
 		}
 		name = fmt.Sprintf("%s -> %s", name, vtarget)
-		// Follow symbolic links like directories
-		if opts.FollowLink {
-			path, err := filepath.Abs(targetPath)
-			if err == nil && fi != nil && fi.IsDir() {
-				if _, ok := node.vpaths[filepath.Clean(path)]; !ok {
-					inf := &Node{FileInfo: fi, path: targetPath}
-					inf.vpaths = node.vpaths
-					inf.Visit(opts)
-					node.nodes = inf.nodes
-				} else {
-					name += " [recursive, not followed]"
-				}
-			}
+		if opts.Accessible && node.broken {
+			name += " (broken link)"
+		}
+		if node.recursive {
+			name += " [recursive, not followed]"
 		}
 	}
 	// Print file details
 	// the main idea of the print logic came from here: github.com/campoy/tools/tree
 	fmt.Fprintln(opts.OutFile, name)
-	add := "│   "
-	for i, nnode := range node.nodes {
-		if opts.NoIndent {
-			add = ""
-		} else {
-			if i == len(node.nodes)-1 {
-				fmt.Fprintf(opts.OutFile, indent+"└── ")
-				add = "    "
-			} else {
-				fmt.Fprintf(opts.OutFile, indent+"├── ")
-			}
-		}
-		nnode.print(indent+add, opts)
+	// Info comment
+	if len(node.comment) > 0 {
+		node.printComment(indent, opts)
+	}
+}
+
+// printMore prints the marker of the entries of the node left out by the
+// MaxEntries and MaxLines options, as its last child.
+func (node *Node) printMore(indent string, more int, opts *Options) {
+	lead, _ := opts.connector(indent, node.depth+1, true, opts.lines())
+	fmt.Fprint(opts.OutFile, lead)
+	ellipsis := "…"
+	if strings.ToLower(opts.Charset) == "ascii" {
+		ellipsis = "..."
 	}
+	fmt.Fprintf(opts.OutFile, "%s and %d more\n", ellipsis, more)
+}
+
+// typeLabel returns the type of the node spelled out for the Accessible
+// option, or "" for regular files and symlinks, shown with "->".
+func typeLabel(node *Node) string {
+	mode := node.Mode()
+	switch {
+	case node.IsDir():
+		return "directory"
+	case mode&os.ModeSymlink != 0:
+		return ""
+	case isExecutable(node):
+		return "executable"
+	}
+	switch fileType(mode) {
+	case TypeSocket:
+		return "socket"
+	case TypeFifo:
+		return "named pipe"
+	case TypeCharDevice:
+		return "character device"
+	case TypeBlockDevice:
+		return "block device"
+	}
+	return ""
+}
+
+// entryCount formats the marker of the EntryCount option.
+func entryCount(n int) string {
+	if n == 1 {
+		return " [1 entry]"
+	}
+	return fmt.Sprintf(" [%d entries]", n)
+}
+
+// fileCount formats the marker of the FileCount option.
+func fileCount(n int) string {
+	if n == 1 {
+		return " [1 file]"
+	}
+	return fmt.Sprintf(" [%d files]", n)
+}
+
+// visitKey identifies the directory fi at path, for the cycle detection
+// of the FollowLink option: by its device and inode numbers if they're
+// available, so bind mounts and case-insensitive paths can't make the
+// same directory look new, or by its absolute path otherwise.
+func visitKey(path string, fi os.FileInfo) string {
+	if ok, inode, device, _, _ := getStat(fi); ok && inode != 0 {
+		return fmt.Sprintf("%d:%d", device, inode)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return filepath.Clean(path)
+}
+
+// DefaultRecursionLimit is the default of the RecursionLimit option.
+const DefaultRecursionLimit = 10000
+
+func (opts *Options) recursionLimit() int {
+	if opts.RecursionLimit > 0 {
+		return opts.RecursionLimit
+	}
+	return DefaultRecursionLimit
+}
+
+// timeFormat returns the layout of the TimeFormat option.
+func (opts *Options) timeFormat() string {
+	if opts.TimeFormat != "" {
+		return opts.TimeFormat
+	}
+	return "Jan 02 15:04"
+}
+
+// indentLines are the lines drawn before the entries of a directory.
+type indentLines struct {
+	branch, last, vertical string
+}
+
+// charsets are the indentLines of the supported Charset options.
+var charsets = map[string]indentLines{
+	"utf-8": {"├── ", "└── ", "│   "},
+	"ascii": {"|-- ", "`-- ", "|   "},
+}
+
+// lines returns the indentation lines of the Charset and IndentWidth
+// options.
+func (opts *Options) lines() indentLines {
+	lines, ok := charsets[strings.ToLower(opts.Charset)]
+	if !ok {
+		lines = charsets["utf-8"]
+	}
+	if w := opts.IndentWidth; w > 0 {
+		lines = indentLines{resizeLine(lines.branch, w), resizeLine(lines.last, w), resizeLine(lines.vertical, w)}
+	}
+	return lines
+}
+
+// resizeLine resizes an indentation line to the given width, by repeating
+// its second character, e.g. "├── " to "├───── " for 7.
+func resizeLine(line string, width int) string {
+	r := []rune(line)
+	return string(r[0]) + strings.Repeat(string(r[1]), width-2) + " "
+}
+
+// shortenName shortens name to width runes by replacing its middle with
+// an ellipsis. The tail keeps at least the extension of name, if it fits.
+func shortenName(name string, width int) string {
+	r := []rune(name)
+	if len(r) <= width {
+		return name
+	}
+	avail := width - 1
+	tail := avail / 2
+	if ext := utf8.RuneCountInString(filepath.Ext(name)); ext > tail && ext < avail {
+		tail = ext
+	}
+	return string(r[:avail-tail]) + "…" + string(r[len(r)-tail:])
+}
+
+func lookupUser(uid string) (string, error) {
+	u, err := user.LookupId(uid)
+	if err != nil {
+		return "", err
+	}
+	return u.Username, nil
+}
+
+func lookupGroup(gid string) (string, error) {
+	g, err := user.LookupGroupId(gid)
+	if err != nil {
+		return "", err
+	}
+	return g.Name, nil
 }
 
 const (
@@ -379,36 +1346,93 @@ const (
 )
 
 // Convert bytes to human readable string. Like a 2 MB, 64.2 KB, 52 B
-func formatBytes(i int64) (result string) {
-	var n float64
-	sFmt, eFmt := "%.01f", ""
+func formatBytes(i int64) string {
+	return formatSize(i, "", 0)
+}
+
+// sizeUnits are the suffix styles of the human readable sizes.
+var sizeUnits = map[string]struct {
+	base     float64
+	suffixes []string
+}{
+	"":    {1024, []string{"K", "M", "G", "T", "P", "E"}},
+	"iec": {1024, []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}},
+	"si":  {1000, []string{"kB", "MB", "GB", "TB", "PB", "EB"}},
+}
+
+// formatSize formats a size in the given units style, see the SizeUnits
+// and SizePrecision options.
+func formatSize(i int64, units string, precision int) string {
+	style := sizeUnits[units]
+	n, suffix := float64(i), ""
+	for _, s := range style.suffixes {
+		if n <= style.base {
+			break
+		}
+		n, suffix = n/style.base, s
+	}
+	decimals := 0
 	switch {
-	case i > EB:
-		eFmt = "E"
-		n = float64(i) / float64(EB)
-	case i > PB:
-		eFmt = "P"
-		n = float64(i) / float64(PB)
-	case i > TB:
-		eFmt = "T"
-		n = float64(i) / float64(TB)
-	case i > GB:
-		eFmt = "G"
-		n = float64(i) / float64(GB)
-	case i > MB:
-		eFmt = "M"
-		n = float64(i) / float64(MB)
-	case i > KB:
-		eFmt = "K"
-		n = float64(i) / float64(KB)
-	default:
-		sFmt = "%.0f"
-		n = float64(i)
+	case suffix == "":
+	case precision > 0:
+		decimals = precision
+	case precision == 0 && n < 10:
+		decimals = 1
 	}
-	if eFmt != "" && n >= 10 {
-		sFmt = "%.0f"
+	return strconv.FormatFloat(n, 'f', decimals, 64) + suffix
+}
+
+// sizeWidth returns the width of the human readable sizes column.
+func (opts *Options) sizeWidth() int {
+	w := 3 + len(sizeUnits[opts.SizeUnits].suffixes[0])
+	if opts.SizePrecision > 0 {
+		w += 1 + opts.SizePrecision
 	}
-	result = fmt.Sprintf(sFmt+eFmt, n)
-	result = strings.Trim(result, " ")
-	return
+	return w
+}
+
+// sizeColumn formats a size for the size columns, see ByteSize and
+// UnitSize.
+func (opts *Options) sizeColumn(i int64) string {
+	if opts.UnitSize {
+		return opts.humanSize(i)
+	}
+	return fmt.Sprintf("%11d", i)
+}
+
+// humanSize formats a size for the UnitSize column.
+func (opts *Options) humanSize(i int64) string {
+	return fmt.Sprintf("%*s", opts.sizeWidth(), formatSize(i, opts.SizeUnits, opts.SizePrecision))
+}
+
+// ParseSize parses a size in bytes with an optional K, M, G, T, P or E
+// suffix, e.g. "100M".
+func ParseSize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	var unit int64 = 1
+	num := s
+	switch strings.ToUpper(s[len(s)-1:]) {
+	case "K":
+		unit = KB
+	case "M":
+		unit = MB
+	case "G":
+		unit = GB
+	case "T":
+		unit = TB
+	case "P":
+		unit = PB
+	case "E":
+		unit = EB
+	}
+	if unit != 1 {
+		num = s[:len(s)-1]
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size '%s'", s)
+	}
+	return int64(n * float64(unit)), nil
 }
@@ -1,7 +1,17 @@
 package tree
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
@@ -15,6 +25,7 @@ type file struct {
 	lastMod time.Time
 	stat    interface{}
 	mode    os.FileMode
+	content string
 }
 
 func (f file) Name() string { return f.name }
@@ -73,6 +84,21 @@ func (fs *MockFs) ReadDir(path string) ([]string, error) {
 	return names, nil
 }
 
+func (fs *MockFs) Open(path string) (io.ReadCloser, error) {
+	f, ok := fs.files[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return ioutil.NopCloser(strings.NewReader(f.content)), nil
+}
+
+func (fs *MockFs) Statfs(path string) (*FsInfo, error) {
+	if st, ok := fs.files[path].Sys().(*syscall.Stat_t); ok && st.Dev != 0 {
+		return &FsInfo{Type: "tmpfs", Total: uint64(2 * MB), Free: uint64(2 * MB), Avail: uint64(2 * MB)}, nil
+	}
+	return &FsInfo{Type: "ext4", Total: uint64(100 * GB), Free: uint64(60 * GB), Avail: uint64(55 * GB)}, nil
+}
+
 // Mock output file
 type Out struct {
 	str string
@@ -97,6 +123,23 @@ var (
 	out = new(Out)
 )
 
+// checkTests visits and prints the given tests, and checks both their
+// output and counts.
+func checkTests(t *testing.T, tests []treeTest) {
+	for _, test := range tests {
+		inf := New("root")
+		d, f := inf.Visit(test.opts)
+		if d != test.dirs || f != test.files {
+			t.Errorf("wrong count for test %q:\ngot:\n%d, %d\nexpected:\n%d, %d", test.name, d, f, test.dirs, test.files)
+		}
+		inf.Print(test.opts)
+		if !out.equal(test.expected) {
+			t.Errorf("%s:\ngot:\n%+v\nexpected:\n%+v", test.name, out.str, test.expected)
+		}
+		out.clear()
+	}
+}
+
 type treeTest struct {
 	name     string
 	opts     *Options // test params.
@@ -121,6 +164,26 @@ var listTests = []treeTest{
     ├── e
     └── .f
 `, 1, 5},
+	{"charset-ascii", &Options{Fs: fs, OutFile: out, Charset: "ASCII"}, "root\n|-- a\n|-- b\n`-- c\n    |-- d\n    `-- e\n", 1, 4},
+	{"indent-width", &Options{Fs: fs, OutFile: out, IndentWidth: 2}, "root\n├ a\n├ b\n└ c\n  ├ d\n  └ e\n", 1, 4},
+	{"indent-width-ascii", &Options{Fs: fs, OutFile: out, Charset: "ascii", IndentWidth: 6}, "root\n|---- a\n|---- b\n`---- c\n      |---- d\n      `---- e\n", 1, 4},
+	{"hidden", &Options{Fs: fs, OutFile: out, HiddenOnly: true}, `root
+└── c
+    └── .f
+`, 1, 1},
+	{"filter", &Options{Fs: fs, OutFile: out, Filter: func(n *Node) bool {
+		return n.Name() != "b" && n.Name() != "e"
+	}}, `root
+├── a
+└── c
+    └── d
+`, 1, 2},
+	{"filter-dirs", &Options{Fs: fs, OutFile: out, Filter: func(n *Node) bool {
+		return n.Depth() == 1 && !n.IsDir()
+	}}, `root
+├── a
+└── b
+`, 0, 2},
 	{"dirs", &Options{Fs: fs, OutFile: out, DirsOnly: true}, `root
 └── c
 `, 1, 0},
@@ -141,6 +204,47 @@ var listTests = []treeTest{
 └── c
     └── e
 `, 1, 2},
+	{"pattern-path", &Options{Fs: fs, OutFile: out, Pattern: "^c/", MatchPath: true}, `root
+└── c
+    ├── d
+    └── e
+`, 1, 2},
+	{"ipattern-path", &Options{Fs: fs, OutFile: out, IPattern: "^c/d$", MatchPath: true}, `root
+├── a
+├── b
+└── c
+    └── e
+`, 1, 3},
+	{"glob", &Options{Fs: fs, OutFile: out, Pattern: "[ae]|d", Glob: true}, `root
+├── a
+└── c
+    ├── d
+    └── e
+`, 1, 3},
+	{"glob-path", &Options{Fs: fs, OutFile: out, Pattern: "**/e", Glob: true, MatchPath: true}, `root
+└── c
+    └── e
+`, 1, 1},
+	{"glob-negate", &Options{Fs: fs, OutFile: out, IPattern: "[a-d]|!b", Glob: true}, `root
+├── b
+└── c
+    └── e
+`, 1, 2},
+	{"pattern-negate", &Options{Fs: fs, OutFile: out, Pattern: "[a-e]|!(b|d)"}, `root
+├── a
+└── c
+    └── e
+`, 1, 2},
+	{"filter-depth", &Options{Fs: fs, OutFile: out, IPattern: "(a|e)", FilterMinDepth: 2}, `root
+├── a
+├── b
+└── c
+    └── d
+`, 1, 3},
+	{"prune", &Options{Fs: fs, OutFile: out, Pattern: "(a|b)", Prune: true}, `root
+├── a
+└── b
+`, 0, 2},
 	{"ipattern", &Options{Fs: fs, OutFile: out, IPattern: "(a|e)"}, `root
 ├── b
 └── c
@@ -187,7 +291,46 @@ func TestSimple(t *testing.T) {
 	}
 }
 
+func TestSizeFilter(t *testing.T) {
+	root := &file{
+		name: "root",
+		size: 200,
+		files: []*file{
+			{name: "a", size: 10},
+			{name: "b", size: 50},
+			{
+				name: "c",
+				size: 100,
+				files: []*file{
+					{name: "d", size: 100},
+					{name: "e", size: 50},
+				},
+			},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"min-size", &Options{Fs: fs, OutFile: out, MinSize: 50}, `root
+├── b
+└── c
+    ├── d
+    └── e
+`, 1, 3},
+		{"max-size", &Options{Fs: fs, OutFile: out, MaxSize: 50}, `root
+├── a
+├── b
+└── c
+    └── e
+`, 1, 3}})
+}
+
 var sortTests = []treeTest{
+	{"default-sort", &Options{Fs: fs, OutFile: out}, `root
+├── a
+├── b
+└── c
+    └── d
+`, 1, 3},
 	{"name-sort", &Options{Fs: fs, OutFile: out, NameSort: true}, `root
 ├── a
 ├── b
@@ -197,8 +340,8 @@ var sortTests = []treeTest{
 	{"dirs-first sort", &Options{Fs: fs, OutFile: out, DirSort: true}, `root
 ├── c
 │   └── d
-├── b
-└── a
+├── a
+└── b
 `, 1, 3},
 	{"reverse sort", &Options{Fs: fs, OutFile: out, ReverSort: true, DirSort: true}, `root
 ├── b
@@ -225,10 +368,22 @@ var sortTests = []treeTest{
     └── d
 `, 1, 3},
 	{"c-time-sort", &Options{Fs: fs, OutFile: out, CTimeSort: true}, `root
+├── a
+├── b
+└── c
+    └── d
+`, 1, 3},
+	{"dirs-first size-sort", &Options{Fs: fs, OutFile: out, DirSort: true, SizeSort: true, ReverSort: true}, `root
 ├── b
+├── a
+└── c
+    └── d
+`, 1, 3},
+	{"dirs-first last-mod-sort", &Options{Fs: fs, OutFile: out, DirSort: true, ModSort: true}, `root
 ├── c
 │   └── d
-└── a
+├── a
+└── b
 `, 1, 3}}
 
 func TestSort(t *testing.T) {
@@ -257,11 +412,52 @@ func TestSort(t *testing.T) {
 	}
 }
 
+func TestTimeRange(t *testing.T) {
+	tFmt := "2006-Jan-02"
+	aTime, _ := time.Parse(tFmt, "2015-Aug-01")
+	bTime, _ := time.Parse(tFmt, "2015-Sep-01")
+	cTime, _ := time.Parse(tFmt, "2015-Oct-01")
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a", lastMod: aTime},
+			{name: "b", lastMod: bTime},
+			{name: "c", files: []*file{{name: "d", lastMod: cTime}}, lastMod: cTime},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"newer-than", &Options{Fs: fs, OutFile: out, NewerThan: aTime}, `root
+├── b
+└── c
+    └── d
+`, 1, 2},
+		{"older-than", &Options{Fs: fs, OutFile: out, OlderThan: cTime}, `root
+├── a
+├── b
+└── c
+`, 1, 2},
+		{"time-range", &Options{Fs: fs, OutFile: out, NewerThan: aTime, OlderThan: cTime}, `root
+├── b
+└── c
+`, 1, 1}})
+}
+
 var graphicTests = []treeTest{
 	{"no-indent", &Options{Fs: fs, OutFile: out, NoIndent: true}, `root
 a
 b
 c
+`, 0, 3},
+	{"depth-prefix", &Options{Fs: fs, OutFile: out, DepthPrefix: true, NoIndent: true}, `0 root
+1 a
+1 b
+1 c
+`, 0, 3},
+	{"icons", &Options{Fs: fs, OutFile: out, Icons: true}, `📁 root
+├── 📄 a
+├── ⚡ b
+└── 📄 c
 `, 0, 3},
 	{"quotes", &Options{Fs: fs, OutFile: out, Quotes: true}, `"root"
 ├── "a"
@@ -277,12 +473,36 @@ c
 ├── [1.5K]  a
 ├── [9.8K]  b
 └── [1000]  c
+`, 0, 3},
+	{"unit-size-iec", &Options{Fs: fs, OutFile: out, UnitSize: true, SizeUnits: "iec"}, `[ 12KiB]  root
+├── [1.5KiB]  a
+├── [9.8KiB]  b
+└── [  1000]  c
+`, 0, 3},
+	{"unit-size-si", &Options{Fs: fs, OutFile: out, UnitSize: true, SizeUnits: "si", SizePrecision: 2}, `[ 12.50kB]  root
+├── [  1.50kB]  a
+├── [ 10.00kB]  b
+└── [    1000]  c
 `, 0, 3},
 	{"show-gid", &Options{Fs: fs, OutFile: out, ShowGid: true}, `root
 ├── [1   ]  a
 ├── [2   ]  b
 └── [1   ]  c
 `, 0, 3},
+	{"executable", &Options{Fs: fs, OutFile: out, ExecOnly: true}, `root
+└── b
+`, 0, 1},
+	{"perm-exact", &Options{Fs: fs, OutFile: out, Perm: 0644}, `root
+└── a
+`, 0, 1},
+	{"perm-all", &Options{Fs: fs, OutFile: out, Perm: 0044, PermMatch: PermAll}, `root
+├── a
+├── b
+└── c
+`, 0, 3},
+	{"perm-any", &Options{Fs: fs, OutFile: out, Perm: 0111, PermMatch: PermAny}, `root
+└── b
+`, 0, 1},
 	{"mode", &Options{Fs: fs, OutFile: out, FileMode: true}, `root
 ├── [-rw-r--r--]  a
 ├── [-rwxr-xr-x]  b
@@ -321,12 +541,34 @@ func TestGraphics(t *testing.T) {
 	}
 }
 
+func TestOwnerFilter(t *testing.T) {
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a", stat: &syscall.Stat_t{Gid: 1, Mode: 0644}},
+			{name: "b", stat: &syscall.Stat_t{Uid: 7, Gid: 2, Mode: 0755}},
+			{name: "c", stat: &syscall.Stat_t{Gid: 1, Mode: 0666}},
+		},
+		stat: &syscall.Stat_t{Gid: 1},
+	}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"group", &Options{Fs: fs, OutFile: out, Group: "1"}, `root
+├── a
+└── c
+`, 0, 2},
+		{"owner", &Options{Fs: fs, OutFile: out, Owner: "7", ShowGid: true}, `root
+└── [2   ]  b
+`, 0, 1},
+		{"owner-name", &Options{Fs: fs, OutFile: out, Owner: "root"}, `root
+├── a
+└── c
+`, 0, 2}})
+}
+
 var symlinkTests = []treeTest{
 	{"symlink", &Options{Fs: fs, OutFile: out}, `root
 └── symlink -> root/symlink
-`, 0, 1},
-	{"symlink-rec", &Options{Fs: fs, OutFile: out, FollowLink: true}, `root
-└── symlink -> root/symlink [recursive, not followed]
 `, 0, 1}}
 
 func TestSymlink(t *testing.T) {
@@ -348,6 +590,145 @@ func TestSymlink(t *testing.T) {
 	}
 }
 
+func TestFsType(t *testing.T) {
+	defer out.clear()
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a", files: []*file{{name: "b"}}},
+			{name: "tmp", files: []*file{{name: "c"}}, stat: &syscall.Stat_t{Dev: 1}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	opts := &Options{Fs: fs, OutFile: out, FsType: true}
+	inf := New(root.name)
+	inf.Visit(opts)
+	inf.Print(opts)
+	expected := `root [ext4]
+├── a
+│   └── b
+└── tmp [tmpfs]
+    └── c
+`
+	if !out.equal(expected) {
+		t.Errorf("fstype:\ngot:\n%+v\nexpected:\n%+v", out.str, expected)
+	}
+	out.clear()
+	FprintMounts(out, inf.Mounts())
+	expected = `Type      Size  Used Avail  Mounted on
+ext4      100G   40G   55G  root
+tmpfs     2.0M     0  2.0M  root/tmp
+`
+	if !out.equal(expected) {
+		t.Errorf("mounts:\ngot:\n%+v\nexpected:\n%+v", out.str, expected)
+	}
+}
+
+func TestTypes(t *testing.T) {
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a"},
+			{name: "b", mode: os.ModeSymlink},
+			{name: "c", files: []*file{{name: "d", mode: os.ModeSocket}, {name: "e", mode: os.ModeNamedPipe}}},
+			{name: "f", mode: os.ModeDevice | os.ModeCharDevice},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"regular", &Options{Fs: fs, OutFile: out, Types: TypeFile}, `root
+├── a
+└── c
+`, 1, 1},
+		{"symlink", &Options{Fs: fs, OutFile: out, Types: TypeSymlink}, `root
+├── b -> root/b
+└── c
+`, 1, 1},
+		{"special", &Options{Fs: fs, OutFile: out, Types: TypeSpecial}, `root
+├── c
+│   ├── d
+│   └── e
+└── f
+`, 1, 3},
+		{"accessible", &Options{Fs: fs, OutFile: out, Accessible: true}, `level 0: root (directory)
+level 1: a
+level 1: b -> root/b (broken link)
+level 1: c (directory)
+level 2: d (socket)
+level 2: e (named pipe)
+level 1: f (character device)
+`, 1, 5}})
+}
+
+func TestEmpty(t *testing.T) {
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a", size: 10, mode: 0644},
+			{name: "b", mode: 0644},
+			{name: "c", files: []*file{{name: "d", files: []*file{}}, {name: "e", size: 1, mode: 0644}}},
+			{name: "f", files: []*file{{name: "g", size: 1, mode: 0644}}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"mark-empty", &Options{Fs: fs, OutFile: out, MarkEmpty: true}, `root
+├── a
+├── b [empty]
+├── c
+│   ├── d [empty]
+│   └── e
+└── f
+    └── g
+`, 3, 4},
+		{"dir-slash", &Options{Fs: fs, OutFile: out, DirSlash: true, MarkEmpty: true}, `root
+├── a
+├── b [empty]
+├── c/
+│   ├── d/ [empty]
+│   └── e
+└── f/
+    └── g
+`, 3, 4},
+		{"empty-only", &Options{Fs: fs, OutFile: out, EmptyOnly: true}, `root
+├── b
+└── c
+    └── d
+`, 2, 1},
+		{"entry-count", &Options{Fs: fs, OutFile: out, EntryCount: true, DeepLevel: 1}, `root [4 entries]
+├── a
+├── b
+├── c [2 entries]
+└── f [1 entry]
+`, 2, 2},
+		{"file-count", &Options{Fs: fs, OutFile: out, FileCount: true}, `root [4 files]
+├── a
+├── b
+├── c [1 file]
+│   ├── d [0 files]
+│   └── e
+└── f [1 file]
+    └── g
+`, 3, 4},
+		{"max-entries", &Options{Fs: fs, OutFile: out, MaxEntries: 1}, `root
+├── a
+└── … and 3 more
+`, 3, 4},
+		{"max-lines", &Options{Fs: fs, OutFile: out, MaxLines: 5}, `root
+├── a
+├── b
+├── c
+│   ├── d
+│   └── … and 1 more
+└── … and 1 more
+`, 3, 4},
+		{"max-lines-ascii", &Options{Fs: fs, OutFile: out, MaxLines: 4, MaxEntries: 3, Charset: "ascii"}, `root
+|-- a
+|-- b
+|-- c
+|   ` + "`-- ... and 2 more\n`-- ... and 1 more\n", 3, 4}})
+}
+
 func TestCount(t *testing.T) {
 	defer out.clear()
 	root := &file{
@@ -391,8 +772,546 @@ func TestCount(t *testing.T) {
 	opt := &Options{Fs: fs, OutFile: out}
 	inf := New(root.name)
 	d, f := inf.Visit(opt)
+	if sd, sf := inf.Scanned(); sd != d || sf != f {
+		t.Errorf("TestCount - expect scanned count to be equal to (%d, %d), got (%d, %d)", d, f, sd, sf)
+	}
 	if d != 7 || f != 8 {
 		inf.Print(opt)
 		t.Errorf("TestCount - expect (dir, file) count to be equal to (7, 8)\n%s", out.str)
 	}
+	opt = &Options{Fs: fs, OutFile: out, Pattern: "[fgj]", Prune: true}
+	inf = New(root.name)
+	d, f = inf.Visit(opt)
+	if sd, sf := inf.Scanned(); d != 5 || f != 3 || sd != 7 || sf != 8 {
+		t.Errorf("TestCount - expect (dir, file) count to be equal to (5, 3) of (7, 8), got (%d, %d) of (%d, %d)", d, f, sd, sf)
+	}
+}
+
+func TestFileLimit(t *testing.T) {
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a", files: []*file{{name: "b"}, {name: "c"}, {name: "d"}}},
+			{name: "e", files: []*file{{name: "f"}, {name: "g"}}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"filelimit", &Options{Fs: fs, OutFile: out, FileLimit: 2}, `root
+├── a [3 entries exceeds filelimit, not opening dir]
+└── e
+    ├── f
+    └── g
+`, 2, 2},
+		{"filelimit-prune", &Options{Fs: fs, OutFile: out, FileLimit: 2, Prune: true}, `root
+├── a [3 entries exceeds filelimit, not opening dir]
+└── e
+    ├── f
+    └── g
+`, 2, 2}})
+}
+
+func TestPruneUnread(t *testing.T) {
+	root := &file{name: "root", files: []*file{
+		{name: "a", files: []*file{{name: "b", files: []*file{{name: "x.go"}}}}},
+		{name: "c", files: []*file{{name: "y.txt"}}},
+		{name: "e", files: []*file{}},
+		{name: "z"},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"level-prune", &Options{Fs: fs, OutFile: out, DeepLevel: 1, Prune: true}, `root
+├── a
+├── c
+├── e
+└── z
+`, 3, 1},
+		{"level-prune-empty", &Options{Fs: fs, OutFile: out, DeepLevel: 2, Prune: true}, `root
+├── a
+│   └── b
+├── c
+│   └── y.txt
+└── z
+`, 3, 2}})
+}
+
+func TestRecursionLimit(t *testing.T) {
+	root := &file{name: "root", files: []*file{
+		{name: "a", files: []*file{{name: "b", files: []*file{{name: "c", files: []*file{}}}}}},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"recursion-limit", &Options{Fs: fs, OutFile: out, RecursionLimit: 2}, `root
+└── a
+    └── root/a/b [recursion limit reached]
+`, 2, 0}})
+}
+
+func TestCollapseDuplicates(t *testing.T) {
+	bind := &syscall.Stat_t{Dev: 1, Ino: 2}
+	root := &file{name: "root", files: []*file{
+		{name: "a", stat: bind, files: []*file{{name: "b", size: 10}}},
+		{name: "c", stat: bind, files: []*file{{name: "b", size: 10}}},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"duplicates", &Options{Fs: fs, OutFile: out, ByteSize: true}, `[         20]  root
+├── [         10]  a
+│   └── [         10]  b
+└── [         10]  c
+    └── [         10]  b
+`, 2, 2},
+		{"collapse-duplicates", &Options{Fs: fs, OutFile: out, ByteSize: true, CollapseDuplicates: true}, `[         10]  root
+├── [         10]  a
+│   └── [         10]  b
+└── [          0]  c [already shown]
+`, 2, 1},
+		{"collapse-duplicates-prune", &Options{Fs: fs, OutFile: out, CollapseDuplicates: true, Prune: true}, `root
+├── a
+│   └── b
+└── c [already shown]
+`, 2, 1}})
+}
+
+func TestParallelSort(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(3))
+	var nodes, expected Nodes
+	for i := 0; i < 3*parallelSortMin; i++ {
+		n := &Node{FileInfo: &file{name: fmt.Sprintf("f%d", (i*7919)%(3*parallelSortMin))}}
+		nodes, expected = append(nodes, n), append(expected, n)
+	}
+	less := ByFunc{Fn: NameSort}.less
+	sort.Sort(nodeSorter{expected, less})
+	sortNodes(nodes, less)
+	for i := range nodes {
+		if nodes[i] != expected[i] {
+			t.Fatalf("wrong order at %d: got %s, expected %s", i, nodes[i].Name(), expected[i].Name())
+		}
+	}
+}
+
+func TestFoldSort(t *testing.T) {
+	root := &file{name: "root", files: []*file{
+		{name: "Zebra"}, {name: ".bashrc"}, {name: "apple"}, {name: "Apple"}, {name: "cat"},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"name-sort", &Options{Fs: fs, OutFile: out, All: true}, `root
+├── .bashrc
+├── Apple
+├── Zebra
+├── apple
+└── cat
+`, 0, 5},
+		{"fold-sort", &Options{Fs: fs, OutFile: out, All: true, FoldSort: true}, `root
+├── .bashrc
+├── Apple
+├── apple
+├── cat
+└── Zebra
+`, 0, 5},
+		{"dotless-fold-sort", &Options{Fs: fs, OutFile: out, All: true, FoldSort: true, DotlessSort: true}, `root
+├── Apple
+├── apple
+├── .bashrc
+├── cat
+└── Zebra
+`, 0, 5}})
+}
+
+func TestExtSort(t *testing.T) {
+	root := &file{name: "root", files: []*file{
+		{name: "main.go"}, {name: "README"}, {name: ".bashrc"}, {name: "a.tar.gz"},
+		{name: "Makefile"}, {name: "go.mod"}, {name: "b.gz"}, {name: "api.go"},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"ext-sort", &Options{Fs: fs, OutFile: out, All: true, ExtSort: true}, `root
+├── .bashrc
+├── Makefile
+├── README
+├── api.go
+├── main.go
+├── a.tar.gz
+├── b.gz
+└── go.mod
+`, 0, 8}})
+}
+
+func TestHiddenFiles(t *testing.T) {
+	root := &file{name: "root", files: []*file{
+		{name: ".hidden", content: "build\r\nnotes.txt\n\ntmp/\n"},
+		{name: "build", files: []*file{{name: "out"}}},
+		{name: "main.go"},
+		{name: "notes.txt"},
+		{name: "tmp", files: []*file{}},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"hidden-files", &Options{Fs: fs, OutFile: out, HiddenFiles: true}, `root
+└── main.go
+`, 0, 1},
+		{"hidden-files-all", &Options{Fs: fs, OutFile: out, HiddenFiles: true, All: true}, `root
+├── .hidden
+├── build
+│   └── out
+├── main.go
+├── notes.txt
+└── tmp
+`, 2, 4},
+		{"hidden-files-only", &Options{Fs: fs, OutFile: out, HiddenFiles: true, HiddenOnly: true}, `root
+├── .hidden
+├── build
+│   └── out
+├── notes.txt
+└── tmp
+`, 2, 3}})
+}
+
+func TestDiskUsage(t *testing.T) {
+	root := &file{name: "root", stat: &syscall.Stat_t{Blocks: 8}, files: []*file{
+		{name: "a", size: 100, stat: &syscall.Stat_t{Blocks: 8}},
+		{name: "b", stat: &syscall.Stat_t{Blocks: 8}, files: []*file{
+			{name: "c", size: 2 << 20, stat: &syscall.Stat_t{Blocks: 1}},
+		}},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"blocks", &Options{Fs: fs, OutFile: out, Blocks: true}, `[      13]  root
+├── [       4]  a
+└── [       5]  b
+    └── [       1]  c
+`, 1, 2},
+		{"disk-size", &Options{Fs: fs, OutFile: out, DiskSize: true}, `[    2097252       12800]  root
+├── [        100        4096]  a
+└── [    2097152        4608]  b
+    └── [    2097152         512]  c
+`, 1, 2},
+		{"disk-size-human", &Options{Fs: fs, OutFile: out, DiskSize: true, UnitSize: true}, `[2.0M  12K]  root
+├── [ 100 4.0K]  a
+└── [2.0M 4.5K]  b
+    └── [2.0M  512]  c
+`, 1, 2}})
+}
+
+func TestDecorators(t *testing.T) {
+	root := &file{name: "root", files: []*file{
+		{name: "a", size: 10},
+		{name: "b", files: []*file{{name: "c", size: 200}}},
+	}}
+	fs.clean().addFile(root.name, root)
+	kind := Decorator{Name: "kind", Width: 4, Fn: func(n *Node) string {
+		if n.IsDir() {
+			return "dir"
+		}
+		return "file"
+	}}
+	digits := Decorator{Name: "digits", Parallel: true, Fn: func(n *Node) string {
+		return strconv.Itoa(len(strconv.FormatInt(n.TotalSize(), 10)))
+	}}
+	checkTests(t, []treeTest{
+		{"decorators", &Options{Fs: fs, OutFile: out, Decorators: []Decorator{kind, digits}}, `[dir  3]  root
+├── [file 2]  a
+└── [dir  3]  b
+    └── [file 3]  c
+`, 1, 2}})
+}
+
+func TestInfo(t *testing.T) {
+	root := &file{name: "root", files: []*file{
+		{name: ".info", content: "# comments\n*.go\n\tGo source.\nb\n\tThe b directory,\n\twith a two lines comment.\nb/d\n\tOverridden.\n"},
+		{name: "a.go"},
+		{name: "b", files: []*file{
+			{name: ".info", content: "d\n\tThe d file.\n"},
+			{name: "c.go"},
+			{name: "d"},
+		}},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"info", &Options{Fs: fs, OutFile: out, Info: true}, `root
+├── a.go
+│   { Go source.
+└── b
+    ⎧ The b directory,
+    ⎩ with a two lines comment.
+    ├── c.go
+    │   { Go source.
+    └── d
+        { The d file.
+`, 1, 3}})
+}
+
+func TestIconSet(t *testing.T) {
+	for _, test := range []struct {
+		file     *file
+		expected string
+	}{
+		{&file{name: "dir", files: []*file{}}, "📁"},
+		{&file{name: "main.go"}, "🐹"},
+		{&file{name: "PHOTO.JPG"}, "🖼️"},
+		{&file{name: "Makefile"}, "🔨"},
+		{&file{name: "link", mode: os.ModeSymlink}, "🔗"},
+		{&file{name: "sock", mode: os.ModeSocket}, "🔌"},
+		{&file{name: "run", mode: 0755}, "⚡"},
+		{&file{name: "notes"}, "📄"},
+	} {
+		if icon := EmojiIcons.Icon(&Node{FileInfo: test.file}); icon != test.expected {
+			t.Errorf("icon of %s: got %s, expected %s", test.file.name, icon, test.expected)
+		}
+	}
+}
+
+func TestParseIcons(t *testing.T) {
+	set, err := ParseIcons(NerdIcons, "di=D:*.GO=G:Makefile=M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		file     *file
+		expected string
+	}{
+		{&file{name: "dir", files: []*file{}}, "D"},
+		{&file{name: "main.go"}, "G"},
+		{&file{name: "Makefile"}, "M"},
+		{&file{name: "main.rs"}, NerdIcons.Exts[".rs"]},
+	} {
+		if icon := set.Icon(&Node{FileInfo: test.file}); icon != test.expected {
+			t.Errorf("icon of %s: got %q, expected %q", test.file.name, icon, test.expected)
+		}
+	}
+	if NerdIcons.Exts[".go"] == "G" {
+		t.Error("ParseIcons changed its base set")
+	}
+}
+
+func TestBidiIsolate(t *testing.T) {
+	root := &file{name: "root", files: []*file{{name: "a.txt"}, {name: "שלום.txt"}, {name: "مرحبا"}}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"bidi-isolate", &Options{Fs: fs, OutFile: out, BidiIsolate: true}, "root\n├── a.txt\n├── ⁨שלום.txt⁩\n└── ⁨مرحبا⁩\n", 0, 3}})
+}
+
+func TestShortenName(t *testing.T) {
+	tests := []struct {
+		name  string
+		width int
+		want  string
+	}{
+		{"short.go", 20, "short.go"},
+		{"verylongname.tar.gz", 19, "verylongname.tar.gz"},
+		{"verylongfilename.tar.gz", 20, "verylongfi…me.tar.gz"},
+		{"averyverylongname.extension", 12, "a….extension"},
+		{"abcdefghij", 5, "ab…ij"},
+		{"noextension.averylongextension", 8, "noex…ion"},
+	}
+	for _, test := range tests {
+		if got := shortenName(test.name, test.width); got != test.want {
+			t.Errorf("shortenName(%q, %d) = %q, want %q", test.name, test.width, got, test.want)
+		}
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	root := &file{name: "root", files: []*file{
+		{name: "a", size: 5, content: "hello"},
+		{name: "b", files: []*file{{name: "c", size: 5, content: "world"}, {name: "d"}}},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"checksum", &Options{Fs: fs, OutFile: out, Checksum: "md5", HashWorkers: 2}, `[                                ]  root
+├── [5d41402abc4b2a76b9719d911017c592]  a
+└── [                                ]  b
+    ├── [7d793037a0760186574b0282f2f435e7]  c
+    └── [d41d8cd98f00b204e9800998ecf8427e]  d
+`, 1, 3}})
+	// The files that can't be read are walk errors
+	opts := &Options{Fs: unreadableFs{fs}, OutFile: out, Checksum: "md5"}
+	inf := New(root.name)
+	inf.Visit(opts)
+	errs := inf.Errors()
+	if len(errs) != 3 || errs[0].Path != "root/a" || errs[0].Dir || errs[0].Error() != "root/a: permission denied" {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+// unreadableFs is a MockFs whose files can't be opened.
+type unreadableFs struct{ *MockFs }
+
+func (unreadableFs) Open(path string) (io.ReadCloser, error) {
+	return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrPermission}
+}
+
+func TestMtree(t *testing.T) {
+	mod := time.Unix(1546387200, 500)
+	root := &file{name: "root", mode: os.ModeDir | 0755, lastMod: mod, files: []*file{
+		{name: "a b", size: 5, mode: 0644, lastMod: mod, content: "hello"},
+		{name: "bin", mode: os.ModeDir | 0755, lastMod: mod, files: []*file{{name: "su", mode: os.ModeSetuid | 0755, lastMod: mod}}},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"mtree", &Options{Fs: fs, OutFile: out, Mtree: true, Checksum: "md5"}, `#mtree
+. type=dir uid=0 gid=0 mode=0755 time=1546387200.000000500
+./a\040b type=file uid=0 gid=0 mode=0644 size=5 time=1546387200.000000500 md5digest=5d41402abc4b2a76b9719d911017c592
+./bin type=dir uid=0 gid=0 mode=0755 time=1546387200.000000500
+./bin/su type=file uid=0 gid=0 mode=4755 size=0 time=1546387200.000000500 md5digest=d41d8cd98f00b204e9800998ecf8427e
+`, 1, 2}})
+}
+
+func TestMerkle(t *testing.T) {
+	merkle := func(root *file) string {
+		fs.clean().addFile(root.name, root)
+		inf := New("root")
+		inf.Visit(&Options{Fs: fs, OutFile: out, Checksum: "sha1", Merkle: true, ReverSort: true})
+		if sum := inf.nodes[0].Checksum(); inf.nodes[0].IsDir() && sum == "" {
+			t.Error("expect a checksum for the directory")
+		}
+		return inf.Checksum()
+	}
+	a := merkle(&file{name: "root", files: []*file{
+		{name: "a", content: "hello"},
+		{name: "b", files: []*file{{name: "c", content: "world"}}},
+	}})
+	// The same content, listed in another order
+	b := merkle(&file{name: "root", files: []*file{
+		{name: "b", files: []*file{{name: "c", content: "world"}}},
+		{name: "a", content: "hello"},
+	}})
+	c := merkle(&file{name: "root", files: []*file{
+		{name: "a", content: "hello"},
+		{name: "b", files: []*file{{name: "c", content: "World"}}},
+	}})
+	if a == "" || a != b {
+		t.Errorf("expect equal root checksums, got %q and %q", a, b)
+	}
+	if a == c {
+		t.Errorf("expect different root checksums, got %q", c)
+	}
+}
+
+func TestExec(t *testing.T) {
+	root := &file{name: "root", files: []*file{
+		{name: "a.go", content: "package a"},
+		{name: "b", files: []*file{{name: "c.txt", content: "c"}}},
+		{name: "d.bin", content: "x"},
+	}}
+	fs.clean().addFile(root.name, root)
+	var mu sync.Mutex
+	var running, max int
+	exec := func(n *Node) (string, error) {
+		mu.Lock()
+		if running++; running > max {
+			max = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		switch filepath.Ext(n.Name()) {
+		case ".go":
+			return "go source", nil
+		case ".bin":
+			return "", errors.New("unknown type")
+		}
+		return "", nil
+	}
+	checkTests(t, []treeTest{
+		{"exec", &Options{Fs: fs, OutFile: out, Exec: exec, ExecWorkers: 2}, `root
+├── a.go [go source]
+├── b
+│   └── c.txt
+└── d.bin [exec: unknown type]
+`, 1, 3},
+	})
+	if max > 2 {
+		t.Errorf("expect at most 2 concurrent calls, got %d", max)
+	}
+}
+
+// The depth 0 work runs once for a symlinked root, not again for its
+// followed target.
+func TestExecLinkRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "real", "b"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "real", "a"), nil, 0644)
+	ioutil.WriteFile(filepath.Join(dir, "real", "b", "c"), nil, 0644)
+	root := filepath.Join(dir, "root")
+	if err := os.Symlink("real", root); err != nil {
+		t.Skip(err)
+	}
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	opts := &Options{Fs: osFs{}, OutFile: out, FollowLink: true, Exec: func(n *Node) (string, error) {
+		mu.Lock()
+		calls[n.Name()]++
+		mu.Unlock()
+		return "", nil
+	}}
+	inf := New(root)
+	if d, f := inf.Visit(opts); d != 1 || f != 2 {
+		t.Errorf("got %d dirs, %d files, expected 1, 2", d, f)
+	}
+	if len(calls) != 2 || calls["a"] != 1 || calls["c"] != 1 {
+		t.Errorf("expected one Exec call per file, got %v", calls)
+	}
+}
+
+func TestExecCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no sh on windows")
+	}
+	node := &Node{path: "root/a b.go"}
+	for _, test := range []struct {
+		fn   func(*Node) (string, error)
+		want string
+		err  bool
+	}{
+		{ExecCommand("echo", "file:{}"), "file:root/a b.go", false},
+		{ExecCommand("echo", "-n"), "root/a b.go", false},
+		{ExecCommand("sh", "-c", "echo infected; exit 1"), "infected", false},
+		{ExecCommand("sh", "-c", "exit 2"), "", true},
+	} {
+		got, err := test.fn(node)
+		if got != test.want || (err != nil) != test.err {
+			t.Errorf("got %q, %v, expected %q", got, err, test.want)
+		}
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	root := &file{name: "root", files: []*file{
+		{name: "big", files: []*file{{name: "a", size: 2 * MB}, {name: "b", size: 1}}},
+		{name: "many", files: []*file{{name: "c"}, {name: "d"}, {name: "e"}}},
+		{name: "small", files: []*file{{name: "f", size: 1}}},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"threshold", &Options{Fs: fs, OutFile: out, SizeThreshold: MB, EntryThreshold: 2}, `root [over 1024K] [over 2 entries]
+├── big [over 1024K]
+│   ├── a
+│   └── b
+├── many [over 2 entries]
+│   ├── c
+│   ├── d
+│   └── e
+└── small
+    └── f
+`, 3, 6},
+		{"threshold-color", &Options{Fs: fs, OutFile: out, SizeThreshold: 2 * MB, Colorize: true, Color: func(_ *Node, s string) string { return s }}, `root ` + "\x1b[1;33m[over 2.0M]\x1b[0m" + `
+├── big ` + "\x1b[1;33m[over 2.0M]\x1b[0m" + `
+│   ├── a
+│   └── b
+├── many
+│   ├── c
+│   ├── d
+│   └── e
+└── small
+    └── f
+`, 3, 6},
+	})
 }
@@ -0,0 +1,45 @@
+//+build linux
+
+package ostree
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// ReadDirInodes reads a directory, and the inode numbers of its entries,
+// from the raw directory entries, without stat'ing them.
+func (f *FS) ReadDirInodes(path string) ([]string, []uint64, error) {
+	dir, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer dir.Close()
+	var names []string
+	var inodes []uint64
+	buf := make([]byte, 16*1024)
+	for {
+		n, err := syscall.ReadDirent(int(dir.Fd()), buf)
+		if err != nil {
+			return nil, nil, &os.PathError{Op: "readdirent", Path: path, Err: err}
+		}
+		if n <= 0 {
+			return names, inodes, nil
+		}
+		for off := 0; off < n; {
+			d := (*syscall.Dirent)(unsafe.Pointer(&buf[off]))
+			off += int(d.Reclen)
+			name := (*[len(d.Name)]byte)(unsafe.Pointer(&d.Name[0]))[:]
+			if i := bytes.IndexByte(name, 0); i >= 0 {
+				name = name[:i]
+			}
+			if d.Ino == 0 || string(name) == "." || string(name) == ".." {
+				continue
+			}
+			names = append(names, string(name))
+			inodes = append(inodes, d.Ino)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//+build !linux
+
+package ostree
+
+// ReadDirInodes reads a directory. The inode numbers of the entries
+// aren't available on this platform, and are all 0.
+func (f *FS) ReadDirInodes(path string) ([]string, []uint64, error) {
+	names, err := f.ReadDir(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return names, make([]uint64, len(names)), nil
+}
@@ -2,6 +2,7 @@ package ostree
 
 import (
 	"bytes"
+	"io"
 	"os"
 
 	"github.com/a8m/tree"
@@ -41,3 +42,8 @@ func Print(dir string) string {
 	tr.Print(opts)
 	return b.String()
 }
+
+// Open opens a file for reading
+func (f *FS) Open(path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
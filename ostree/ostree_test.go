@@ -1,7 +1,13 @@
 package ostree
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/a8m/tree"
 )
 
 func TestTree(t *testing.T) {
@@ -17,3 +23,183 @@ func TestTree(t *testing.T) {
 		t.Errorf("\nactual\n%s\n != expect\n%s\n", actual, expect)
 	}
 }
+
+func TestBrokenLinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "a"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a", filepath.Join(dir, "b")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	if err := os.Symlink("missing", filepath.Join(dir, "c")); err != nil {
+		t.Fatal(err)
+	}
+	b := new(bytes.Buffer)
+	opts := &tree.Options{Fs: new(FS), OutFile: b, BrokenOnly: true}
+	inf := tree.New(dir)
+	if _, f := inf.Visit(opts); f != 1 {
+		t.Errorf("expect 1 file, got %d", f)
+	}
+	if n := inf.BrokenLinks(); n != 1 {
+		t.Errorf("expect 1 broken link, got %d", n)
+	}
+	inf.Print(opts)
+	expect := dir + "\n└── c -> missing\n"
+	if actual := b.String(); actual != expect {
+		t.Errorf("\nactual\n%s\n != expect\n%s\n", actual, expect)
+	}
+}
+
+func TestRunRoots(t *testing.T) {
+	b := new(bytes.Buffer)
+	opts := &tree.Options{Fs: new(FS), OutFile: b, FsType: true}
+	r := tree.Run([]string{"testdata/a", "testdata/c"}, opts)
+	if r.Dirs != 1 || r.Files != 2 {
+		t.Errorf("expect (1, 2) combined count, got (%d, %d)", r.Dirs, r.Files)
+	}
+	if len(r.Mounts) > 1 {
+		t.Errorf("expect a single mount for both roots, got %d", len(r.Mounts))
+	}
+	for _, root := range []string{"testdata/a", "testdata/c"} {
+		if !bytes.Contains(b.Bytes(), []byte("\n"+root)) && !bytes.HasPrefix(b.Bytes(), []byte(root)) {
+			t.Errorf("expect a tree rooted at %s, got:\n%s", root, b)
+		}
+	}
+}
+
+func TestFollowLinkCycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "a"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("..", filepath.Join(dir, "a", "up")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	b := new(bytes.Buffer)
+	opts := &tree.Options{Fs: new(FS), OutFile: b, FollowLink: true}
+	inf := tree.New(dir)
+	inf.Visit(opts)
+	inf.Print(opts)
+	expect := dir + "\n└── a\n    └── up -> .. [recursive, not followed]\n"
+	if actual := b.String(); actual != expect {
+		t.Errorf("\nactual\n%s\n != expect\n%s\n", actual, expect)
+	}
+}
+
+func TestFollowLinkSibling(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "a"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "a", "f"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("..", filepath.Join(dir, "a", "up")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	if err := os.Symlink("a", filepath.Join(dir, "b")); err != nil {
+		t.Fatal(err)
+	}
+	b := new(bytes.Buffer)
+	opts := &tree.Options{Fs: new(FS), OutFile: b, FollowLink: true}
+	inf := tree.New(dir)
+	inf.Visit(opts)
+	inf.Print(opts)
+	// Only the links to an ancestor aren't followed
+	expect := dir + `
+├── a
+│   ├── f
+│   └── up -> .. [recursive, not followed]
+└── b -> a
+    ├── f
+    └── up -> .. [recursive, not followed]
+`
+	if actual := b.String(); actual != expect {
+		t.Errorf("\nactual\n%s\n != expect\n%s\n", actual, expect)
+	}
+}
+
+func TestFollowLinkCounts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "a", "b", "f"), make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a", filepath.Join(dir, "link")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	opts := &tree.Options{Fs: new(FS), OutFile: new(bytes.Buffer), FollowLink: true}
+	inf := tree.New(dir)
+	if d, f := inf.Visit(opts); d != 4 || f != 2 {
+		t.Errorf("expect 4 dirs and 2 files, got %d and %d", d, f)
+	}
+	if s := inf.TotalSize(); s < 200 {
+		t.Errorf("expect the followed file in the total size, got %d", s)
+	}
+	opts.DeepLevel = 2
+	inf = tree.New(dir)
+	if d, f := inf.Visit(opts); d != 4 || f != 0 {
+		t.Errorf("expect 4 dirs and no files with DeepLevel 2, got %d and %d", d, f)
+	}
+}
+
+func TestReadDirInodes(t *testing.T) {
+	fs := new(FS)
+	names, inodes, err := fs.ReadDirInodes("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || len(inodes) != 2 {
+		t.Fatalf("expect 2 entries, got %v", names)
+	}
+	b := new(bytes.Buffer)
+	opts := &tree.Options{Fs: fs, OutFile: b, InodeOrder: true}
+	inf := tree.New("testdata")
+	inf.Visit(opts)
+	inf.Print(opts)
+	if actual, expect := b.String(), Print("testdata"); actual != expect {
+		t.Errorf("\nactual\n%s\n != expect\n%s\n", actual, expect)
+	}
+}
+
+func TestDereference(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "a"), make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a", filepath.Join(dir, "b")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	b := new(bytes.Buffer)
+	opts := &tree.Options{Fs: new(FS), OutFile: b, ByteSize: true, Dereference: true}
+	inf := tree.New(dir)
+	inf.Visit(opts)
+	inf.Print(opts)
+	expect := "[        200]  " + dir + "\n├── [        100]  a\n└── [        100]  b -> a\n"
+	if actual := b.String(); actual != expect {
+		t.Errorf("\nactual\n%s\n != expect\n%s\n", actual, expect)
+	}
+}
@@ -0,0 +1,31 @@
+//+build darwin freebsd
+
+package ostree
+
+import (
+	"syscall"
+
+	"github.com/a8m/tree"
+)
+
+// Statfs returns the filesystem information of the given path
+func (f *FS) Statfs(path string) (*tree.FsInfo, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return nil, err
+	}
+	var name []byte
+	for _, c := range st.Fstypename {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+	bsize := uint64(st.Bsize)
+	return &tree.FsInfo{
+		Type:  string(name),
+		Total: uint64(st.Blocks) * bsize,
+		Free:  uint64(st.Bfree) * bsize,
+		Avail: uint64(st.Bavail) * bsize,
+	}, nil
+}
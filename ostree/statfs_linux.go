@@ -0,0 +1,65 @@
+//+build linux
+
+package ostree
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/a8m/tree"
+)
+
+// Filesystem magic numbers, see statfs(2).
+var fsTypes = map[int64]string{
+	0x9123683e: "btrfs",
+	0x0027e0eb: "cgroup",
+	0x63677270: "cgroup2",
+	0x28cd3d45: "cramfs",
+	0x64626720: "debugfs",
+	0x1373:     "devfs",
+	0x1cd1:     "devpts",
+	0x137d:     "ext",
+	0xef51:     "ext2",
+	0xef53:     "ext4",
+	0x65735546: "fuse",
+	0x4244:     "hfs",
+	0x9660:     "iso9660",
+	0x3153464a: "jfs",
+	0x19800202: "mqueue",
+	0x4d44:     "msdos",
+	0x6969:     "nfs",
+	0x5346544e: "ntfs",
+	0x794c7630: "overlay",
+	0x9fa0:     "proc",
+	0x52654973: "reiserfs",
+	0x517b:     "smb",
+	0xfe534d42: "smb2",
+	0xff534d42: "cifs",
+	0x73717368: "squashfs",
+	0x62656572: "sysfs",
+	0x01021994: "tmpfs",
+	0x15013346: "udf",
+	0x00011954: "ufs",
+	0x2011bab0: "exfat",
+	0x58465342: "xfs",
+	0x2fc12fc1: "zfs",
+}
+
+// Statfs returns the filesystem information of the given path
+func (f *FS) Statfs(path string) (*tree.FsInfo, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return nil, err
+	}
+	name, ok := fsTypes[int64(st.Type)]
+	if !ok {
+		name = fmt.Sprintf("0x%x", st.Type)
+	}
+	bsize := uint64(st.Bsize)
+	return &tree.FsInfo{
+		Type:  name,
+		Total: uint64(st.Blocks) * bsize,
+		Free:  uint64(st.Bfree) * bsize,
+		Avail: uint64(st.Bavail) * bsize,
+	}, nil
+}
@@ -0,0 +1,14 @@
+//+build !linux,!darwin,!freebsd
+
+package ostree
+
+import (
+	"errors"
+
+	"github.com/a8m/tree"
+)
+
+// Statfs is not supported on this platform
+func (f *FS) Statfs(path string) (*tree.FsInfo, error) {
+	return nil, errors.New("statfs not supported")
+}
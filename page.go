@@ -0,0 +1,82 @@
+package tree
+
+import "fmt"
+
+// pager is the window of the entries printed by PrintPage.
+type pager struct {
+	// next is the display index of the next entry, and [start, end) the
+	// window.
+	next, start, end int
+	// pending prints the ancestors of the next entry that are before the
+	// window, closest last.
+	pending []func()
+}
+
+// PrintPage prints the entries of a visited tree from the offset-th to
+// the (offset+limit-1)-th in display order, the root being the 0th, like
+// Print but without the MaxEntries and MaxLines options. The ancestors of
+// the first one are printed before it for context, so that each page
+// reads as a tree on its own. A zero limit means all the entries from the
+// offset. It returns the number of entries of the tree, to compute the
+// pages, e.g:
+//
+//	total := inf.PrintPage(opts, 0, 50)
+//	for offset := 50; offset < total; offset += 50 {
+//		inf.PrintPage(opts, offset, 50)
+//	}
+func (node *Node) PrintPage(opts *Options, offset, limit int) (total int) {
+	total = node.count()
+	end := total
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	if offset >= end {
+		return total
+	}
+	p := &pager{start: offset, end: end}
+	node.printPage("", opts.linePrefix(node.depth), opts, p)
+	return total
+}
+
+// count returns the number of entries of the visited tree of the node.
+func (node *Node) count() int {
+	n := 1
+	for _, nnode := range node.nodes {
+		n += nnode.count()
+	}
+	return n
+}
+
+// printPage prints the node and its children that are in the window of
+// p, with lead at the start of its line.
+func (node *Node) printPage(indent, lead string, opts *Options, p *pager) {
+	i := p.next
+	p.next++
+	entry := func() {
+		fmt.Fprint(opts.OutFile, lead)
+		node.printEntry(indent, opts)
+	}
+	if i >= p.start {
+		for _, fn := range p.pending {
+			fn()
+		}
+		p.pending = nil
+		entry()
+	} else {
+		n := len(p.pending)
+		p.pending = append(p.pending, entry)
+		defer func() {
+			if len(p.pending) > n {
+				p.pending = p.pending[:n]
+			}
+		}()
+	}
+	lines := opts.lines()
+	for j, nnode := range node.nodes {
+		if p.next >= p.end {
+			return
+		}
+		clead, add := opts.connector(indent, nnode.depth, j == len(node.nodes)-1, lines)
+		nnode.printPage(indent+add, clead, opts, p)
+	}
+}
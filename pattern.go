@@ -0,0 +1,105 @@
+package tree
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// PatternError is the error of an invalid Pattern or IPattern option.
+type PatternError struct {
+	// Option is "Pattern" or "IPattern", or "Find" for Node.Find.
+	Option  string
+	Pattern string
+	Err     error
+}
+
+func (e *PatternError) Error() string {
+	return fmt.Sprintf("invalid %s: %v", e.Option, e.Err)
+}
+
+func (e *PatternError) Unwrap() error { return e.Err }
+
+// patternList is a compiled list of '|' separated patterns. Like in
+// .gitignore, a pattern prefixed with '!' negates the matches of the
+// preceding patterns, and the last matching pattern wins.
+type patternList []struct {
+	re     *regexp.Regexp
+	negate bool
+}
+
+// compilePatterns compiles the given regular expression, or wildcard
+// pattern if glob is set, into a patternList.
+func compilePatterns(pattern string, glob, ignoreCase bool) (patternList, error) {
+	var list patternList
+	for _, p := range splitPatterns(pattern) {
+		negate := len(p) > 1 && p[0] == '!'
+		if negate {
+			p = p[1:]
+		}
+		if glob {
+			p = globToRegexp(p)
+		}
+		if ignoreCase {
+			p = "(?i)" + p
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, struct {
+			re     *regexp.Regexp
+			negate bool
+		}{re, negate})
+	}
+	return list, nil
+}
+
+// match reports whether s matches the list.
+func (l patternList) match(s string) (matched bool) {
+	for _, p := range l {
+		if matched == p.negate && p.re.MatchString(s) {
+			matched = !p.negate
+		}
+	}
+	return
+}
+
+// find returns the location of the match in s, or nil if s doesn't
+// match the list.
+func (l patternList) find(s string) (loc []int) {
+	for _, p := range l {
+		if m := p.re.FindStringIndex(s); m != nil {
+			if p.negate {
+				loc = nil
+			} else if loc == nil {
+				loc = m
+			}
+		}
+	}
+	return
+}
+
+// splitPatterns splits s on the '|' that are not nested in parentheses,
+// brackets or braces, nor escaped.
+func splitPatterns(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth > 0 {
+				depth--
+			}
+		case '|':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
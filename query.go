@@ -0,0 +1,130 @@
+package tree
+
+import "strings"
+
+// Flatten returns the node and all its visited descendants, in display
+// order (parents before their children).
+func (node *Node) Flatten() Nodes {
+	nodes := Nodes{node}
+	for _, nnode := range node.nodes {
+		nodes = append(nodes, nnode.Flatten()...)
+	}
+	return nodes
+}
+
+// Filter returns the nodes for which fn returns true, keeping their
+// order. It doesn't descend the nodes, use Flatten for that, e.g:
+//
+//	large := inf.Flatten().Filter(func(n *tree.Node) bool {
+//		return !n.IsDir() && n.Size() > 1<<20
+//	})
+func (n Nodes) Filter(fn func(*Node) bool) Nodes {
+	var nodes Nodes
+	for _, node := range n {
+		if fn(node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// Prune removes the descendants of a visited node for which fn returns
+// true, with their subtrees, and updates the cumulative sizes and file
+// counts of the directories. fn is called in display order, parents
+// before their children, and not on the descendants of the removed
+// nodes. It returns the number of directories and files left, like
+// Visit. The directory checksums of the Merkle option aren't updated, e.g:
+//
+//	dirs, files := inf.Prune(func(n *tree.Node) bool {
+//		return n.IsDir() && n.TotalSize() < 1<<20
+//	})
+func (node *Node) Prune(fn func(*Node) bool) (dirs, files int) {
+	if node.nodes == nil {
+		return
+	}
+	nodes := make(Nodes, 0, len(node.nodes))
+	if node.isDir() {
+		node.sizeErr = nil
+	}
+	for _, nnode := range node.nodes {
+		if node.isDir() {
+			node.subSize(nnode)
+		}
+		if fn(nnode) {
+			continue
+		}
+		d, f := nnode.Prune(fn)
+		switch {
+		case nnode.FileInfo == nil:
+		case nnode.isDir():
+			d++
+		default:
+			f++
+		}
+		nodes = append(nodes, nnode)
+		if node.isDir() {
+			node.addSize(nnode)
+		}
+		dirs, files = dirs+d, files+f
+	}
+	node.nodes = nodes
+	if node.isDir() {
+		node.nfiles = files
+	}
+	return
+}
+
+// TotalSize returns the size of a file, or the cumulative size of the
+// files listed below a directory. Entries that couldn't be stat'ed have
+// no size.
+func (node *Node) TotalSize() int64 {
+	switch {
+	case node.FileInfo == nil:
+		return 0
+	case node.isDir():
+		return node.size
+	}
+	return node.Size()
+}
+
+// Find returns the nodes of the visited tree whose name matches the
+// regular expression pattern, or the wildcard one if glob is set (see the
+// Pattern and Glob options). Patterns containing a '/' match the paths
+// relative to the root instead, e.g. "src/**/*.go". The nodes are in
+// display order, and their Path is the full one.
+func (node *Node) Find(pattern string, glob bool) (Nodes, error) {
+	list, err := compilePatterns(pattern, glob, false)
+	if err != nil {
+		return nil, &PatternError{"Find", pattern, err}
+	}
+	byPath := strings.Contains(pattern, "/")
+	return node.Flatten().Filter(func(n *Node) bool {
+		if n.FileInfo == nil {
+			return false
+		}
+		if byPath {
+			return list.match(n.relPath())
+		}
+		return list.match(n.Name())
+	}), nil
+}
+
+// SetMeta attaches the value to the node under the given key, for the
+// annotations computed by decorators and custom formatters (hashes, git
+// status...). It's safe for concurrent use.
+func (node *Node) SetMeta(key string, value interface{}) {
+	node.metaMu.Lock()
+	defer node.metaMu.Unlock()
+	if node.meta == nil {
+		node.meta = make(map[string]interface{})
+	}
+	node.meta[key] = value
+}
+
+// Meta returns the value attached to the node under the given key, or
+// nil if there's none.
+func (node *Node) Meta(key string) interface{} {
+	node.metaMu.Lock()
+	defer node.metaMu.Unlock()
+	return node.meta[key]
+}
@@ -0,0 +1,179 @@
+package tree
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a", size: 10},
+			{name: "b", files: []*file{{name: "c", size: 5}, {name: "d", size: 20}}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	inf := New(root.name)
+	inf.Visit(&Options{Fs: fs, OutFile: out})
+	var paths []string
+	for _, node := range inf.Flatten() {
+		paths = append(paths, node.Path())
+	}
+	if got, expected := fmt.Sprint(paths), "[root root/a root/b root/b/c root/b/d]"; got != expected {
+		t.Errorf("Flatten: got %s, expected %s", got, expected)
+	}
+	files := inf.Flatten().Filter(func(n *Node) bool { return !n.IsDir() && n.Size() >= 10 })
+	if len(files) != 2 || files[0].Path() != "root/a" || files[1].Path() != "root/b/d" {
+		t.Errorf("Filter: got %v", files)
+	}
+	for _, test := range []struct {
+		node     *Node
+		expected int64
+	}{
+		{inf, 35},
+		{inf.nodes[0], 10},
+		{inf.nodes[1], 25},
+		{&Node{path: "missing"}, 0},
+	} {
+		if size := test.node.TotalSize(); size != test.expected {
+			t.Errorf("TotalSize of %s: got %d, expected %d", test.node.path, size, test.expected)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a", size: 10},
+			{name: "b", files: []*file{{name: "c", size: 5}, {name: "d", size: 20}}},
+			{name: "e", files: []*file{{name: "f", size: 1}}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	opts := &Options{Fs: fs, OutFile: out, FileCount: true}
+	inf := New(root.name)
+	inf.Visit(opts)
+	var called []string
+	dirs, files := inf.Prune(func(n *Node) bool {
+		called = append(called, n.Name())
+		return n.Name() == "c" || n.IsDir() && n.TotalSize() < 5
+	})
+	if dirs != 1 || files != 2 {
+		t.Errorf("Prune: got %d dirs, %d files, expected 1, 2", dirs, files)
+	}
+	if got, expected := fmt.Sprint(called), "[a b c d e]"; got != expected {
+		t.Errorf("Prune: called on %s, expected %s", got, expected)
+	}
+	if size := inf.TotalSize(); size != 30 {
+		t.Errorf("TotalSize after Prune: got %d, expected 30", size)
+	}
+	out.str = ""
+	inf.Print(opts)
+	expected := `root [2 files]
+├── a
+└── b [1 file]
+    └── d
+`
+	if !out.equal(expected) {
+		t.Errorf("Print after Prune: got:\n%s\nexpected:\n%s", out.str, expected)
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a.go"},
+			{name: "b", files: []*file{{name: "c.go"}, {name: "d.txt"}}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	inf := New(root.name)
+	inf.Visit(&Options{Fs: fs, OutFile: out})
+	for _, test := range []struct {
+		pattern  string
+		glob     bool
+		expected string
+	}{
+		{"*.go", true, "[root/a.go root/b/c.go]"},
+		{"b/*", true, "[root/b/c.go root/b/d.txt]"},
+		{`\.txt$`, false, "[root/b/d.txt]"},
+		{"*.md", true, "[]"},
+	} {
+		nodes, err := inf.Find(test.pattern, test.glob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for _, node := range nodes {
+			paths = append(paths, node.Path())
+		}
+		if got := fmt.Sprint(paths); got != test.expected {
+			t.Errorf("Find(%q): got %s, expected %s", test.pattern, got, test.expected)
+		}
+	}
+	if _, err := inf.Find("(a", false); err == nil || err.Error() != "invalid Find: error parsing regexp: missing closing ): `(a`" {
+		t.Errorf("Find: unexpected error %v", err)
+	}
+}
+
+func TestMeta(t *testing.T) {
+	node := &Node{path: "root"}
+	if v := node.Meta("hash"); v != nil {
+		t.Errorf("Meta of a missing key: got %v, expected nil", v)
+	}
+	node.SetMeta("hash", "abc")
+	node.SetMeta("lines", 10)
+	if v := node.Meta("hash"); v != "abc" {
+		t.Errorf("Meta(hash): got %v, expected abc", v)
+	}
+	if v := node.Meta("lines"); v != 10 {
+		t.Errorf("Meta(lines): got %v, expected 10", v)
+	}
+}
+
+func TestPrintPage(t *testing.T) {
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a"},
+			{name: "b", files: []*file{{name: "c"}, {name: "d", files: []*file{{name: "e"}}}}},
+			{name: "f"},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	opts := &Options{Fs: fs, OutFile: out}
+	inf := New(root.name)
+	inf.Visit(opts)
+	for _, test := range []struct {
+		offset, limit int
+		expected      string
+	}{
+		{0, 3, `root
+├── a
+├── b
+`},
+		{3, 2, `root
+├── b
+│   ├── c
+│   └── d
+`},
+		{5, 0, `root
+├── b
+│   └── d
+│       └── e
+└── f
+`},
+		{7, 2, ""},
+	} {
+		out.str = ""
+		if total := inf.PrintPage(opts, test.offset, test.limit); total != 7 {
+			t.Errorf("PrintPage(%d, %d): got %d entries, expected 7", test.offset, test.limit, total)
+		}
+		if !out.equal(test.expected) {
+			t.Errorf("PrintPage(%d, %d): got:\n%s\nexpected:\n%s", test.offset, test.limit, out.str, test.expected)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package tree
+
+// Result is the outcome of Run.
+type Result struct {
+	// Dirs and Files are the number of listed directories and files.
+	Dirs  int
+	Files int
+	// ScannedDirs and ScannedFiles include the filtered out ones.
+	ScannedDirs  int
+	ScannedFiles int
+	BrokenLinks  int
+	// Errors are the entries that couldn't be read.
+	Errors []*WalkError
+	// Mounts are the distinct filesystems encountered in all the roots,
+	// if the 'FsType' or 'FsUsage' options are set.
+	Mounts []Mount
+	// Truncated is set if some directories were not descended because
+	// of the 'DeepLevel' option.
+	Truncated bool
+	// Mismatches is the number of entries that don't match the 'Manifest'
+	// option.
+	Mismatches int
+	// Findings are the risky entries, if the 'Audit' option is set.
+	Findings []AuditFinding
+	// Stale are the stale files by directory, if the 'StaleSince' option
+	// is set.
+	Stale []StaleGroup
+	// Hardlinks are the files with several hard links in all the roots,
+	// if the 'Hardlinks' option is set.
+	Hardlinks []HardlinkGroup
+}
+
+// Skipped returns the number of scanned entries that were filtered out.
+func (r *Result) Skipped() int {
+	return r.ScannedDirs + r.ScannedFiles - r.Dirs - r.Files
+}
+
+// ExitCode returns the exit status a command should return: 0 on
+// success, and 1 if some entries couldn't be read or didn't match the
+// manifest.
+func (r *Result) ExitCode() int {
+	if len(r.Errors) > 0 || r.Mismatches > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Run visits and prints each of the given roots as its own tree, one
+// after the other (or in a single array with the JSON option), and
+// returns the combined result.
+func Run(roots []string, opts *Options) *Result {
+	r := new(Result)
+	seen := make(map[string]bool)
+	// JSON option, the roots are printed in a single array
+	var visited []*Node
+	for _, root := range roots {
+		inf := New(root)
+		d, f := inf.Visit(opts)
+		if opts.JSON {
+			inf.writeSnapshot(opts)
+			visited = append(visited, inf)
+		} else {
+			inf.Print(opts)
+		}
+		sd, sf := inf.Scanned()
+		r.Dirs, r.Files = r.Dirs+d, r.Files+f
+		r.ScannedDirs, r.ScannedFiles = r.ScannedDirs+sd, r.ScannedFiles+sf
+		r.BrokenLinks += inf.BrokenLinks()
+		r.Errors = append(r.Errors, inf.Errors()...)
+		r.Mounts = inf.appendMounts(r.Mounts, seen)
+		r.Truncated = r.Truncated || inf.Truncated()
+		r.Mismatches += inf.Mismatches()
+		r.Findings = append(r.Findings, inf.AuditFindings()...)
+		r.Stale = append(r.Stale, inf.StaleGroups()...)
+		if opts.Hardlinks {
+			r.Hardlinks = appendHardlinks(r.Hardlinks, inf.HardlinkGroups())
+		}
+	}
+	if opts.JSON {
+		printJSON(opts, visited)
+	}
+	return r
+}
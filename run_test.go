@@ -0,0 +1,35 @@
+package tree
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	defer out.clear()
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a"},
+			{name: "b", mode: os.ModeSymlink},
+			{name: "c", files: []*file{{name: "d", files: []*file{{name: "e"}}}}},
+			{name: "f", files: []*file{}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	efs := &errFs{fs, map[string]error{"readdir:root/f": errors.New("permission denied")}}
+	r := Run([]string{"root", "root/c"}, &Options{Fs: efs, OutFile: out, DeepLevel: 2, IPattern: "a"})
+	if r.Dirs != 4 || r.Files != 2 || r.ScannedDirs != 4 || r.ScannedFiles != 3 || r.Skipped() != 1 {
+		t.Errorf("unexpected counts: %+v", r)
+	}
+	if r.BrokenLinks != 1 || !r.Truncated || len(r.Errors) != 1 || r.Errors[0].Path != "root/f" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	if r.ExitCode() != 1 {
+		t.Errorf("expected exit code 1, got %d", r.ExitCode())
+	}
+	if r := Run([]string{"root/c"}, &Options{Fs: fs, OutFile: out}); r.ExitCode() != 0 || r.Truncated {
+		t.Errorf("unexpected result: %+v", r)
+	}
+}
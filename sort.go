@@ -1,6 +1,15 @@
 package tree
 
-import "os"
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
 
 func (n Nodes) Len() int      { return len(n) }
 func (n Nodes) Swap(i, j int) { n[i], n[j] = n[j], n[i] }
@@ -11,9 +20,93 @@ type ByFunc struct {
 }
 
 func (b ByFunc) Less(i, j int) bool {
-	return b.Fn(b.Nodes[i].FileInfo, b.Nodes[j].FileInfo)
+	return b.less(b.Nodes[i], b.Nodes[j])
 }
 
+func (b ByFunc) less(n1, n2 *Node) bool {
+	// Nodes that couldn't be stat'ed are ordered by path
+	if n1.FileInfo == nil || n2.FileInfo == nil {
+		return n1.path < n2.path
+	}
+	if b.Fn(n1.FileInfo, n2.FileInfo) {
+		return true
+	}
+	if b.Fn(n2.FileInfo, n1.FileInfo) {
+		return false
+	}
+	// Ties are ordered by name, and then by path, so the order doesn't
+	// depend on the readdir one
+	if n1.Name() != n2.Name() {
+		return n1.Name() < n2.Name()
+	}
+	return n1.path < n2.path
+}
+
+// parallelSortMin is the number of nodes from which sortNodes sorts in
+// parallel.
+const parallelSortMin = 1 << 14
+
+// sortNodes sorts nodes by less, keeping the order of equal nodes. Large
+// directories are split into chunks that are sorted concurrently, and
+// then merged.
+func sortNodes(nodes Nodes, less func(n1, n2 *Node) bool) {
+	procs := runtime.GOMAXPROCS(0)
+	if len(nodes) < parallelSortMin || procs < 2 {
+		sort.Stable(nodeSorter{nodes, less})
+		return
+	}
+	size := (len(nodes) + procs - 1) / procs
+	var chunks []Nodes
+	var wg sync.WaitGroup
+	for i := 0; i < len(nodes); i += size {
+		end := i + size
+		if end > len(nodes) {
+			end = len(nodes)
+		}
+		chunk := nodes[i:end]
+		chunks = append(chunks, chunk)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sort.Stable(nodeSorter{chunk, less})
+		}()
+	}
+	wg.Wait()
+	for len(chunks) > 1 {
+		var merged []Nodes
+		for i := 0; i < len(chunks); i += 2 {
+			if i+1 == len(chunks) {
+				merged = append(merged, chunks[i])
+			} else {
+				merged = append(merged, mergeNodes(chunks[i], chunks[i+1], less))
+			}
+		}
+		chunks = merged
+	}
+	copy(nodes, chunks[0])
+}
+
+// mergeNodes merges two sorted slices, preferring the first one on ties.
+func mergeNodes(a, b Nodes, less func(n1, n2 *Node) bool) Nodes {
+	merged := make(Nodes, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if less(b[0], a[0]) {
+			merged, b = append(merged, b[0]), b[1:]
+		} else {
+			merged, a = append(merged, a[0]), a[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+type nodeSorter struct {
+	Nodes
+	less func(n1, n2 *Node) bool
+}
+
+func (s nodeSorter) Less(i, j int) bool { return s.less(s.Nodes[i], s.Nodes[j]) }
+
 type SortFunc func(f1, f2 os.FileInfo) bool
 
 func ModSort(f1, f2 os.FileInfo) bool {
@@ -32,6 +125,60 @@ func NameSort(f1, f2 os.FileInfo) bool {
 	return f1.Name() < f2.Name()
 }
 
+// ExtSort compares the extensions, and then the names, like ls -X. The
+// names without extension, including the dotfiles such as ".bashrc", come
+// first.
+func ExtSort(f1, f2 os.FileInfo) bool {
+	e1, e2 := sortExt(f1.Name()), sortExt(f2.Name())
+	if e1 != e2 {
+		return e1 < e2
+	}
+	return f1.Name() < f2.Name()
+}
+
+// sortExt returns the extension of a name for ExtSort, without the dot.
+func sortExt(name string) string {
+	ext := filepath.Ext(strings.TrimLeft(name, "."))
+	return strings.TrimPrefix(ext, ".")
+}
+
+// FoldNameSort compares the names case-insensitively, so "apple" comes
+// before "Zebra" like with ls.
+func FoldNameSort(f1, f2 os.FileInfo) bool {
+	return foldLess(f1.Name(), f2.Name())
+}
+
+// nameSort returns the name SortFunc, with the FoldSort and DotlessSort
+// options applied.
+func (opts *Options) nameSort() SortFunc {
+	switch {
+	case opts.DotlessSort:
+		return func(f1, f2 os.FileInfo) bool {
+			n1, n2 := strings.TrimLeft(f1.Name(), "."), strings.TrimLeft(f2.Name(), ".")
+			if opts.FoldSort {
+				return foldLess(n1, n2)
+			}
+			return n1 < n2
+		}
+	case opts.FoldSort:
+		return FoldNameSort
+	}
+	return NameSort
+}
+
+// foldLess reports whether s1 is less than s2, ignoring case.
+func foldLess(s1, s2 string) bool {
+	for s1 != "" && s2 != "" {
+		r1, n1 := utf8.DecodeRuneInString(s1)
+		r2, n2 := utf8.DecodeRuneInString(s2)
+		if l1, l2 := unicode.ToLower(r1), unicode.ToLower(r2); l1 != l2 {
+			return l1 < l2
+		}
+		s1, s2 = s1[n1:], s2[n2:]
+	}
+	return len(s1) < len(s2)
+}
+
 func VerSort(f1, f2 os.FileInfo) bool {
 	return NaturalLess(f1.Name(), f2.Name())
 }
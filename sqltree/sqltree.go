@@ -0,0 +1,255 @@
+// Package sqltree stores visited trees in a SQL database, and implements
+// a tree.Fs replaying them, to browse or compare scans offline.
+//
+// The schema is written for SQLite, and the database is opened by the
+// caller with the driver of its choice, e.g.:
+//
+//	db, err := sql.Open("sqlite3", "scans.db") // e.g. github.com/mattn/go-sqlite3
+//	if err != nil {
+//		return err
+//	}
+//	if err := sqltree.Init(db); err != nil {
+//		return err
+//	}
+//	// Scan once, with checksums
+//	opts := &tree.Options{Fs: new(ostree.FS), OutFile: ioutil.Discard, Checksum: "sha256"}
+//	inf := tree.New("/data")
+//	inf.Visit(opts)
+//	scan, err := sqltree.Store(db, inf)
+//	if err != nil {
+//		return err
+//	}
+//	// And query offline
+//	opts = &tree.Options{Fs: sqltree.New(db, scan), OutFile: os.Stdout}
+//	inf = tree.New("/data")
+//	inf.Visit(opts)
+//	inf.Print(opts)
+package sqltree
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/a8m/tree"
+)
+
+// schema creates the tables of the index. The entries are the visited
+// nodes of each scan, and their parent is the path of their directory,
+// or "" for the root.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS scans (
+	id   INTEGER PRIMARY KEY,
+	root TEXT NOT NULL,
+	time INTEGER NOT NULL
+)`,
+	`CREATE TABLE IF NOT EXISTS entries (
+	scan   INTEGER NOT NULL REFERENCES scans (id) ON DELETE CASCADE,
+	path   TEXT NOT NULL,
+	parent TEXT NOT NULL,
+	size   INTEGER NOT NULL,
+	mode   INTEGER NOT NULL,
+	mtime  INTEGER NOT NULL,
+	hash   TEXT,
+	target TEXT,
+	PRIMARY KEY (scan, path)
+)`,
+	`CREATE INDEX IF NOT EXISTS entries_parent ON entries (scan, parent)`,
+}
+
+// The queries of the index.
+const (
+	insertScan  = `INSERT INTO scans (root, time) VALUES (?, ?)`
+	insertEntry = `INSERT INTO entries (scan, path, parent, size, mode, mtime, hash, target) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	selectScans = `SELECT id, root, time FROM scans ORDER BY id`
+	selectEntry = `SELECT size, mode, mtime, hash, target FROM entries WHERE scan = ? AND path = ?`
+	selectDir   = `SELECT path FROM entries WHERE scan = ? AND parent = ? ORDER BY path`
+)
+
+// Init creates the tables of the index, if they don't exist.
+func Init(db *sql.DB) error {
+	for _, query := range schema {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Scan is a stored tree.
+type Scan struct {
+	ID   int64
+	Root string
+	Time time.Time
+}
+
+// Store writes the entries of a visited node and its children as a new
+// scan, and returns its ID. The entries that couldn't be read are not
+// stored, and the hash is the checksum of the Checksum option, if set.
+func Store(db *sql.DB, root *tree.Node) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(insertScan, filepath.Clean(root.Path()), time.Now().UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	scan, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	stmt, err := tx.Prepare(insertEntry)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	err = tree.StreamEntries(root, func(e *tree.Entry) error {
+		if e.Error != "" {
+			return nil
+		}
+		path, parent := filepath.Clean(e.Path), ""
+		if e.Depth > 0 {
+			parent = filepath.Dir(path)
+		}
+		var hash, target sql.NullString
+		if s, ok := e.Meta["checksum"].(string); ok {
+			hash = sql.NullString{String: s, Valid: true}
+		}
+		if e.Target != "" {
+			target = sql.NullString{String: e.Target, Valid: true}
+		}
+		_, err := stmt.Exec(scan, path, parent, e.Size, int64(e.Mode), e.ModTime.UnixNano(), hash, target)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return scan, tx.Commit()
+}
+
+// Scans returns the stored scans, oldest first.
+func Scans(db *sql.DB) ([]Scan, error) {
+	rows, err := db.Query(selectScans)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var scans []Scan
+	for rows.Next() {
+		var s Scan
+		var t int64
+		if err := rows.Scan(&s.ID, &s.Root, &t); err != nil {
+			return nil, err
+		}
+		s.Time = time.Unix(0, t)
+		scans = append(scans, s)
+	}
+	return scans, rows.Err()
+}
+
+// FS is the file-system of a stored scan. The paths are the ones of the
+// scan, starting with its root.
+type FS struct {
+	db   *sql.DB
+	scan int64
+}
+
+// New returns the FS of the given scan.
+func New(db *sql.DB, scan int64) *FS {
+	return &FS{db: db, scan: scan}
+}
+
+// Stat returns the FileInfo of a stored entry.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	fi, err := fs.entry(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return fi, nil
+}
+
+// ReadDir returns the names of the stored entries of a directory.
+func (fs *FS) ReadDir(name string) ([]string, error) {
+	rows, err := fs.db.Query(selectDir, fs.scan, filepath.Clean(name))
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+		names = append(names, filepath.Base(p))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	if names == nil {
+		// Tell the missing directories from the empty ones
+		if _, err := fs.entry(name); err != nil {
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+	return names, nil
+}
+
+// Readlink returns the stored target of a symbolic link, see
+// tree.LinkReader.
+func (fs *FS) Readlink(name string) (string, error) {
+	fi, err := fs.entry(name)
+	if err != nil {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	if fi.mode&os.ModeSymlink == 0 || !fi.target.Valid {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrInvalid}
+	}
+	return fi.target.String, nil
+}
+
+// Checksum returns the stored checksum of a file, or "" if it was
+// scanned without the Checksum option.
+func (fs *FS) Checksum(name string) (string, error) {
+	fi, err := fs.entry(name)
+	if err != nil {
+		return "", &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return fi.hash.String, nil
+}
+
+// entry reads a stored entry, or returns os.ErrNotExist.
+func (fs *FS) entry(name string) (*fileInfo, error) {
+	name = filepath.Clean(name)
+	fi := &fileInfo{name: filepath.Base(name)}
+	var mode, mtime int64
+	err := fs.db.QueryRow(selectEntry, fs.scan, name).Scan(&fi.size, &mode, &mtime, &fi.hash, &fi.target)
+	if err == sql.ErrNoRows {
+		return nil, os.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	fi.mode, fi.modTime = os.FileMode(mode), time.Unix(0, mtime)
+	return fi, nil
+}
+
+// fileInfo is the FileInfo of a stored entry.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+	hash    sql.NullString
+	target  sql.NullString
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,172 @@
+package sqltree
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/a8m/tree"
+	"github.com/a8m/tree/ostree"
+)
+
+// memDriver is an in-memory database/sql driver, running the queries of
+// the index only.
+type memDriver struct {
+	scans   [][]driver.Value
+	entries [][]driver.Value
+}
+
+func (d *memDriver) Open(string) (driver.Conn, error) { return d, nil }
+func (d *memDriver) Prepare(query string) (driver.Stmt, error) {
+	return &memStmt{d, query}, nil
+}
+func (d *memDriver) Close() error              { return nil }
+func (d *memDriver) Begin() (driver.Tx, error) { return d, nil }
+func (d *memDriver) Commit() error             { return nil }
+func (d *memDriver) Rollback() error           { return nil }
+
+type memStmt struct {
+	d     *memDriver
+	query string
+}
+
+func (s *memStmt) Close() error  { return nil }
+func (s *memStmt) NumInput() int { return -1 }
+
+func (s *memStmt) Exec(args []driver.Value) (driver.Result, error) {
+	switch s.query {
+	case insertScan:
+		s.d.scans = append(s.d.scans, append([]driver.Value{int64(len(s.d.scans) + 1)}, args...))
+		return memResult(len(s.d.scans)), nil
+	case insertEntry:
+		s.d.entries = append(s.d.entries, args)
+		return driver.RowsAffected(1), nil
+	}
+	for _, query := range schema {
+		if s.query == query {
+			return driver.RowsAffected(0), nil
+		}
+	}
+	return nil, errors.New("unexpected query: " + s.query)
+}
+
+func (s *memStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows := &memRows{}
+	switch s.query {
+	case selectScans:
+		rows.cols, rows.values = []string{"id", "root", "time"}, s.d.scans
+	case selectEntry:
+		rows.cols = []string{"size", "mode", "mtime", "hash", "target"}
+		for _, e := range s.d.entries {
+			if e[0] == args[0] && e[1] == args[1] {
+				rows.values = append(rows.values, e[3:])
+			}
+		}
+	case selectDir:
+		rows.cols = []string{"path"}
+		for _, e := range s.d.entries {
+			if e[0] == args[0] && e[2] == args[1] {
+				rows.values = append(rows.values, e[1:2])
+			}
+		}
+		sort.Slice(rows.values, func(i, j int) bool {
+			return rows.values[i][0].(string) < rows.values[j][0].(string)
+		})
+	default:
+		return nil, errors.New("unexpected query: " + s.query)
+	}
+	return rows, nil
+}
+
+// memResult is the result of an insert, and its ID.
+type memResult int64
+
+func (r memResult) LastInsertId() (int64, error) { return int64(r), nil }
+func (r memResult) RowsAffected() (int64, error) { return 1, nil }
+
+type memRows struct {
+	cols   []string
+	values [][]driver.Value
+}
+
+func (r *memRows) Columns() []string { return r.cols }
+func (r *memRows) Close() error      { return nil }
+func (r *memRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+var mem = new(memDriver)
+
+func init() {
+	sql.Register("sqltree-mem", mem)
+}
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "a", "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "a", "b.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a/b.txt", filepath.Join(dir, "c")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	db, err := sql.Open("sqltree-mem", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Init(db); err != nil {
+		t.Fatal(err)
+	}
+	opts := &tree.Options{Fs: new(ostree.FS), OutFile: ioutil.Discard, ByteSize: true, Checksum: "md5"}
+	inf := tree.New(dir)
+	inf.Visit(opts)
+	scan, err := Store(db, inf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scans, err := Scans(db); err != nil || len(scans) != 1 || scans[0].ID != scan || scans[0].Root != dir {
+		t.Fatalf("unexpected scans %v, %v", scans, err)
+	}
+	// The files are gone, the scan remains
+	os.RemoveAll(dir)
+	b := new(bytes.Buffer)
+	fs := New(db, scan)
+	opts = &tree.Options{Fs: fs, OutFile: b, ByteSize: true}
+	inf = tree.New(dir)
+	inf.Visit(opts)
+	inf.Print(opts)
+	// The directories sizes are the stored sums of their files
+	expected := `[         12]  ` + dir + `
+├── [          5]  a
+│   ├── [          5]  b.txt
+│   └── [          0]  empty
+└── [          7]  c -> a/b.txt
+`
+	if b.String() != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", b, expected)
+	}
+	if sum, err := fs.Checksum(filepath.Join(dir, "a", "b.txt")); err != nil || sum != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("unexpected checksum %q, %v", sum, err)
+	}
+	if _, err := fs.Stat(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("expect a not exist error, got %v", err)
+	}
+}
@@ -0,0 +1,280 @@
+// Package sshtree implements a tree.Fs of a remote host, walked over SSH.
+//
+// The directories are enumerated at once on the remote host by GNU find,
+// run by the ssh command, so nothing has to be installed there. The
+// listing is then served from memory, e.g.:
+//
+//	fs := sshtree.New("user@example.com")
+//	opts := &tree.Options{Fs: fs, OutFile: os.Stdout}
+//	inf := tree.New("/var/www")
+//	inf.Visit(opts)
+//	inf.Print(opts)
+package sshtree
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// findFormat are the fields printed by find for each entry, NUL
+// terminated: type, size, permissions, modification time, link target,
+// and path.
+const findFormat = `%y\0%s\0%m\0%T@\0%l\0%p\0`
+
+// FS is the file-system of a remote host. The directories are scanned on
+// the first ReadDir of a path that wasn't scanned yet, and the other
+// paths are stat'ed alone, e.g. the targets of the symbolic links.
+type FS struct {
+	// Host is the destination of ssh, e.g. "user@example.com".
+	Host string
+	// Command is the ssh command and its options, "ssh" by default, e.g.
+	// []string{"ssh", "-p", "2222"}. "--", the host and the remote command
+	// are appended to it, so a host can't be taken for an option.
+	Command []string
+	// MaxDepth limits the scan to MaxDepth levels below the scanned
+	// directory if positive, see the DeepLevel option of tree.
+	MaxDepth int
+
+	mu     sync.Mutex
+	roots  []string
+	files  map[string]*fileInfo
+	dirs   map[string][]string
+	listed map[string]bool
+	errs   map[string]error
+}
+
+// New returns the FS of the given host.
+func New(host string) *FS {
+	return &FS{Host: host}
+}
+
+// Stat returns the FileInfo of a path, without following symbolic links.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	name = path.Clean(name)
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if _, ok := fs.files[name]; !ok && !fs.scanned(name) {
+		if err := fs.scan(name, false); err != nil {
+			return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+		}
+	}
+	if fi, ok := fs.files[name]; ok {
+		return fi, nil
+	}
+	if err, ok := fs.errs[name]; ok {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+// ReadDir returns the names of the entries of a scanned directory.
+func (fs *FS) ReadDir(name string) ([]string, error) {
+	name = path.Clean(name)
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if !fs.scanned(name) {
+		if err := fs.scan(name, true); err != nil {
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+	if err, ok := fs.errs[name]; ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	fi, ok := fs.files[name]
+	if !ok || !fi.IsDir() {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return fs.dirs[name], nil
+}
+
+// Readlink returns the target of a symbolic link, see tree.LinkReader.
+func (fs *FS) Readlink(name string) (string, error) {
+	name = path.Clean(name)
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fi, ok := fs.files[name]
+	if !ok || fi.mode&os.ModeSymlink == 0 {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrInvalid}
+	}
+	return fi.link, nil
+}
+
+// scanned reports whether name is below one of the scanned directories.
+func (fs *FS) scanned(name string) bool {
+	for _, root := range fs.roots {
+		if name == root || root == "." && !path.IsAbs(name) && !strings.HasPrefix(name, "../") ||
+			strings.HasPrefix(name, strings.TrimSuffix(root, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// findError matches the errors of find, e.g.
+// "find: '/root': Permission denied".
+var findError = regexp.MustCompile(`^find: '(.*)': (.*)$`)
+
+// scan lists the directory root on the remote host, or only stats it if
+// list isn't set.
+func (fs *FS) scan(root string, list bool) error {
+	if fs.files == nil {
+		fs.files, fs.errs = make(map[string]*fileInfo), make(map[string]error)
+		fs.dirs, fs.listed = make(map[string][]string), make(map[string]bool)
+	}
+	script := "LC_ALL=C find " + quote(root)
+	if !list {
+		script += " -maxdepth 0"
+	} else if fs.MaxDepth > 0 {
+		script += " -maxdepth " + strconv.Itoa(fs.MaxDepth)
+	}
+	script += " -printf " + quote(findFormat)
+	command := fs.Command
+	if len(command) == 0 {
+		command = []string{"ssh"}
+	}
+	args := append(append([]string{}, command[1:]...), "--", fs.Host, script)
+	cmd := exec.Command(command[0], args...)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	n, perr := fs.parse(out, list)
+	werr := cmd.Wait()
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		if m := findError.FindStringSubmatch(line); m != nil {
+			fs.errs[path.Clean(m[1])] = errors.New(strings.ToLower(m[2]))
+		}
+	}
+	if perr != nil {
+		return perr
+	}
+	// find fails on the unreadable entries, but still lists the others
+	if n == 0 && werr != nil {
+		if _, ok := fs.errs[root]; !ok {
+			return fmt.Errorf("%v: %s", werr, strings.TrimSpace(stderr.String()))
+		}
+	}
+	if list {
+		fs.roots = append(fs.roots, root)
+	}
+	return nil
+}
+
+// parse reads the entries printed by find, and returns their number.
+func (fs *FS) parse(r io.Reader, list bool) (int, error) {
+	br := bufio.NewReader(r)
+	var n int
+	for {
+		var fields [6]string
+		for i := range fields {
+			f, err := br.ReadString(0)
+			if err == io.EOF && i == 0 && f == "" {
+				return n, nil
+			}
+			if err != nil {
+				return n, fmt.Errorf("truncated listing: %v", err)
+			}
+			fields[i] = f[:len(f)-1]
+		}
+		fi, err := parseEntry(fields)
+		if err != nil {
+			return n, err
+		}
+		name := path.Clean(fields[5])
+		// the first entry is the scanned one, the others are in the
+		// listed directories
+		if list && n > 0 && !fs.listed[name] {
+			dir := path.Dir(name)
+			fs.dirs[dir] = append(fs.dirs[dir], fi.name)
+			fs.listed[name] = true
+		}
+		fs.files[name] = fi
+		n++
+	}
+}
+
+// fileTypes are the modes of the types printed by find's %y.
+var fileTypes = map[string]os.FileMode{
+	"f": 0,
+	"d": os.ModeDir,
+	"l": os.ModeSymlink,
+	"p": os.ModeNamedPipe,
+	"s": os.ModeSocket,
+	"c": os.ModeDevice | os.ModeCharDevice,
+	"b": os.ModeDevice,
+}
+
+// parseEntry parses the fields of an entry printed by find.
+func parseEntry(fields [6]string) (*fileInfo, error) {
+	mode, ok := fileTypes[fields[0]]
+	if !ok {
+		return nil, fmt.Errorf("invalid type '%s' of %s", fields[0], fields[5])
+	}
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid size of %s: %v", fields[5], err)
+	}
+	perm, err := strconv.ParseUint(fields[2], 8, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid permissions of %s: %v", fields[5], err)
+	}
+	mode |= os.FileMode(perm) & os.ModePerm
+	if perm&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if perm&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if perm&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	mtime, err := strconv.ParseFloat(fields[3], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modification time of %s: %v", fields[5], err)
+	}
+	sec := int64(mtime)
+	return &fileInfo{
+		name:    path.Base(fields[5]),
+		size:    size,
+		mode:    mode,
+		modTime: time.Unix(sec, int64((mtime-float64(sec))*1e9)),
+		link:    fields[4],
+	}, nil
+}
+
+// quote quotes s for the remote shell.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// fileInfo is the FileInfo of a remote entry.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+	link    string
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() interface{}   { return nil }
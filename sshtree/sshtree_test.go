@@ -0,0 +1,69 @@
+package sshtree
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/a8m/tree"
+)
+
+// localFS returns a FS running the remote commands locally.
+func localFS(t *testing.T) *FS {
+	if err := exec.Command("find", ".", "-maxdepth", "0", "-printf", "").Run(); err != nil {
+		t.Skip("GNU find is required:", err)
+	}
+	return &FS{Host: "localhost", Command: []string{"sh", "-c", `exec sh -c "$2"`}}
+}
+
+func TestFS(t *testing.T) {
+	fs := localFS(t)
+	dir, err := ioutil.TempDir("", "sshtree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "a", "b"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "a", "it's a file"), []byte("hello"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "a", "b", "c"), nil, 0755)
+	os.Symlink("a/b", filepath.Join(dir, "link"))
+	os.Symlink("missing", filepath.Join(dir, "broken"))
+	b := new(bytes.Buffer)
+	opts := &tree.Options{Fs: fs, OutFile: b, ByteSize: true}
+	inf := tree.New(dir)
+	d, f := inf.Visit(opts)
+	inf.Print(opts)
+	if d != 2 || f != 4 {
+		t.Errorf("expect 2 dirs and 4 files, got %d and %d", d, f)
+	}
+	for _, line := range []string{"it's a file", "[          5]", "link -> a/b", "broken -> missing"} {
+		if !strings.Contains(b.String(), line) {
+			t.Errorf("expect %q in:\n%s", line, b)
+		}
+	}
+	if r := tree.Run([]string{dir}, opts); r.BrokenLinks != 1 {
+		t.Errorf("expect 1 broken link, got %d", r.BrokenLinks)
+	}
+	fi, err := fs.Stat(filepath.Join(dir, "a", "b", "c"))
+	if err != nil || fi.Mode().Perm() != 0755 || fi.IsDir() {
+		t.Errorf("unexpected stat of c: %v, %v", fi, err)
+	}
+	if _, err := fs.Stat(filepath.Join(dir, "nope")); !os.IsNotExist(err) {
+		t.Errorf("expect a not exist error, got %v", err)
+	}
+}
+
+func TestFSErrors(t *testing.T) {
+	fs := localFS(t)
+	if _, err := fs.Stat("/nonexistent/sshtree"); err == nil {
+		t.Error("expect an error for a missing root")
+	}
+	fs.Command = []string{"false"}
+	if _, err := fs.Stat("/tmp"); err == nil {
+		t.Error("expect an error for a failing ssh command")
+	}
+}
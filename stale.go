@@ -0,0 +1,103 @@
+package tree
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// StaleFile is a regular file neither modified nor accessed since the
+// StaleSince option.
+type StaleFile struct {
+	Path string
+	Size int64
+	// LastUsed is the latest of its modification and access times.
+	LastUsed time.Time
+}
+
+// StaleGroup is the stale files of a directory, and their total size.
+type StaleGroup struct {
+	Dir   string
+	Files []StaleFile
+	Size  int64
+}
+
+// lastUsed returns the latest of the modification and access times of a
+// file. The access time is as precise as the mount allows, e.g. updated
+// at most daily with relatime.
+func lastUsed(fi os.FileInfo) time.Time {
+	t := fi.ModTime()
+	if at := accessTime(fi); at.After(t) {
+		t = at
+	}
+	return t
+}
+
+// isStale reports whether a file is a regular one unused since the given
+// time.
+func isStale(fi os.FileInfo, since time.Time) bool {
+	return fi.Mode().IsRegular() && lastUsed(fi).Before(since)
+}
+
+// StaleGroups returns the stale files of a tree visited with the
+// StaleSince option, grouped by directory, the largest groups first.
+func (node *Node) StaleGroups() []StaleGroup {
+	var groups []StaleGroup
+	node.appendStale(&groups)
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Size > groups[j].Size
+	})
+	return groups
+}
+
+func (node *Node) appendStale(groups *[]StaleGroup) {
+	var g StaleGroup
+	for _, nnode := range node.nodes {
+		if nnode.stale {
+			fi := nnode.info()
+			g.Files = append(g.Files, StaleFile{nnode.path, fi.Size(), lastUsed(fi)})
+			g.Size += fi.Size()
+		}
+	}
+	if g.Files != nil {
+		g.Dir = node.path
+		*groups = append(*groups, g)
+	}
+	for _, nnode := range node.nodes {
+		if nnode.IsDir() {
+			nnode.appendStale(groups)
+		}
+	}
+}
+
+// FprintStale writes a cleanup report of the given groups, e.g:
+//
+//	3 stale files in 2 directories, 2.5M reclaimable
+//	  root/logs: 2 files, 2.0M
+//	    app.1.log  1024K  2023-01-02
+//	    app.2.log  1024K  2023-01-01
+//	  root/tmp: 1 file, 512K
+//	    dump.bin  512K  2022-11-30
+func FprintStale(w io.Writer, groups []StaleGroup) {
+	var n int
+	var size int64
+	for _, g := range groups {
+		n += len(g.Files)
+		size += g.Size
+	}
+	if n == 0 {
+		fmt.Fprintln(w, "no stale files")
+		return
+	}
+	fmt.Fprintf(w, "%s in %s, %s reclaimable\n", plural(n, "stale file", "stale files"),
+		plural(len(groups), "directory", "directories"), formatBytes(size))
+	for _, g := range groups {
+		fmt.Fprintf(w, "  %s: %s, %s\n", g.Dir, plural(len(g.Files), "file", "files"), formatBytes(g.Size))
+		for _, f := range g.Files {
+			fmt.Fprintf(w, "    %s  %s  %s\n", filepath.Base(f.Path), formatBytes(f.Size), f.LastUsed.Format("2006-01-02"))
+		}
+	}
+}
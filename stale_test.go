@@ -0,0 +1,43 @@
+package tree
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStale(t *testing.T) {
+	old := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	recent := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	root := &file{name: "root", mode: os.ModeDir | 0755, files: []*file{
+		{name: "logs", mode: os.ModeDir | 0755, files: []*file{
+			{name: "app.1.log", size: 1 << 20, lastMod: old},
+			{name: "app.2.log", size: 1 << 20, lastMod: old.AddDate(0, 0, -1)},
+			{name: "app.log", size: 100, lastMod: recent},
+		}},
+		{name: "tmp", mode: os.ModeDir | 0755, files: []*file{
+			{name: "dump.bin", size: 512 << 10, lastMod: old.AddDate(0, -1, 0)},
+		}},
+	}}
+	fs.clean().addFile(root.name, root)
+	inf := New("root")
+	inf.Visit(&Options{Fs: fs, OutFile: out, StaleSince: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)})
+	b := new(bytes.Buffer)
+	FprintStale(b, inf.StaleGroups())
+	expected := `3 stale files in 2 directories, 2.5M reclaimable
+  root/logs: 2 files, 2.0M
+    app.1.log  1024K  2023-01-02
+    app.2.log  1024K  2023-01-01
+  root/tmp: 1 file, 512K
+    dump.bin  512K  2022-12-02
+`
+	if b.String() != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", b, expected)
+	}
+	b.Reset()
+	FprintStale(b, nil)
+	if b.String() != "no stale files\n" {
+		t.Errorf("got %q for no stale files", b)
+	}
+}
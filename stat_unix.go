@@ -18,3 +18,21 @@ func getStat(fi os.FileInfo) (ok bool, inode, device, uid, gid uint64) {
 	}
 	return true, uint64(stat.Ino), uint64(stat.Dev), uint64(stat.Uid), uint64(stat.Gid)
 }
+
+// getLinks returns the number of hard links to the file.
+func getLinks(fi os.FileInfo) (nlink uint64, ok bool) {
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Nlink), true
+}
+
+// getBlocks returns the number of 512-byte blocks allocated to the file.
+func getBlocks(fi os.FileInfo) (blocks int64, ok bool) {
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int64(stat.Blocks), true
+}
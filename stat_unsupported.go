@@ -7,3 +7,11 @@ import "os"
 func getStat(fi os.FileInfo) (ok bool, inode, device, uid, gid uint64) {
 	return false, 0, 0, 0, 0
 }
+
+func getLinks(fi os.FileInfo) (nlink uint64, ok bool) {
+	return 0, false
+}
+
+func getBlocks(fi os.FileInfo) (blocks int64, ok bool) {
+	return 0, false
+}
@@ -0,0 +1,19 @@
+package tree
+
+// StreamEntries sends the entries of a visited node one by one, in
+// display order (depth-first, parents before their children). The sent
+// entries have no Contents, their place in the tree is given by their
+// Path and Depth. It's meant to feed streaming transports, such as the
+// Walk method of the gRPC service defined in proto/tree.proto.
+// Streaming stops at the first error returned by send.
+func StreamEntries(node *Node, send func(*Entry) error) error {
+	if err := send(newEntry(node)); err != nil {
+		return err
+	}
+	for _, nnode := range node.nodes {
+		if err := StreamEntries(nnode, send); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package tree
+
+import "fmt"
+
+// ThresholdStyle is the color of the SizeThreshold and EntryThreshold
+// markers, bold yellow.
+const ThresholdStyle = "1;33"
+
+// thresholdMarker returns the markers of a directory exceeding the
+// SizeThreshold or EntryThreshold options, e.g. " [over 1.0G]", or "".
+func (opts *Options) thresholdMarker(node *Node) string {
+	if !node.isDir() {
+		return ""
+	}
+	var markers []string
+	if size, _ := node.dirSize(); opts.SizeThreshold > 0 && size > opts.SizeThreshold {
+		markers = append(markers, fmt.Sprintf("[over %s]", formatBytes(opts.SizeThreshold)))
+	}
+	if opts.EntryThreshold > 0 && node.entries > opts.EntryThreshold {
+		markers = append(markers, fmt.Sprintf("[over %d entries]", opts.EntryThreshold))
+	}
+	var s string
+	for _, marker := range markers {
+		if opts.Colorize {
+			marker = ANSIColorFormat(ThresholdStyle, marker)
+		}
+		s += " " + marker
+	}
+	return s
+}
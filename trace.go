@@ -0,0 +1,40 @@
+package tree
+
+// Tracer starts a span around each directory read of a walk (see the
+// 'Tracer' option). It's shaped to be adapted to an OpenTelemetry tracer,
+// for example:
+//
+//	type otelTracer struct {
+//		ctx context.Context
+//		trace.Tracer
+//	}
+//
+//	func (t otelTracer) StartSpan(name, path string) tree.Span {
+//		_, span := t.Start(t.ctx, name, trace.WithAttributes(attribute.String("tree.path", path)))
+//		return otelSpan{span}
+//	}
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) End(entries int, err error) {
+//		s.SetAttributes(attribute.Int("tree.entries", entries))
+//		if err != nil {
+//			s.RecordError(err)
+//			s.SetStatus(codes.Error, err.Error())
+//		}
+//		s.Span.End()
+//	}
+type Tracer interface {
+	// StartSpan starts a span for an operation on the given path.
+	StartSpan(name, path string) Span
+}
+
+// Span is an operation started by a Tracer.
+type Span interface {
+	// End ends the span, with the number of read entries and the
+	// operation error, if any.
+	End(entries int, err error)
+}
+
+// SpanReadDir is the name of the spans started around each directory read.
+const SpanReadDir = "tree.ReadDir"
@@ -0,0 +1,40 @@
+package tree
+
+import (
+	"strings"
+	"testing"
+)
+
+type fakeTracer struct {
+	spans []string
+}
+
+type fakeSpan struct {
+	t    *fakeTracer
+	name string
+}
+
+func (t *fakeTracer) StartSpan(name, path string) Span {
+	return &fakeSpan{t, name + " " + path}
+}
+
+func (s *fakeSpan) End(entries int, err error) {
+	s.t.spans = append(s.t.spans, s.name+" "+strings.Repeat("*", entries))
+}
+
+func TestTracer(t *testing.T) {
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a"},
+			{name: "b", files: []*file{{name: "c"}, {name: "d"}, {name: "e"}}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	tracer := new(fakeTracer)
+	New(root.name).Visit(&Options{Fs: fs, OutFile: out, Tracer: tracer})
+	expected := "tree.ReadDir root **|tree.ReadDir root/b ***"
+	if actual := strings.Join(tracer.spans, "|"); actual != expected {
+		t.Errorf("\ngot:\n%s\nexpected:\n%s", actual, expected)
+	}
+}
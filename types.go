@@ -0,0 +1,132 @@
+package tree
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// FileType is a set of file kinds, used by the 'Types' option.
+type FileType uint
+
+const (
+	TypeFile FileType = 1 << iota
+	TypeSymlink
+	TypeSocket
+	TypeFifo
+	TypeBlockDevice
+	TypeCharDevice
+	TypeOther
+	// TypeSpecial matches all kinds of special files.
+	TypeSpecial = TypeSocket | TypeFifo | TypeBlockDevice | TypeCharDevice | TypeOther
+)
+
+// fileType returns the kind of file described by the given mode.
+func fileType(mode os.FileMode) FileType {
+	switch {
+	case mode.IsRegular():
+		return TypeFile
+	case mode&os.ModeSymlink != 0:
+		return TypeSymlink
+	case mode&os.ModeSocket != 0:
+		return TypeSocket
+	case mode&os.ModeNamedPipe != 0:
+		return TypeFifo
+	case mode&os.ModeCharDevice != 0:
+		return TypeCharDevice
+	case mode&os.ModeDevice != 0:
+		return TypeBlockDevice
+	default:
+		return TypeOther
+	}
+}
+
+// Has reports whether the set contains the kind of file described by the
+// given mode.
+func (t FileType) Has(mode os.FileMode) bool {
+	return t&fileType(mode) != 0
+}
+
+// typeChars maps find(1) like type characters to file types.
+var typeChars = map[rune]FileType{
+	'f': TypeFile,
+	'l': TypeSymlink,
+	's': TypeSocket,
+	'p': TypeFifo,
+	'b': TypeBlockDevice,
+	'c': TypeCharDevice,
+}
+
+// ParseFileTypes parses a set of find(1) like type characters, any of:
+// f (regular file), l (symlink), s (socket), p (fifo), b (block device)
+// and c (char device).
+func ParseFileTypes(s string) (FileType, error) {
+	var t FileType
+	for _, c := range s {
+		ft, ok := typeChars[c]
+		if !ok {
+			return 0, fmt.Errorf("file type '%c' not valid, should be any of: flspbc", c)
+		}
+		t |= ft
+	}
+	return t, nil
+}
+
+// PermMatch defines how the 'Perm' option is matched against files.
+type PermMatch int
+
+const (
+	// PermExact matches files whose permission bits are exactly Perm.
+	PermExact PermMatch = iota
+	// PermAll matches files having all of the Perm bits set.
+	PermAll
+	// PermAny matches files having any of the Perm bits set.
+	PermAny
+)
+
+// permBits are the mode bits compared by the 'Perm' option.
+const permBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// Match reports whether the given mode matches perm.
+func (m PermMatch) Match(mode, perm os.FileMode) bool {
+	mode, perm = mode&permBits, perm&permBits
+	switch m {
+	case PermAll:
+		return mode&perm == perm
+	case PermAny:
+		return mode&perm != 0
+	default:
+		return mode == perm
+	}
+}
+
+// ParsePerm parses a find(1) like octal permission, optionally
+// prefixed with '-' (all bits) or '/' (any bit), e.g. "-4000".
+func ParsePerm(s string) (os.FileMode, PermMatch, error) {
+	if s == "" {
+		return 0, PermExact, nil
+	}
+	match := PermExact
+	num := s
+	switch s[0] {
+	case '-':
+		match, num = PermAll, s[1:]
+	case '/':
+		match, num = PermAny, s[1:]
+	}
+	n, err := strconv.ParseUint(num, 8, 32)
+	if err != nil || n > 07777 || n == 0 {
+		return 0, match, fmt.Errorf("invalid permission '%s'", s)
+	}
+	mode := os.FileMode(n & 0777)
+	if n&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if n&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if n&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode, match, nil
+}
@@ -0,0 +1,95 @@
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Validate checks that the options are complete and consistent, and
+// returns an error describing the first problem found.
+func (opts *Options) Validate() error {
+	if opts.Fs == nil {
+		return errors.New("missing Fs option")
+	}
+	if opts.OutFile == nil {
+		return errors.New("missing OutFile option")
+	}
+	if opts.DeepLevel < 0 {
+		return fmt.Errorf("invalid DeepLevel %d, should be positive", opts.DeepLevel)
+	}
+	if opts.FilterMinDepth < 0 || opts.FilterMaxDepth < 0 {
+		return errors.New("invalid filter depth, should be positive")
+	}
+	if opts.FilterMaxDepth > 0 && opts.FilterMinDepth > opts.FilterMaxDepth {
+		return fmt.Errorf("invalid filter depth range %d-%d", opts.FilterMinDepth, opts.FilterMaxDepth)
+	}
+	if opts.RecursionLimit < 0 {
+		return fmt.Errorf("invalid RecursionLimit %d, should be positive", opts.RecursionLimit)
+	}
+	if opts.FileLimit < 0 {
+		return fmt.Errorf("invalid FileLimit %d, should be positive", opts.FileLimit)
+	}
+	if opts.MaxEntries < 0 || opts.MaxLines < 0 {
+		return errors.New("invalid output limit, should be positive")
+	}
+	if opts.SizeThreshold < 0 || opts.EntryThreshold < 0 {
+		return errors.New("invalid threshold, should be positive")
+	}
+	var sorts []string
+	for _, s := range []struct {
+		name string
+		set  bool
+	}{
+		{"ModSort", opts.ModSort},
+		{"CTimeSort", opts.CTimeSort},
+		{"VerSort", opts.VerSort},
+		{"SizeSort", opts.SizeSort},
+		{"NameSort", opts.NameSort},
+		{"ExtSort", opts.ExtSort},
+	} {
+		if s.set {
+			sorts = append(sorts, s.name)
+		}
+	}
+	if len(sorts) > 1 {
+		return fmt.Errorf("conflicting sort options: %s", strings.Join(sorts, ", "))
+	}
+	if _, err := opts.compileFilters(); err != nil {
+		return err
+	}
+	if _, ok := charsets[strings.ToLower(opts.Charset)]; !ok && opts.Charset != "" {
+		return fmt.Errorf("invalid Charset '%s', should be one of: utf-8,ascii", opts.Charset)
+	}
+	if _, ok := sizeUnits[opts.SizeUnits]; !ok {
+		return fmt.Errorf("invalid SizeUnits '%s', should be one of: iec,si", opts.SizeUnits)
+	}
+	if _, ok := checksums[strings.ToLower(opts.Checksum)]; !ok && opts.Checksum != "" {
+		return fmt.Errorf("invalid Checksum '%s', should be one of: md5,sha1,sha256", opts.Checksum)
+	}
+	if _, ok := opts.Fs.(FileOpener); !ok && opts.Checksum != "" {
+		return fmt.Errorf("invalid Checksum '%s', the Fs can't open files", opts.Checksum)
+	}
+	if !linkGraphFormats[opts.LinkGraph] && opts.LinkGraph != "" {
+		return fmt.Errorf("invalid LinkGraph '%s', should be one of: dot,json", opts.LinkGraph)
+	}
+	if opts.Format != "" && lookupFormat(opts.Format) == nil {
+		return fmt.Errorf("unknown Format '%s', should be one of: %s", opts.Format, strings.Join(Formats(), ","))
+	}
+	if opts.IndentWidth == 1 || opts.IndentWidth < 0 {
+		return fmt.Errorf("invalid IndentWidth %d, should be at least 2", opts.IndentWidth)
+	}
+	if opts.NameWidth > 0 && opts.NameWidth < 3 || opts.NameWidth < 0 {
+		return fmt.Errorf("invalid NameWidth %d, should be at least 3", opts.NameWidth)
+	}
+	if opts.MinSize < 0 || opts.MaxSize < 0 {
+		return errors.New("invalid size range, should be positive")
+	}
+	if opts.MaxSize > 0 && opts.MinSize > opts.MaxSize {
+		return fmt.Errorf("invalid size range %d-%d", opts.MinSize, opts.MaxSize)
+	}
+	if !opts.NewerThan.IsZero() && !opts.OlderThan.IsZero() && !opts.NewerThan.Before(opts.OlderThan) {
+		return errors.New("invalid time range, NewerThan should be before OlderThan")
+	}
+	return nil
+}
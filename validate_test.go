@@ -0,0 +1,48 @@
+package tree
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		opts     *Options
+		expected string
+	}{
+		{&Options{Fs: fs, OutFile: out}, ""},
+		{&Options{Fs: fs, OutFile: out, NoSort: true, DirSort: true, Pattern: "a|!b"}, ""},
+		{&Options{Fs: fs, OutFile: out, ModSort: true, DirSort: true}, ""},
+		{&Options{OutFile: out}, "missing Fs option"},
+		{&Options{Fs: fs}, "missing OutFile option"},
+		{&Options{Fs: fs, OutFile: out, DeepLevel: -1}, "invalid DeepLevel -1, should be positive"},
+		{&Options{Fs: fs, OutFile: out, FilterMinDepth: 3, FilterMaxDepth: 2}, "invalid filter depth range 3-2"},
+		{&Options{Fs: fs, OutFile: out, ModSort: true, SizeSort: true}, "conflicting sort options: ModSort, SizeSort"},
+		{&Options{Fs: fs, OutFile: out, Pattern: "(a"}, "invalid Pattern: error parsing regexp: missing closing ): `(a`"},
+		{&Options{Fs: fs, OutFile: out, IPattern: "a|!*"}, "invalid IPattern: error parsing regexp: missing argument to repetition operator: `*`"},
+		{&Options{Fs: fs, OutFile: out, Charset: "latin1"}, "invalid Charset 'latin1', should be one of: utf-8,ascii"},
+		{&Options{Fs: fs, OutFile: out, IndentWidth: 1}, "invalid IndentWidth 1, should be at least 2"},
+		{&Options{Fs: fs, OutFile: out, Owner: "no-such-user-x"}, "invalid Owner: unknown user 'no-such-user-x'"},
+		{&Options{Fs: fs, OutFile: out, Group: "no-such-group-x"}, "invalid Group: unknown group 'no-such-group-x'"},
+		{&Options{Fs: fs, OutFile: out, Checksum: "crc"}, "invalid Checksum 'crc', should be one of: md5,sha1,sha256"},
+		{&Options{Fs: struct{ Fs }{fs}, OutFile: out, Checksum: "md5"}, "invalid Checksum 'md5', the Fs can't open files"},
+		{&Options{Fs: fs, OutFile: out, LinkGraph: "svg"}, "invalid LinkGraph 'svg', should be one of: dot,json"},
+		{&Options{Fs: fs, OutFile: out, Format: "org-nope"}, "unknown Format 'org-nope', should be one of: " + strings.Join(Formats(), ",")},
+		{&Options{Fs: fs, OutFile: out, NameWidth: 2}, "invalid NameWidth 2, should be at least 3"},
+		{&Options{Fs: fs, OutFile: out, MinSize: 10, MaxSize: 5}, "invalid size range 10-5"},
+		{&Options{Fs: fs, OutFile: out, MaxLines: -1}, "invalid output limit, should be positive"},
+		{&Options{Fs: fs, OutFile: out, EntryThreshold: -1}, "invalid threshold, should be positive"},
+		{&Options{Fs: fs, OutFile: out, NewerThan: now, OlderThan: now}, "invalid time range, NewerThan should be before OlderThan"},
+	}
+	for _, test := range tests {
+		var actual string
+		if err := test.opts.Validate(); err != nil {
+			actual = err.Error()
+		}
+		if actual != test.expected {
+			t.Errorf("\ngot:\n%s\nexpected:\n%s", actual, test.expected)
+		}
+	}
+}
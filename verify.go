@@ -0,0 +1,313 @@
+package tree
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The kinds of Change of a verification against a manifest.
+const (
+	Missing    = "missing"
+	Extra      = "extra"
+	Mismatched = "mismatched"
+)
+
+// ReadManifest reads a manifest to verify a tree against, see the
+// Manifest option: an mtree(5) spec, or the Documents of ReadSnapshot.
+func ReadManifest(r io.Reader) ([]*Entry, error) {
+	br := bufio.NewReader(r)
+	if b, _ := br.Peek(len("#mtree")); bytes.Equal(b, []byte("#mtree")) {
+		root, err := ReadMtree(br)
+		if err != nil {
+			return nil, err
+		}
+		return []*Entry{root}, nil
+	}
+	return ReadSnapshot(br)
+}
+
+// entryTypes are the Entry.Type of the mtree(5) type keywords.
+var entryTypes = map[string]string{
+	"dir":    "directory",
+	"file":   "file",
+	"link":   "link",
+	"socket": "socket",
+	"fifo":   "fifo",
+	"block":  "blockdev",
+	"char":   "chardev",
+}
+
+// ReadMtree reads an mtree(5) spec, in the full path form of --mtree or
+// the hierarchical one of mtree -c, and returns its root ("."). The
+// unspecified sizes are -1, and the unspecified modes have no Perm. The
+// digests are the "md5", "sha1" and "sha256" Meta.
+func ReadMtree(r io.Reader) (*Entry, error) {
+	root := &Entry{Type: "directory", Name: ".", Path: ".", Size: -1}
+	entries := map[string]*Entry{".": root}
+	var entry func(p string) *Entry
+	entry = func(p string) *Entry {
+		if e, ok := entries[p]; ok {
+			return e
+		}
+		parent := entry(path.Dir(p))
+		e := &Entry{Name: path.Base(p), Path: p, Depth: parent.Depth + 1, Size: -1}
+		parent.Contents = append(parent.Contents, e)
+		entries[p] = e
+		return e
+	}
+	set := make(map[string]string)
+	cwd := "."
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		for strings.HasSuffix(line, "\\") && scanner.Scan() {
+			n++
+			line = strings.TrimSuffix(line, "\\") + " " + strings.TrimSpace(scanner.Text())
+		}
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(line)
+		keywords := make(map[string]string)
+		for _, kv := range fields[1:] {
+			i := strings.IndexByte(kv, '=')
+			if i < 0 {
+				keywords[kv] = ""
+			} else {
+				keywords[kv[:i]] = kv[i+1:]
+			}
+		}
+		switch fields[0] {
+		case "/set":
+			for k, v := range keywords {
+				set[k] = v
+			}
+			continue
+		case "/unset":
+			for k := range keywords {
+				if k == "all" {
+					set = make(map[string]string)
+				}
+				delete(set, k)
+			}
+			continue
+		case "..":
+			cwd = path.Dir(cwd)
+			continue
+		}
+		name, err := mtreeUnescape(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("mtree line %d: %v", n, err)
+		}
+		for k, v := range set {
+			if _, ok := keywords[k]; !ok {
+				keywords[k] = v
+			}
+		}
+		full := strings.Contains(name, "/")
+		p := path.Join(cwd, name)
+		if full {
+			p = path.Clean(name)
+		}
+		e := entry(p)
+		if err := setMtreeKeywords(e, keywords); err != nil {
+			return nil, fmt.Errorf("mtree line %d: %v", n, err)
+		}
+		// The directories of the hierarchical form are entered
+		if !full && e.Type == "directory" && p != "." {
+			cwd = p
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// setMtreeKeywords sets the properties of an entry from its mtree(5)
+// keywords.
+func setMtreeKeywords(e *Entry, keywords map[string]string) error {
+	if typ, ok := keywords["type"]; ok {
+		if e.Type, ok = entryTypes[typ]; !ok {
+			return fmt.Errorf("invalid type '%s'", typ)
+		}
+	}
+	if s, ok := keywords["size"]; ok {
+		size, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid size '%s'", s)
+		}
+		e.Size = size
+	}
+	if s, ok := keywords["mode"]; ok {
+		perm, err := strconv.ParseUint(s, 8, 32)
+		if err != nil {
+			return fmt.Errorf("invalid mode '%s'", s)
+		}
+		e.Mode = os.FileMode(perm & 0777)
+		for bit, mode := range map[uint64]os.FileMode{04000: os.ModeSetuid, 02000: os.ModeSetgid, 01000: os.ModeSticky} {
+			if perm&bit != 0 {
+				e.Mode |= mode
+			}
+		}
+		e.Perm = e.Mode.String()
+	}
+	if s, ok := keywords["time"]; ok {
+		sec, nsec := s, "0"
+		if i := strings.IndexByte(s, '.'); i >= 0 {
+			sec, nsec = s[:i], s[i+1:]
+		}
+		secs, err1 := strconv.ParseInt(sec, 10, 64)
+		nsecs, err2 := strconv.ParseInt(nsec, 10, 64)
+		if err1 != nil || err2 != nil {
+			return fmt.Errorf("invalid time '%s'", s)
+		}
+		e.ModTime = time.Unix(secs, nsecs)
+	}
+	if s, ok := keywords["link"]; ok {
+		link, err := mtreeUnescape(s)
+		if err != nil {
+			return err
+		}
+		e.Target = link
+	}
+	for algo := range checksums {
+		for _, k := range []string{algo, algo + "digest"} {
+			if sum, ok := keywords[k]; ok {
+				if e.Meta == nil {
+					e.Meta = make(map[string]interface{})
+				}
+				e.Meta[algo] = sum
+			}
+		}
+	}
+	return nil
+}
+
+// mtreeUnescape decodes the octal sequences of mtreeEscape, and the
+// backslashed characters.
+func mtreeUnescape(s string) (string, error) {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+3 < len(s) && isOctal(s[i+1]) && isOctal(s[i+2]) && isOctal(s[i+3]) {
+			c, _ := strconv.ParseUint(s[i+1:i+4], 8, 8)
+			b.WriteByte(byte(c))
+			i += 3
+		} else if i+1 < len(s) {
+			b.WriteByte(s[i+1])
+			i++
+		} else {
+			return "", fmt.Errorf("invalid escape in '%s'", s)
+		}
+	}
+	return b.String(), nil
+}
+
+func isOctal(c byte) bool { return c >= '0' && c <= '7' }
+
+// ManifestChecksum returns the strongest hash function of the digests of
+// a manifest, e.g. "sha256", or "" if it has none. It's meant to set the
+// Checksum option before verifying.
+func ManifestChecksum(roots []*Entry) string {
+	found := make(map[string]bool)
+	var walk func(e *Entry)
+	walk = func(e *Entry) {
+		for algo, newHash := range checksums {
+			sum, _ := e.Meta[checksumKey].(string)
+			if _, ok := e.Meta[algo]; ok || len(sum) == 2*newHash().Size() {
+				found[algo] = true
+			}
+		}
+		for _, child := range e.Contents {
+			walk(child)
+		}
+	}
+	for _, e := range roots {
+		walk(e)
+	}
+	for _, algo := range []string{"sha256", "sha1", "md5"} {
+		if found[algo] {
+			return algo
+		}
+	}
+	return ""
+}
+
+// verifyChange returns the comparison of the walked entries with the ones
+// of a manifest, checking the digests of the given hash function.
+func verifyChange(algo string) func(old, new *Entry) *Change {
+	algo = strings.ToLower(algo)
+	return func(old, new *Entry) *Change {
+		switch {
+		case new == nil:
+			return &Change{Kind: Missing, Old: shallowEntry(old)}
+		case old == nil:
+			return &Change{Kind: Extra, New: shallowEntry(new)}
+		}
+		var m []string
+		if old.Type != "" && old.Type != new.Type {
+			m = append(m, "type")
+		} else if new.Type != "directory" && old.Size >= 0 && old.Size != new.Size {
+			m = append(m, "size")
+		}
+		if old.Perm != "" && mtreePerm(old.Mode) != mtreePerm(new.Mode) {
+			m = append(m, "mode")
+		}
+		if old.Target != "" && old.Target != new.Target {
+			m = append(m, "link")
+		}
+		want, ok := old.Meta[algo].(string)
+		if !ok {
+			want, _ = old.Meta[checksumKey].(string)
+		}
+		// The snapshots checksums may be of another hash function
+		if got, _ := new.Meta[checksumKey].(string); len(got) == len(want) && got != want && new.Type != "directory" {
+			m = append(m, "checksum")
+		}
+		if m == nil {
+			return nil
+		}
+		return &Change{Kind: Mismatched, Old: shallowEntry(old), New: shallowEntry(new), Mismatches: m}
+	}
+}
+
+// mismatchLabel describes the mismatches of a change, e.g. "size 12,
+// expected 5".
+func mismatchLabel(ch *Change) string {
+	var labels []string
+	for _, m := range ch.Mismatches {
+		switch m {
+		case "type":
+			labels = append(labels, fmt.Sprintf("type %s, expected %s", ch.New.Type, ch.Old.Type))
+		case "size":
+			labels = append(labels, fmt.Sprintf("size %d, expected %d", ch.New.Size, ch.Old.Size))
+		case "mode":
+			labels = append(labels, fmt.Sprintf("mode %04o, expected %04o", mtreePerm(ch.New.Mode), mtreePerm(ch.Old.Mode)))
+		case "link":
+			labels = append(labels, fmt.Sprintf("link %s, expected %s", ch.New.Target, ch.Old.Target))
+		default:
+			labels = append(labels, m)
+		}
+	}
+	return strings.Join(labels, "; ")
+}
+
+// Mismatches returns the number of missing, extra and mismatched entries
+// of a tree printed with the Manifest option.
+func (node *Node) Mismatches() int {
+	return node.mismatches
+}
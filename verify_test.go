@@ -0,0 +1,89 @@
+package tree
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	mod := time.Unix(1546387200, 0)
+	root := &file{name: "root", mode: 0755, files: []*file{
+		{name: "a b", size: 5, mode: 0644, lastMod: mod, content: "hello"},
+		{name: "bin", mode: 0755, files: []*file{{name: "su", size: 5, mode: os.ModeSetuid | 0755, content: "world"}}},
+		{name: "old", size: 1, mode: 0644},
+	}}
+	fs.clean().addFile(root.name, root)
+	inf := New("root")
+	opts := &Options{Fs: fs, OutFile: out, Mtree: true, Checksum: "sha1"}
+	inf.Visit(opts)
+	inf.Print(opts)
+	manifest, err := ReadManifest(strings.NewReader(out.str))
+	out.clear()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if algo := ManifestChecksum(manifest); algo != "sha1" {
+		t.Errorf("expect the sha1 checksums, got %q", algo)
+	}
+	if e := manifest[0].Contents[0]; e.Name != "a b" || e.Size != 5 || !e.ModTime.Equal(mod) || e.Meta["sha1"] == nil {
+		t.Errorf("unexpected mtree entry %+v", e)
+	}
+	root = &file{name: "root", mode: 0755, files: []*file{
+		{name: "a b", size: 5, mode: 0600, lastMod: mod, content: "HELLO"},
+		{name: "bin", mode: 0755, files: []*file{{name: "su", size: 6, mode: 0755, content: "world!"}}},
+		{name: "new", mode: 0644},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"verify", &Options{Fs: fs, OutFile: out, Manifest: manifest, Checksum: "sha1"}, `root
+├── [mismatched mode 0600, expected 0644; checksum]  a b
+├── bin
+│   └── [mismatched size 6, expected 5; mode 0755, expected 4755; checksum]  su
+├── [extra]  new
+└── [missing]  old
+`, 1, 3},
+	})
+	r := Run([]string{"root"}, &Options{Fs: fs, OutFile: out, Manifest: manifest, ChangesJSON: true})
+	if r.Mismatches != 4 || r.ExitCode() != 1 {
+		t.Errorf("expect 4 mismatches and a failure, got %d, %d", r.Mismatches, r.ExitCode())
+	}
+	out.clear()
+}
+
+func TestReadMtree(t *testing.T) {
+	spec := `#mtree
+/set type=file uid=0 gid=0 mode=0644
+. type=dir mode=0755
+    my\040file size=3 \
+        sha256digest=abc
+    sub type=dir mode=0755
+        link type=link link=../my\040file
+    ..
+other size=7
+`
+	root, err := ReadMtree(strings.NewReader(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	var walk func(e *Entry)
+	walk = func(e *Entry) {
+		paths = append(paths, e.Path+":"+e.Type+":"+e.Perm+":"+e.Target)
+		for _, c := range e.Contents {
+			walk(c)
+		}
+	}
+	walk(root)
+	expected := ".:directory:-rwxr-xr-x:,my file:file:-rw-r--r--:,sub:directory:-rwxr-xr-x:,sub/link:link:-rw-r--r--:../my file,other:file:-rw-r--r--:"
+	if got := strings.Join(paths, ","); got != expected {
+		t.Errorf("got %s\nexpected %s", got, expected)
+	}
+	if sum := root.Contents[0].Meta["sha256"]; sum != "abc" {
+		t.Errorf("expect the continued line digest, got %v", sum)
+	}
+	if _, err := ReadMtree(strings.NewReader("#mtree\nfile type=door\n")); err == nil {
+		t.Error("expect an invalid type error")
+	}
+}
@@ -0,0 +1,120 @@
+package tree
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// xmlTree is the document of the XML option, as printed by GNU tree -X.
+type xmlTree struct {
+	XMLName xml.Name `xml:"tree"`
+	Root    *xmlEntry
+	Report  xmlReport `xml:"report"`
+}
+
+// xmlEntry is a visited node, its element name being its Entry.Type. The
+// optional attributes are the ones of the properties options, in the
+// order of GNU tree.
+type xmlEntry struct {
+	XMLName  xml.Name
+	Name     string `xml:"name,attr"`
+	Target   string `xml:"target,attr,omitempty"`
+	Inode    string `xml:"inode,attr,omitempty"`
+	Device   string `xml:"dev,attr,omitempty"`
+	Mode     string `xml:"mode,attr,omitempty"`
+	Perm     string `xml:"prot,attr,omitempty"`
+	User     string `xml:"user,attr,omitempty"`
+	Group    string `xml:"group,attr,omitempty"`
+	Size     string `xml:"size,attr,omitempty"`
+	Time     string `xml:"time,attr,omitempty"`
+	Error    string `xml:"error,omitempty"`
+	Contents []*xmlEntry
+}
+
+type xmlReport struct {
+	Directories int  `xml:"directories"`
+	Files       *int `xml:"files,omitempty"`
+}
+
+// printXML prints the visited node as an XML document, in the format of
+// GNU tree -X: a tree element with the nested elements of the entries,
+// named after their type, and a report. The mode, owner, size and time
+// attributes are printed with the options of the matching columns.
+func (node *Node) printXML(opts *Options) {
+	dirs, files := node.counts()
+	doc := &xmlTree{Root: node.xmlEntry(opts), Report: xmlReport{Directories: dirs}}
+	if !opts.DirsOnly {
+		doc.Report.Files = &files
+	}
+	io.WriteString(opts.OutFile, xml.Header)
+	enc := xml.NewEncoder(opts.OutFile)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		opts.warn("xml not written", node.path, err)
+	}
+	fmt.Fprintln(opts.OutFile)
+}
+
+// xmlEntry returns the element of a visited node and its children.
+func (node *Node) xmlEntry(opts *Options) *xmlEntry {
+	e := &xmlEntry{Name: filepath.Base(node.path)}
+	if node.depth == 0 {
+		e.Name = node.path
+	}
+	if node.err != nil {
+		e.Error = errReason(node.err)
+	}
+	if node.FileInfo == nil {
+		e.XMLName.Local = "other"
+		return e
+	}
+	fi := node.info()
+	if node.IsDir() {
+		e.XMLName.Local = "directory"
+	} else {
+		e.XMLName.Local = typeNames[fileType(node.Mode())]
+	}
+	if node.Mode()&os.ModeSymlink != 0 {
+		e.Target = node.link
+	}
+	ok, inode, device, uid, gid := getStat(fi)
+	if ok && opts.Inodes {
+		e.Inode = strconv.FormatUint(inode, 10)
+	}
+	if ok && opts.Device {
+		e.Device = strconv.FormatUint(device, 10)
+	}
+	if opts.FileMode {
+		e.Mode, e.Perm = fmt.Sprintf("%04o", mtreePerm(fi.Mode())), fi.Mode().String()
+	}
+	if ok && opts.ShowUid {
+		e.User = strconv.FormatUint(uid, 10)
+		if name, err := lookupUser(e.User); err == nil {
+			e.User = name
+		}
+	}
+	if ok && opts.ShowGid {
+		e.Group = strconv.FormatUint(gid, 10)
+		if name, err := lookupGroup(e.Group); err == nil {
+			e.Group = name
+		}
+	}
+	if opts.ByteSize || opts.UnitSize {
+		size := fi.Size()
+		if node.IsDir() {
+			size, _ = node.dirSize()
+		}
+		e.Size = strconv.FormatInt(size, 10)
+	}
+	if opts.LastMod {
+		e.Time = fi.ModTime().Format(opts.timeFormat())
+	}
+	for _, nnode := range node.nodes {
+		e.Contents = append(e.Contents, nnode.xmlEntry(opts))
+	}
+	return e
+}
@@ -0,0 +1,57 @@
+package tree
+
+import (
+	"testing"
+	"time"
+)
+
+func TestXML(t *testing.T) {
+	mtime := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	root := &file{name: "root", files: []*file{
+		{name: "a&b", size: 8, lastMod: mtime, mode: 0644},
+		{name: "c", files: []*file{{name: "d", size: 2, lastMod: mtime, mode: 0600}}},
+		{name: "e", files: []*file{}},
+	}}
+	fs.clean().addFile(root.name, root)
+	checkTests(t, []treeTest{
+		{"xml", &Options{Fs: fs, OutFile: out, XML: true}, `<?xml version="1.0" encoding="UTF-8"?>
+<tree>
+  <directory name="root">
+    <file name="a&amp;b"></file>
+    <directory name="c">
+      <file name="d"></file>
+    </directory>
+    <directory name="e"></directory>
+  </directory>
+  <report>
+    <directories>2</directories>
+    <files>2</files>
+  </report>
+</tree>
+`, 2, 2},
+		{"xml-attrs", &Options{Fs: fs, OutFile: out, XML: true, DirsOnly: true, ByteSize: true}, `<?xml version="1.0" encoding="UTF-8"?>
+<tree>
+  <directory name="root" size="0">
+    <directory name="c" size="0"></directory>
+    <directory name="e" size="0"></directory>
+  </directory>
+  <report>
+    <directories>2</directories>
+  </report>
+</tree>
+`, 2, 0},
+		{"xml-mode", &Options{Fs: fs, OutFile: out, XML: true, FileMode: true, LastMod: true, Pattern: "d"}, `<?xml version="1.0" encoding="UTF-8"?>
+<tree>
+  <directory name="root" mode="0000" prot="----------" time="Jan 01 00:00">
+    <directory name="c" mode="0000" prot="----------" time="Jan 01 00:00">
+      <file name="d" mode="0600" prot="-rw-------" time="May 01 12:00"></file>
+    </directory>
+    <directory name="e" mode="0000" prot="----------" time="Jan 01 00:00"></directory>
+  </directory>
+  <report>
+    <directories>2</directories>
+    <files>1</files>
+  </report>
+</tree>
+`, 2, 1}})
+}